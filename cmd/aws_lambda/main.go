@@ -1,12 +1,10 @@
 package main
 
 import (
-	"net/http"
+	"context"
 	"os"
 
-	"github.com/BaronBonet/content-generator/internal/adapters"
-	"github.com/BaronBonet/content-generator/internal/core/ports"
-	"github.com/BaronBonet/content-generator/internal/core/service"
+	"github.com/BaronBonet/content-generator/internal/app"
 	"github.com/BaronBonet/content-generator/internal/handlers"
 	"github.com/BaronBonet/content-generator/internal/infrastructure"
 	"github.com/BaronBonet/go-logger/logger"
@@ -16,32 +14,18 @@ import (
 func main() {
 	log := logger.NewZapLogger(true, infrastructure.Version)
 
-	NYTimesKey, exists := os.LookupEnv("NEW_YORK_TIMES_KEY")
-	if !exists {
-		log.Fatal("NEW_YORK_TIMES_KEY not found")
-	}
-	newsAdapter := adapters.NewNYTimesNewsAdapter(NYTimesKey, http.DefaultClient)
-
-	OpenAIKey, exists := os.LookupEnv("OPENAI_KEY")
-	if !exists {
-		log.Fatal("OPENAI_KEY not found")
-	}
-
-	llmAdapter := adapters.NewChatGPTAdapter(OpenAIKey, http.DefaultClient)
-
-	imageGenerationAdapter := adapters.NewDalleImageGenerationAdapter(OpenAIKey, http.DefaultClient)
-
-	twitterAdapter, err := adapters.NewTwitterAdapterFromEnv(log)
+	// Previews of dry runs end up in the CloudWatch logs. The lambda filesystem is ephemeral, so terraform configures
+	// REPOSITORY_BUCKET to store drafts and checkpoints in S3.
+	config, err := app.LoadConfig(os.Getenv("CONFIG_FILE"))
 	if err != nil {
-		log.Fatal("Error when creating twitter adapter", "error", err)
+		log.Fatal("Invalid configuration", "error", err)
 	}
-	instagramAdapter, err := adapters.NewInstagramAdapterFromEnv(log)
+
+	contentService, err := app.NewService(context.Background(), config, log)
 	if err != nil {
-		log.Fatal("Error when creating instagram adapter", "error", err)
+		log.Fatal("Error when creating the adapters", "error", err)
 	}
 
-	contentService := service.NewNewsContentService(log, newsAdapter, llmAdapter, imageGenerationAdapter, []ports.SocialMediaAdapter{instagramAdapter, twitterAdapter})
-
 	handler := handlers.NewAWSLambdaEventHandler(log, contentService)
 	lambda.Start(handler.HandleEvent)
 }
@@ -2,12 +2,9 @@ package main
 
 import (
 	"context"
-	"net/http"
 	"os"
 
-	"github.com/BaronBonet/content-generator/internal/adapters"
-	"github.com/BaronBonet/content-generator/internal/core/ports"
-	"github.com/BaronBonet/content-generator/internal/core/service"
+	"github.com/BaronBonet/content-generator/internal/app"
 	"github.com/BaronBonet/content-generator/internal/handlers"
 	"github.com/BaronBonet/go-logger/logger"
 	"github.com/joho/godotenv"
@@ -21,29 +18,20 @@ func main() {
 		logger.Fatal("Error loading .env file")
 	}
 
-	NYTimesKey, exists := os.LookupEnv("NEW_YORK_TIMES_KEY")
-	if !exists {
-		logger.Fatal("NEW_YORK_TIMES_KEY not found")
+	config, err := app.LoadConfig(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		logger.Fatal("Invalid configuration", "error", err)
 	}
-	newsAdapter := adapters.NewNYTimesNewsAdapter(NYTimesKey, http.DefaultClient)
-
-	OpenAIKey, exists := os.LookupEnv("OPENAI_KEY")
-	if !exists {
-		logger.Fatal("OPENAI_KEY not found")
+	// Drafts have to be stored somewhere to be approved later
+	if config.Repository.Directory == "" {
+		config.Repository.Directory = "data"
 	}
 
-	llmAdapter := adapters.NewChatGPTAdapter(OpenAIKey, http.DefaultClient)
-
-	imageGenerationAdapter := adapters.NewDalleImageGenerationAdapter(OpenAIKey, http.DefaultClient)
-
-	twitterAdapter, err := adapters.NewTwitterAdapterFromEnv(logger)
+	ctx := context.Background()
+	contentService, err := app.NewService(ctx, config, logger)
 	if err != nil {
-		logger.Fatal("Error when creating twitter adapter", "error", err)
+		logger.Fatal("Error when creating the adapters", "error", err)
 	}
-	instagramAdapter, err := adapters.NewInstagramAdapterFromEnv(logger)
-
-	contentService := service.NewNewsContentService(logger, newsAdapter, llmAdapter, imageGenerationAdapter, []ports.SocialMediaAdapter{instagramAdapter, twitterAdapter})
-	ctx := context.Background()
 
 	handler := handlers.NewCLIHandler(ctx, contentService, logger)
 	if err := handler.Run(os.Args); err != nil {
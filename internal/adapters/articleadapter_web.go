@@ -0,0 +1,92 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"golang.org/x/net/html"
+)
+
+// maxArticlePageSize limits how much of a page is read, articles are far smaller than this
+const maxArticlePageSize = 5 << 20
+
+type webArticleAdapter struct {
+	client httpClient
+}
+
+// NewWebArticleAdapter reads articles from web pages, using the metadata news sites provide for link previews and the
+// main text of the page
+func NewWebArticleAdapter(httpClient httpClient) ports.ArticleAdapter {
+	return &webArticleAdapter{client: httpClient}
+}
+
+func (a *webArticleAdapter) GetArticle(ctx context.Context, articleURL string) (domain.NewsArticle, error) {
+	parsedURL, err := url.Parse(articleURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return domain.NewsArticle{}, fmt.Errorf("invalid article url %q", articleURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, articleURL, nil)
+	if err != nil {
+		return domain.NewsArticle{}, err
+	}
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return domain.NewsArticle{}, fmt.Errorf("failed to fetch article: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return domain.NewsArticle{}, fmt.Errorf("failed to fetch article, status code: %d", resp.StatusCode)
+	}
+	page, err := html.Parse(io.LimitReader(resp.Body, maxArticlePageSize))
+	if err != nil {
+		return domain.NewsArticle{}, fmt.Errorf("failed to read article: %w", err)
+	}
+
+	return articleFromPage(page, parsedURL)
+}
+
+// articleFromPage builds the article from the Open Graph metadata of the page, falling back to the title element. The
+// body is the main text of the page, or the description when the story could not be found in the page.
+func articleFromPage(page *html.Node, pageURL *url.URL) (domain.NewsArticle, error) {
+	tags := metaTags(page)
+	body := firstNonEmpty(tags["og:description"], tags["twitter:description"], tags["description"])
+	if text := mainText(page); len(text) > len(body) {
+		body = text
+	}
+	article := domain.NewsArticle{
+		Title:   firstNonEmpty(tags["og:title"], tags["twitter:title"], pageTitle(page)),
+		Body:    body,
+		Url:     pageURL.String(),
+		Source:  firstNonEmpty(tags["og:site_name"], strings.TrimPrefix(pageURL.Hostname(), "www.")),
+		Section: tags["article:section"],
+	}
+	if article.Title == "" {
+		return domain.NewsArticle{}, fmt.Errorf("no article found at %s", pageURL)
+	}
+
+	published := time.Now()
+	if date, err := time.Parse(time.RFC3339, tags["article:published_time"]); err == nil {
+		published = date
+	}
+	article.Date = domain.NewDate(published)
+	return article, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
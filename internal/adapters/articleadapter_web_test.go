@@ -0,0 +1,77 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebArticleAdapter_GetArticle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/comet":
+			fmt.Fprint(w, `<html><head>
+				<title>Comet spotted | Example News</title>
+				<meta property="og:title" content="Comet spotted over the North Sea" />
+				<meta content="Astronomers saw a comet &amp; its tail." property="og:description">
+				<meta property="og:site_name" content="Example News">
+				<meta property="article:section" content="science">
+				<meta property="article:published_time" content="2023-06-01T08:00:00Z">
+			</head><body></body></html>`)
+		case "/story":
+			fmt.Fprint(w, `<html><head><title>Bees return</title><meta name="description" content="Bees are back."></head>
+				<body>
+				<nav><p>Home, World, Science, Climate, Business, Opinion and more sections</p></nav>
+				<script>var p = "<p>not a paragraph of the story at all, really</p>";</script>
+				<main><article>
+					<p class="byline">By A. Reporter</p>
+					<p>The bees have returned to the valley, <b>years</b> after they disappeared from the orchards.</p>
+					<figure><p>A bee on an apple blossom, photographed in the valley this spring.</p></figure>
+					<p><a href="/a">Read more about bees and the orchards of the valley here</a></p>
+					<p>Farmers said the harvest,<br>which had been failing, is expected to recover this year.</p>
+				</article></main>
+				<footer><p>Copyright Example News, all rights reserved, 2023</p></footer>
+				</body></html>`)
+		case "/plain":
+			fmt.Fprint(w, `<html><head><title> Plain page </title><meta name="description" content="Just a page"></head></html>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	adapter := NewWebArticleAdapter(server.Client())
+
+	article, err := adapter.GetArticle(context.Background(), server.URL+"/comet")
+	require.NoError(t, err)
+	require.Equal(t, domain.NewsArticle{
+		Title:   "Comet spotted over the North Sea",
+		Body:    "Astronomers saw a comet & its tail.",
+		Date:    domain.Date{Day: 1, Month: time.June, Year: 2023},
+		Url:     server.URL + "/comet",
+		Source:  "Example News",
+		Section: "science",
+	}, article)
+
+	article, err = adapter.GetArticle(context.Background(), server.URL+"/plain")
+	require.NoError(t, err)
+	require.Equal(t, "Plain page", article.Title)
+	require.Equal(t, "Just a page", article.Body)
+	require.Equal(t, "127.0.0.1", article.Source)
+
+	article, err = adapter.GetArticle(context.Background(), server.URL+"/story")
+	require.NoError(t, err)
+	require.Equal(t, "The bees have returned to the valley, years after they disappeared from the orchards."+
+		"\n\nFarmers said the harvest, which had been failing, is expected to recover this year.", article.Body)
+
+	_, err = adapter.GetArticle(context.Background(), server.URL+"/missing")
+	require.EqualError(t, err, "failed to fetch article, status code: 404")
+
+	_, err = adapter.GetArticle(context.Background(), "file:///etc/passwd")
+	require.EqualError(t, err, `invalid article url "file:///etc/passwd"`)
+}
@@ -0,0 +1,264 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AWSConfig configures the adapters calling AWS services. The lambda runtime provides the credentials of the execution
+// role in the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+type AWSConfig struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is only set for temporary credentials
+	SessionToken string
+	// Endpoint overrides the url of the service, e.g. to use a local stub. Defaults to the regional endpoint.
+	Endpoint string
+}
+
+// awsClient calls the AWS services using the JSON protocol, like SSM and Secrets Manager, or a REST protocol, like S3,
+// signing the requests with Signature Version 4
+type awsClient struct {
+	// service is the name requests are signed for, which is also the start of the regional endpoint
+	service    string
+	config     AWSConfig
+	httpClient httpClient
+	now        func() time.Time
+}
+
+// awsError is the body of an error response of the JSON protocol
+type awsError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+func (e *awsError) Error() string {
+	return e.Type + ": " + e.Message
+}
+
+// awsStatusError is an error response of a REST service, S3 describes the error in an xml body
+type awsStatusError struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *awsStatusError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("status code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s, status code: %d", e.Code, e.Message, e.StatusCode)
+}
+
+func newAWSClient(service string, config AWSConfig, httpClient httpClient) *awsClient {
+	return &awsClient{service: service, config: config, httpClient: httpClient, now: time.Now}
+}
+
+// call calls the operation with the target, e.g. AmazonSSM.GetParameter, decoding the response into output. An error
+// response is returned as an *awsError.
+func (c *awsClient) call(ctx context.Context, target string, input any, output any) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s input: %w", target, err)
+	}
+	endpoint := c.config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", c.service, c.config.Region)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	c.sign(req, body)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var awsErr awsError
+		if err = json.Unmarshal(bodyBytes, &awsErr); err != nil || awsErr.Type == "" {
+			return fmt.Errorf("%s failed, status code: %d", target, resp.StatusCode)
+		}
+		// Some services prefix the type with its namespace, e.g. com.amazonaws.ssm#ParameterNotFound
+		awsErr.Type = awsErr.Type[strings.LastIndex(awsErr.Type, "#")+1:]
+		return &awsErr
+	}
+	if err = json.Unmarshal(bodyBytes, output); err != nil {
+		return fmt.Errorf("failed to unmarshal %s output: %w", target, err)
+	}
+	return nil
+}
+
+// send sends a request to a REST service and returns the body of the response, rawURL is the full url of the resource.
+// A response that is not successful is returned as an *awsStatusError.
+func (c *awsClient) send(ctx context.Context, method string, rawURL string, body []byte, contentType string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", withoutURL(err))
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	// S3 requires the hash of the payload in a header as well as in the signature
+	req.Header.Set("X-Amz-Content-Sha256", sha256Hex(body))
+	c.sign(req, body)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		statusErr := &awsStatusError{StatusCode: resp.StatusCode}
+		_ = xml.Unmarshal(bodyBytes, statusErr)
+		return nil, statusErr
+	}
+	return bodyBytes, nil
+}
+
+// sign adds the Signature Version 4 authorization header to the request, see
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+func (c *awsClient) sign(req *http.Request, body []byte) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := strings.Join([]string{amzDate[:8], c.config.Region, c.service, "aws4_request"}, "/")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if c.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.config.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	uri := req.URL.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uri,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.config.AccessKeyID, scope, signedHeaders, c.signature(amzDate, scope, canonicalRequest)))
+}
+
+// presign returns a url that allows anyone to get the resource at rawURL until it expires, without credentials. The
+// url does not outlive the temporary credentials it is signed with, see
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+func (c *awsClient) presign(rawURL string, expires time.Duration) (string, error) {
+	presignedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url: %w", withoutURL(err))
+	}
+	amzDate := c.now().UTC().Format("20060102T150405Z")
+	scope := strings.Join([]string{amzDate[:8], c.config.Region, c.service, "aws4_request"}, "/")
+
+	query := presignedURL.Query()
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", c.config.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if c.config.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", c.config.SessionToken)
+	}
+
+	uri := presignedURL.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		uri,
+		canonicalQuery(query),
+		"host:" + presignedURL.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	presignedURL.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + c.signature(amzDate, scope, canonicalRequest)
+	return presignedURL.String(), nil
+}
+
+// signature signs the canonical request with a key derived from the secret access key for the scope
+func (c *awsClient) signature(amzDate string, scope string, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + c.config.SecretAccessKey)
+	for _, part := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalQuery sorts the query parameters by name and value, encoding them the way Signature Version 4 expects
+func canonicalQuery(query url.Values) string {
+	var params []string
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, awsEscape(name)+"="+awsEscape(value))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// awsEscape percent encodes everything except the unreserved characters, unlike url.QueryEscape spaces become %20
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,155 @@
+package adapters
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// unlikelyPattern matches the classes and ids of paragraphs that are not part of the story, e.g. captions
+	unlikelyPattern = regexp.MustCompile(`(?i)caption|comment|credit|footer|newsletter|promo|related|share|social|sponsor|subscribe`)
+	// boilerplateElements are the elements that never contain the story, they are skipped with everything inside them
+	boilerplateElements = map[atom.Atom]bool{
+		atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true, atom.Svg: true, atom.Iframe: true,
+		atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Form: true, atom.Figure: true,
+	}
+)
+
+const (
+	// minParagraphLength is the length below which a paragraph is never part of the story, e.g. bylines and buttons
+	minParagraphLength = 25
+	// minParagraphScore is the score a paragraph needs to be part of the main text
+	minParagraphScore = 1.5
+)
+
+// metaTags returns the content of the meta tags of the page by their property or name, e.g. og:title. The first tag
+// with a property or name wins.
+func metaTags(page *html.Node) map[string]string {
+	tags := map[string]string{}
+	for _, tag := range findElements(page, nil, atom.Meta) {
+		key := strings.ToLower(firstNonEmpty(attribute(tag, "property"), attribute(tag, "name")))
+		if _, exists := tags[key]; key != "" && !exists {
+			tags[key] = cleanText(attribute(tag, "content"))
+		}
+	}
+	return tags
+}
+
+// pageTitle returns the content of the title element of the page
+func pageTitle(page *html.Node) string {
+	titles := findElements(page, nil, atom.Title)
+	if len(titles) == 0 {
+		return ""
+	}
+	return nodeText(titles[0])
+}
+
+// cleanText collapses the whitespace of the text, the parser already unescaped its HTML entities
+func cleanText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// mainText returns the text of the story on the page, like the reader view of a browser. The elements that are never
+// part of the story are skipped, and of the article and main elements the one with the most text is used, or the whole
+// page when there are none. Its paragraphs are scored so navigation, bylines and lists of links are left out. The
+// paragraphs are separated by empty lines, the text is empty when no paragraph scores high enough.
+func mainText(page *html.Node) string {
+	var text string
+	for _, container := range findElements(page, isBoilerplate, atom.Article, atom.Main) {
+		if candidate := paragraphsText(container); len(candidate) > len(text) {
+			text = candidate
+		}
+	}
+	if text == "" {
+		text = paragraphsText(page)
+	}
+	return text
+}
+
+// paragraphsText returns the text of the paragraphs below the node that score high enough to be part of the story
+func paragraphsText(node *html.Node) string {
+	var paragraphs []string
+	for _, paragraph := range findElements(node, isBoilerplate, atom.P) {
+		if unlikelyPattern.MatchString(attribute(paragraph, "class") + " " + attribute(paragraph, "id")) {
+			continue
+		}
+		text := nodeText(paragraph)
+		if paragraphScore(paragraph, text) >= minParagraphScore {
+			paragraphs = append(paragraphs, text)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// paragraphScore scores a paragraph like Readability does: one point, a point per comma and a point per hundred
+// characters up to three, reduced by the share of the text that is links
+func paragraphScore(paragraph *html.Node, text string) float64 {
+	if len(text) < minParagraphLength {
+		return 0
+	}
+	score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+
+	linkLength := 0
+	for _, link := range findElements(paragraph, isBoilerplate, atom.A) {
+		linkLength += len(nodeText(link))
+	}
+	return score * (1 - math.Min(float64(linkLength)/float64(len(text)), 1))
+}
+
+// findElements returns the elements with one of the tags below the node in document order, including the ones nested
+// in each other. The elements skip returns true for are not looked into.
+func findElements(node *html.Node, skip func(*html.Node) bool, tags ...atom.Atom) []*html.Node {
+	var found []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || (skip != nil && skip(child)) {
+			continue
+		}
+		for _, tag := range tags {
+			if child.DataAtom == tag {
+				found = append(found, child)
+				break
+			}
+		}
+		found = append(found, findElements(child, skip, tags...)...)
+	}
+	return found
+}
+
+// nodeText returns the text below the node without the boilerplate elements, line breaks become spaces
+func nodeText(node *html.Node) string {
+	var text strings.Builder
+	var collect func(*html.Node)
+	collect = func(node *html.Node) {
+		switch {
+		case node.Type == html.TextNode:
+			text.WriteString(node.Data)
+		case node.Type == html.ElementNode && node.DataAtom == atom.Br:
+			text.WriteString(" ")
+		case isBoilerplate(node):
+			return
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(node)
+	return cleanText(text.String())
+}
+
+func isBoilerplate(node *html.Node) bool {
+	return node.Type == html.ElementNode && boilerplateElements[node.DataAtom]
+}
+
+// attribute returns the value of the attribute of the element, the parser lowercases the attribute names
+func attribute(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if attr.Namespace == "" && attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package adapters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+func TestMainText(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		expected string
+	}{
+		{
+			name: "nested containers",
+			page: `<main><p>A teaser of the main page that is long enough to be a paragraph.</p>
+				<article><header><article><p>The headline of the story, nested in the header of the article.</p></article></header>
+				<p>The first paragraph of the story, after the nested header of the article.</p>
+				<aside><aside><p>An aside nested in an aside, which a regexp would have cut off early.</p></aside>
+				<p>The rest of the outer aside, which is not part of the story either.</p></aside>
+				<p>The second paragraph of the story, after the nested asides of the article.</p>
+				</article></main>`,
+			expected: "A teaser of the main page that is long enough to be a paragraph." +
+				"\n\nThe first paragraph of the story, after the nested header of the article." +
+				"\n\nThe second paragraph of the story, after the nested asides of the article.",
+		},
+		{
+			name: "container with the most text",
+			page: `<article><p>A short story that is long enough to be a paragraph.</p></article>
+				<article><p>A longer story that is long enough to be a paragraph, and then some more.</p></article>`,
+			expected: "A longer story that is long enough to be a paragraph, and then some more.",
+		},
+		{
+			name: "greater than in attributes",
+			page: `<article data-layout="a > b"><p title="1 > 0" class="lead">The story starts, even though the
+				attributes contain a greater than sign.</p><figure data-x="<p>"><p>A caption that is long enough to be a
+				paragraph.</p></figure><p>The story ends <img alt="<br>" src="/a.png"> without a caption, as a
+				story should.</p></article>`,
+			expected: "The story starts, even though the attributes contain a greater than sign." +
+				"\n\nThe story ends without a caption, as a story should.",
+		},
+		{
+			name: "unlikely paragraphs and links",
+			page: `<p class="image-caption">A caption that is long enough to be a paragraph.</p>
+				<p id="newsletter">Subscribe to the newsletter to get the story every day.</p>
+				<p><a href="/more">A link that is long enough to be a paragraph.</a></p>
+				<p>A story without containers is taken from the whole page.</p>`,
+			expected: "A story without containers is taken from the whole page.",
+		},
+		{
+			name:     "no story",
+			page:     `<p>Too short</p><script>document.write("<p>A script that is long enough to be a paragraph.</p>")</script>`,
+			expected: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := html.Parse(strings.NewReader(tt.page))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, mainText(page))
+		})
+	}
+}
+
+func TestMetaTags(t *testing.T) {
+	page, err := html.Parse(strings.NewReader(`<html><head>
+		<meta property="og:title" content="Prices > expected &amp; rising">
+		<meta name="OG:Title" content="Second title">
+		<meta name="description" content="  A
+			description  ">
+		<meta charset="utf-8">
+	</head></html>`))
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		"og:title":    "Prices > expected & rising",
+		"description": "A description",
+	}, metaTags(page))
+}
@@ -1,6 +1,10 @@
 package adapters
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
 
 // httpClient is an interface that represents an HTTP client.
 // This exists, so we can mock the HTTP client, which is used in multiple adapters in our tests.
@@ -10,3 +14,13 @@ type httpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 	Get(url string) (*http.Response, error)
 }
+
+// withoutURL removes the url from the error of a request, for the adapters with credentials in their urls. The errors of
+// publishing end up in logs, stored runs and API responses, which must not reveal the credentials.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
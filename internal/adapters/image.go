@@ -0,0 +1,85 @@
+package adapters
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+)
+
+// openImage opens an image from a url, or from the local filesystem when the image was stored by a repository.
+// It returns the image together with its content type.
+func openImage(client httpClient, image domain.ImagePath) (io.ReadCloser, string, error) {
+	path := string(image)
+	if !isRemoteImage(image) {
+		file, err := os.Open(strings.TrimPrefix(path, "file://"))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open image: %w", err)
+		}
+		return file, imageContentType(filepath.Ext(path)), nil
+	}
+
+	resp, err := client.Get(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download image: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("failed to download image, status code: %d", resp.StatusCode)
+	}
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
+
+// isRemoteImage returns true when the image is at a url rather than on the local filesystem
+func isRemoteImage(image domain.ImagePath) bool {
+	return strings.HasPrefix(string(image), "http://") || strings.HasPrefix(string(image), "https://")
+}
+
+// publicImageURL returns the url an image can be downloaded from by a third party. Images stored on the local filesystem
+// are expected to be served at baseURL, without it the public url the repository kept for the image is used.
+func publicImageURL(baseURL string, image domain.GeneratedImage) (string, error) {
+	path := string(image.Path)
+	switch {
+	case isRemoteImage(image.Path):
+		return path, nil
+	case baseURL != "":
+		return strings.TrimSuffix(baseURL, "/") + "/" + url.PathEscape(filepath.Base(path)), nil
+	case image.URL != "":
+		return image.URL, nil
+	default:
+		return "", fmt.Errorf("%s is a local file and no public image base url is configured", path)
+	}
+}
+
+// imageExtension returns the file extension for an image content type, defaulting to .png which DALL-E produces
+func imageExtension(contentType string) string {
+	switch strings.TrimSpace(strings.Split(contentType, ";")[0]) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ".png"
+	}
+}
+
+// imageContentType is the inverse of imageExtension
+func imageContentType(extension string) string {
+	switch strings.ToLower(extension) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	default:
+		return "image/png"
+	}
+}
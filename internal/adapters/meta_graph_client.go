@@ -0,0 +1,352 @@
+package adapters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+// metaGraphAPI describes how the Instagram and Threads Graph APIs differ, both follow the same container then publish flow
+type metaGraphAPI struct {
+	name    string
+	host    string
+	version string
+	// containerEdge and publishEdge are the edges of the user creating and publishing media containers
+	containerEdge string
+	publishEdge   string
+	// statusField holds the status of a media container and statusDetailField explains why it failed
+	statusField       string
+	statusDetailField string
+	textParam         string
+	imageMediaType    string
+	refreshGrantType  string
+}
+
+var (
+	instagramGraphAPI = metaGraphAPI{
+		name:              "Instagram",
+		host:              "https://graph.instagram.com",
+		version:           "v21.0",
+		containerEdge:     "media",
+		publishEdge:       "media_publish",
+		statusField:       "status_code",
+		statusDetailField: "status",
+		textParam:         "caption",
+		refreshGrantType:  "ig_refresh_token",
+	}
+	threadsGraphAPI = metaGraphAPI{
+		name:              "Threads",
+		host:              "https://graph.threads.net",
+		version:           "v1.0",
+		containerEdge:     "threads",
+		publishEdge:       "threads_publish",
+		statusField:       "status",
+		statusDetailField: "error_message",
+		textParam:         "text",
+		imageMediaType:    "IMAGE",
+		refreshGrantType:  "th_refresh_token",
+	}
+)
+
+// MetaGraphConfig configures the adapters using the Instagram or Threads Graph API
+type MetaGraphConfig struct {
+	// UserID is the id of the Instagram professional account or Threads profile to publish with
+	UserID string
+	// AccessToken is a long-lived access token, which is refreshed once a day while it is in use
+	AccessToken string
+	// Repository stores the refreshed access token so it survives restarts, the token is only kept in memory when nil.
+	// The stored token is used until another access token is configured.
+	Repository ports.RepositoryAdapter
+	// PublicImageBaseURL is the url the images stored by the repository are served at. The Graph APIs download images
+	// themselves, so images stored on the local filesystem can only be published when they are publicly available.
+	PublicImageBaseURL string
+}
+
+// metaGraphClient publishes media through the Instagram or Threads Graph API
+type metaGraphClient struct {
+	api          metaGraphAPI
+	config       MetaGraphConfig
+	httpClient   httpClient
+	logger       logger.Logger
+	now          func() time.Time
+	pollInterval time.Duration
+	maxPolls     int
+
+	mu    sync.Mutex
+	token domain.AccessToken
+	// loaded is set once the token to use was picked from the configured and the stored token
+	loaded bool
+}
+
+func newMetaGraphClient(api metaGraphAPI, config MetaGraphConfig, httpClient httpClient, logger logger.Logger) *metaGraphClient {
+	return &metaGraphClient{
+		api:          api,
+		config:       config,
+		httpClient:   httpClient,
+		logger:       logger,
+		now:          time.Now,
+		pollInterval: 5 * time.Second,
+		maxPolls:     60,
+		token:        domain.AccessToken{Value: config.AccessToken},
+	}
+}
+
+// publishImages publishes the images as a single post, or as a carousel when there are several, and returns the media id
+func (c *metaGraphClient) publishImages(ctx context.Context, images []domain.GeneratedImage, text string) (string, error) {
+	c.refreshToken(ctx)
+
+	params := url.Values{c.api.textParam: {text}}
+	if len(images) == 1 {
+		if err := c.setImageParams(params, images[0]); err != nil {
+			return "", err
+		}
+		if c.api.imageMediaType != "" {
+			params.Set("media_type", c.api.imageMediaType)
+		}
+	} else {
+		children := make([]string, 0, len(images))
+		for _, image := range images {
+			childParams := url.Values{"is_carousel_item": {"true"}}
+			if err := c.setImageParams(childParams, image); err != nil {
+				return "", err
+			}
+			if c.api.imageMediaType != "" {
+				childParams.Set("media_type", c.api.imageMediaType)
+			}
+			childID, err := c.createContainer(ctx, childParams)
+			if err != nil {
+				return "", err
+			}
+			children = append(children, childID)
+		}
+		params.Set("media_type", "CAROUSEL")
+		params.Set("children", strings.Join(children, ","))
+	}
+	return c.publish(ctx, params)
+}
+
+// publishText publishes a text only post, replying to replyToID when it is set
+func (c *metaGraphClient) publishText(ctx context.Context, text string, replyToID string) (string, error) {
+	params := url.Values{"media_type": {"TEXT"}, c.api.textParam: {text}}
+	if replyToID != "" {
+		params.Set("reply_to_id", replyToID)
+	}
+	return c.publish(ctx, params)
+}
+
+// publish creates a media container, waits until it is ready and publishes it
+func (c *metaGraphClient) publish(ctx context.Context, params url.Values) (string, error) {
+	containerID, err := c.createContainer(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	if err = c.waitForContainer(ctx, containerID); err != nil {
+		return "", err
+	}
+
+	var response struct {
+		ID string `json:"id"`
+	}
+	err = c.request(ctx, http.MethodPost, c.path(c.config.UserID, c.api.publishEdge), url.Values{"creation_id": {containerID}}, &response)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish %s media: %w", c.api.name, err)
+	}
+	return response.ID, nil
+}
+
+func (c *metaGraphClient) setImageParams(params url.Values, image domain.GeneratedImage) error {
+	imageURL, err := c.publicImageURL(image)
+	if err != nil {
+		return err
+	}
+	params.Set("image_url", imageURL)
+	params.Set("alt_text", image.Prompt)
+	return nil
+}
+
+// publicImageURL returns the url the Graph API can download the image from
+func (c *metaGraphClient) publicImageURL(image domain.GeneratedImage) (string, error) {
+	imageURL, err := publicImageURL(c.config.PublicImageBaseURL, image)
+	if err != nil {
+		return "", fmt.Errorf("%s can only publish images at a public url: %w", c.api.name, err)
+	}
+	return imageURL, nil
+}
+
+func (c *metaGraphClient) createContainer(ctx context.Context, params url.Values) (string, error) {
+	var response struct {
+		ID string `json:"id"`
+	}
+	if err := c.request(ctx, http.MethodPost, c.path(c.config.UserID, c.api.containerEdge), params, &response); err != nil {
+		return "", fmt.Errorf("failed to create %s media container: %w", c.api.name, err)
+	}
+	return response.ID, nil
+}
+
+// waitForContainer polls a media container until it is ready to be published
+func (c *metaGraphClient) waitForContainer(ctx context.Context, containerID string) error {
+	for i := 0; i < c.maxPolls; i++ {
+		var response map[string]string
+		err := c.request(ctx, http.MethodGet, c.path(containerID), url.Values{"fields": {c.api.statusField + "," + c.api.statusDetailField}}, &response)
+		if err != nil {
+			return fmt.Errorf("failed to get %s media container status: %w", c.api.name, err)
+		}
+		switch response[c.api.statusField] {
+		case "FINISHED", "PUBLISHED":
+			return nil
+		case "ERROR", "EXPIRED":
+			return fmt.Errorf("%s media container %s has status %s: %s", c.api.name, containerID, response[c.api.statusField], response[c.api.statusDetailField])
+		}
+		c.logger.Debug("Waiting for media container to be ready", "api", c.api.name, "container id", containerID)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s media container %s was not ready in time: %w", c.api.name, containerID, ctx.Err())
+		case <-time.After(c.pollInterval):
+		}
+	}
+	return fmt.Errorf("%s media container %s was not ready after %d status checks", c.api.name, containerID, c.maxPolls)
+}
+
+// permalink returns the url of published media
+func (c *metaGraphClient) permalink(ctx context.Context, mediaID string) (string, error) {
+	var response struct {
+		Permalink string `json:"permalink"`
+	}
+	if err := c.request(ctx, http.MethodGet, c.path(mediaID), url.Values{"fields": {"permalink"}}, &response); err != nil {
+		return "", fmt.Errorf("failed to get %s permalink: %w", c.api.name, err)
+	}
+	return response.Permalink, nil
+}
+
+// refreshToken extends a long-lived access token, which expires after 60 days unless it is refreshed.
+// A token can be refreshed once it is a day old, so it is refreshed at most once a day. Failing to refresh is not an
+// error as long as the current token is still valid.
+func (c *metaGraphClient) refreshToken(ctx context.Context) {
+	c.mu.Lock()
+	if !c.loaded {
+		c.token = c.loadToken(ctx)
+		c.loaded = true
+	}
+	token := c.token
+	c.mu.Unlock()
+	if c.now().Sub(token.RefreshedAt) < 24*time.Hour {
+		return
+	}
+
+	var response struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	// Unlike the other endpoints refreshing is not versioned
+	err := c.request(ctx, http.MethodGet, "/refresh_access_token", url.Values{"grant_type": {c.api.refreshGrantType}}, &response)
+	if err != nil {
+		c.logger.Warn("Could not refresh the access token", "api", c.api.name, "error", err)
+		return
+	}
+
+	token = domain.AccessToken{
+		Value:       response.AccessToken,
+		Origin:      token.Origin,
+		RefreshedAt: c.now(),
+		ExpiresAt:   c.now().Add(time.Duration(response.ExpiresIn) * time.Second),
+	}
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+	c.logger.Debug("Refreshed access token", "api", c.api.name, "expires at", token.ExpiresAt)
+	c.storeToken(ctx, token)
+}
+
+// loadToken returns the stored token, unless another token was configured since it was stored. The configured token
+// counts as refreshed when it is first used, its age is unknown and refreshing a token younger than a day fails.
+func (c *metaGraphClient) loadToken(ctx context.Context) domain.AccessToken {
+	hash := sha256.Sum256([]byte(c.config.AccessToken))
+	configured := domain.AccessToken{Value: c.config.AccessToken, Origin: hex.EncodeToString(hash[:8]), RefreshedAt: c.now()}
+	if c.config.Repository == nil {
+		return configured
+	}
+
+	stored, err := c.config.Repository.GetAccessToken(ctx, c.tokenName())
+	switch {
+	case err == nil && stored.Origin == configured.Origin:
+		return stored
+	case err == nil:
+		c.logger.Info("Another access token was configured, replacing the stored one", "api", c.api.name)
+	case !errors.Is(err, domain.ErrNotFound):
+		c.logger.Warn("Could not read the stored access token, using the configured one", "api", c.api.name, "error", err)
+		return configured
+	}
+	c.storeToken(ctx, configured)
+	return configured
+}
+
+func (c *metaGraphClient) storeToken(ctx context.Context, token domain.AccessToken) {
+	if c.config.Repository == nil {
+		return
+	}
+	if err := c.config.Repository.SaveAccessToken(ctx, c.tokenName(), token); err != nil {
+		c.logger.Error("Could not store the access token", "api", c.api.name, "error", err)
+	}
+}
+
+// tokenName is the name of the token in the repository, every account has its own token
+func (c *metaGraphClient) tokenName() string {
+	return strings.ToLower(c.api.name) + "-" + c.config.UserID
+}
+
+// path builds the path of a versioned Graph API endpoint
+func (c *metaGraphClient) path(segments ...string) string {
+	return "/" + c.api.version + "/" + strings.Join(segments, "/")
+}
+
+// request calls the Graph API, the parameters are sent in the query string and the response is decoded into v. The
+// access token is sent in the Authorization header, so it is not part of the url that errors of requests contain.
+func (c *metaGraphClient) request(ctx context.Context, method string, path string, params url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.api.host+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.mu.Lock()
+	req.Header.Set("Authorization", "Bearer "+c.token.Value)
+	c.mu.Unlock()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(bodyBytes, &errorResponse) == nil && errorResponse.Error.Message != "" {
+			return fmt.Errorf("status code: %d, %s", resp.StatusCode, errorResponse.Error.Message)
+		}
+		return fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	if err = json.Unmarshal(bodyBytes, v); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
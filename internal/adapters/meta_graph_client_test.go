@@ -0,0 +1,188 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMetaGraphServer implements the container flow of a Graph API, containers are ready after one status check
+type fakeMetaGraphServer struct {
+	api metaGraphAPI
+
+	mu         sync.Mutex
+	containers []url.Values
+	polls      map[string]int
+	published  []url.Values
+	refreshes  int
+	tokens     []string
+}
+
+func newFakeMetaGraphServer(t *testing.T, api metaGraphAPI) (*fakeMetaGraphServer, *httptest.Server) {
+	fake := &fakeMetaGraphServer{api: api, polls: map[string]int{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		params := r.URL.Query()
+		require.False(t, params.Has("access_token"), "the access token must not be in the url")
+		fake.tokens = append(fake.tokens, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+
+		if r.URL.Path == "/refresh_access_token" {
+			require.Equal(t, api.refreshGrantType, params.Get("grant_type"))
+			fake.refreshes++
+			fmt.Fprint(w, `{"access_token": "refreshed-token", "token_type": "bearer", "expires_in": 5184000}`)
+			return
+		}
+
+		segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/"+api.version+"/"), "/")
+		switch {
+		case r.Method == http.MethodPost && len(segments) == 2 && segments[1] == api.containerEdge:
+			require.Equal(t, "user-id", segments[0])
+			fake.containers = append(fake.containers, params)
+			fmt.Fprintf(w, `{"id": "container-%d"}`, len(fake.containers))
+		case r.Method == http.MethodPost && len(segments) == 2 && segments[1] == api.publishEdge:
+			var index int
+			_, err := fmt.Sscanf(params.Get("creation_id"), "container-%d", &index)
+			require.NoError(t, err)
+			fake.published = append(fake.published, fake.containers[index-1])
+			fmt.Fprintf(w, `{"id": "media-%d"}`, len(fake.published))
+		case r.Method == http.MethodGet && strings.HasPrefix(segments[0], "container-"):
+			require.Equal(t, api.statusField+","+api.statusDetailField, params.Get("fields"))
+			fake.polls[segments[0]]++
+			status := "IN_PROGRESS"
+			if fake.polls[segments[0]] > 1 {
+				status = "FINISHED"
+			}
+			fmt.Fprintf(w, `{%q: %q, "id": %q}`, api.statusField, status, segments[0])
+		case r.Method == http.MethodGet && strings.HasPrefix(segments[0], "media-"):
+			fmt.Fprintf(w, `{"permalink": "https://example.com/p/%s/"}`, segments[0])
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": {"message": "Unsupported request", "type": "GraphMethodException", "code": 100}}`)
+		}
+	}))
+	return fake, server
+}
+
+// useFakeServer points a client at a fake server and makes it poll without waiting
+func useFakeServer(client *metaGraphClient, server *httptest.Server) {
+	client.api.host = server.URL
+	client.httpClient = server.Client()
+	client.pollInterval = time.Millisecond
+}
+
+func TestMetaGraphClient_RefreshToken(t *testing.T) {
+	fake, server := newFakeMetaGraphServer(t, threadsGraphAPI)
+	defer server.Close()
+
+	ctx := context.Background()
+	now := time.Date(2023, 8, 1, 8, 0, 0, 0, time.UTC)
+	repository := NewFileSystemRepositoryAdapter(t.TempDir(), nil)
+	newClient := func(accessToken string) *metaGraphClient {
+		client := newMetaGraphClient(threadsGraphAPI, MetaGraphConfig{UserID: "user-id", AccessToken: accessToken, Repository: repository},
+			nil, logger.NewTestLogger())
+		useFakeServer(client, server)
+		client.now = func() time.Time { return now }
+		return client
+	}
+
+	// The age of the configured token is unknown, it is stored and refreshed once it is a day old
+	client := newClient("configured-token")
+	client.refreshToken(ctx)
+	require.Equal(t, 0, fake.refreshes)
+
+	// A restart uses the stored token instead of refreshing the configured token again
+	now = now.Add(time.Hour)
+	newClient("configured-token").refreshToken(ctx)
+	require.Equal(t, 0, fake.refreshes)
+
+	now = now.Add(24 * time.Hour)
+	client.refreshToken(ctx)
+	require.Equal(t, 1, fake.refreshes)
+	require.Equal(t, []string{"configured-token"}, fake.tokens)
+
+	// The refreshed token is used after a restart
+	restarted := newClient("configured-token")
+	restarted.refreshToken(ctx)
+	require.Equal(t, 1, fake.refreshes)
+	require.Equal(t, "refreshed-token", restarted.token.Value)
+	require.Equal(t, time.Date(2023, 10, 1, 9, 0, 0, 0, time.UTC), restarted.token.ExpiresAt)
+
+	// Configuring another token replaces the stored one
+	replaced := newClient("new-token")
+	replaced.refreshToken(ctx)
+	require.Equal(t, 1, fake.refreshes)
+	require.Equal(t, "new-token", replaced.token.Value)
+	stored, err := repository.GetAccessToken(ctx, "threads-user-id")
+	require.NoError(t, err)
+	require.Equal(t, "new-token", stored.Value)
+}
+
+func TestMetaGraphClient_ErrorWithoutToken(t *testing.T) {
+	// Nothing listens on the address of a closed server
+	_, server := newFakeMetaGraphServer(t, instagramGraphAPI)
+	server.Close()
+
+	client := newMetaGraphClient(instagramGraphAPI, MetaGraphConfig{UserID: "user-id", AccessToken: "IGSECRET"}, nil, logger.NewTestLogger())
+	useFakeServer(client, server)
+
+	var response struct{}
+	err := client.request(context.Background(), http.MethodGet, client.path("media-1"), url.Values{"fields": {"permalink"}}, &response)
+	require.ErrorContains(t, err, "failed to send request")
+	require.NotContains(t, err.Error(), "IGSECRET")
+}
+
+func TestMetaGraphClient_PublicImageURL(t *testing.T) {
+	testCases := []struct {
+		name          string
+		baseURL       string
+		image         domain.GeneratedImage
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "Remote Image",
+			image:    domain.GeneratedImage{Path: "https://test.com/test.png"},
+			expected: "https://test.com/test.png",
+		},
+		{
+			name:     "Stored Image",
+			baseURL:  "https://cdn.example.com/images/",
+			image:    domain.GeneratedImage{Path: "data/images/abc123.png", URL: "https://test.com/test.png"},
+			expected: "https://cdn.example.com/images/abc123.png",
+		},
+		{
+			name:     "Stored Image With Public URL",
+			image:    domain.GeneratedImage{Path: "data/images/abc123.png", URL: "https://bucket.s3.amazonaws.com/images/abc123.png"},
+			expected: "https://bucket.s3.amazonaws.com/images/abc123.png",
+		},
+		{
+			name:          "Stored Image Without Base URL",
+			image:         domain.GeneratedImage{Path: "data/images/abc123.png"},
+			expectedError: "Instagram can only publish images at a public url: data/images/abc123.png is a local file and no public image base url is configured",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newMetaGraphClient(instagramGraphAPI, MetaGraphConfig{PublicImageBaseURL: tc.baseURL}, nil, logger.NewTestLogger())
+			imageURL, err := client.publicImageURL(tc.image)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, imageURL)
+		})
+	}
+}
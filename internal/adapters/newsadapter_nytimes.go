@@ -30,54 +30,66 @@ func NewNYTimesNewsAdapter(apiKey string, httpClient httpClient) ports.NewsAdapt
 }
 
 func (n *nyTimesAdapter) GetMainArticle(ctx context.Context) (domain.NewsArticle, error) {
+	articles, err := n.GetTopArticles(ctx)
+	if err != nil {
+		return domain.NewsArticle{}, err
+	}
+	return articles[0], nil
+}
+
+// GetTopArticles returns the top stories of the home page, in the order they are shown on it
+func (n *nyTimesAdapter) GetTopArticles(ctx context.Context) ([]domain.NewsArticle, error) {
 	url := fmt.Sprintf(apiURL, n.apiKey)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return domain.NewsArticle{}, err
+		return nil, err
 	}
 
 	resp, err := n.client.Do(req)
 	if err != nil {
-		return domain.NewsArticle{}, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return domain.NewsArticle{}, fmt.Errorf("failed to fetch data from New York Times API, status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to fetch data from New York Times API, status code: %d", resp.StatusCode)
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return domain.NewsArticle{}, err
+		return nil, err
 	}
 
 	var apiResponse NYTApiResponse
 	err = json.Unmarshal(body, &apiResponse)
 	if err != nil {
-		return domain.NewsArticle{}, err
+		return nil, err
 	}
 
 	if len(apiResponse.Results) == 0 {
-		return domain.NewsArticle{}, errors.New("no articles found")
+		return nil, errors.New("no articles found")
 	}
 
-	mainArticle := apiResponse.Results[0]
-	date, err := time.Parse(time.RFC3339, mainArticle.PublishedDate)
-	if err != nil {
-		return domain.NewsArticle{}, err
+	articles := make([]domain.NewsArticle, 0, len(apiResponse.Results))
+	for _, article := range apiResponse.Results {
+		date, err := time.Parse(time.RFC3339, article.PublishedDate)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, domain.NewsArticle{
+			Title: article.Title,
+			Body:  article.Abstract,
+			Date: domain.Date{
+				Day:   date.Day(),
+				Month: date.Month(),
+				Year:  date.Year(),
+			},
+			Source:  "New York Times",
+			Url:     article.Url,
+			Section: article.Section,
+		})
 	}
-
-	return domain.NewsArticle{
-		Title: mainArticle.Title,
-		Body:  mainArticle.Abstract,
-		Date: domain.Date{
-			Day:   date.Day(),
-			Month: date.Month(),
-			Year:  date.Year(),
-		},
-		Source: "New York Times",
-		Url:    mainArticle.Url,
-	}, nil
+	return articles, nil
 }
 
 type NYTApiResponse struct {
@@ -89,4 +101,5 @@ type NYTArticle struct {
 	Abstract      string `json:"abstract"`
 	PublishedDate string `json:"published_date"`
 	Url           string `json:"url"`
+	Section       string `json:"section"`
 }
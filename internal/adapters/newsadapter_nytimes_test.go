@@ -69,3 +69,24 @@ func TestNYTimesAdapter_GetMainArticle(t *testing.T) {
 		})
 	}
 }
+
+func TestNYTimesAdapter_GetTopArticles(t *testing.T) {
+	mockClient := newMockHttpClient(t)
+	mockClient.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body: ioutil.NopCloser(strings.NewReader(`{
+			"results": [
+				{"title": "Summit held", "section": "world", "published_date": "2022-01-01T00:00:00-05:00"},
+				{"title": "Comet spotted", "section": "science", "published_date": "2022-01-02T00:00:00-05:00"}
+			]
+		}`)),
+	}, nil)
+
+	articles, err := NewNYTimesNewsAdapter("test-api-key", mockClient).GetTopArticles(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(articles) != 2 || articles[0].Section != "world" || articles[1].Title != "Comet spotted" || articles[1].Section != "science" {
+		t.Errorf("Unexpected articles: %+v", articles)
+	}
+}
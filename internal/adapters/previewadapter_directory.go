@@ -0,0 +1,89 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+)
+
+// unnamedChannelDirectory contains the previews of the social medias outside of the channels, the underscore keeps it
+// apart from the directories of the channels
+const unnamedChannelDirectory = "_main"
+
+type directoryPreviewAdapter struct {
+	directory string
+	client    httpClient
+}
+
+// NewDirectoryPreviewAdapter creates a preview adapter that writes each preview into its own subdirectory, named after
+// the channel and the id of the adapter, as a post.txt file together with the downloaded images.
+func NewDirectoryPreviewAdapter(directory string, httpClient httpClient) ports.PreviewAdapter {
+	return &directoryPreviewAdapter{
+		directory: directory,
+		client:    httpClient,
+	}
+}
+
+func (d *directoryPreviewAdapter) RenderPreview(_ context.Context, preview domain.PostPreview) error {
+	channel, adapterID := preview.Channel, preview.AdapterID
+	if channel == "" {
+		channel = unnamedChannelDirectory
+	}
+	if adapterID == "" {
+		adapterID = preview.AdapterName
+	}
+	dir := filepath.Join(d.directory, previewPathElement(channel), previewPathElement(adapterID))
+	// The images of an earlier preview may have other extensions, or there may have been more of them
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clear preview directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create preview directory: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "post.txt"), []byte(formatPreview(preview)), 0o644); err != nil {
+		return fmt.Errorf("failed to write preview: %w", err)
+	}
+
+	for i, image := range preview.Images {
+		if err := d.downloadImage(image.Path, filepath.Join(dir, fmt.Sprintf("image-%d", i+1))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// previewPathElement turns a channel name or adapter id into a directory name, it cannot point outside the directory
+func previewPathElement(name string) string {
+	name = strings.NewReplacer(" ", "_", "/", "_", "\\", "_").Replace(strings.ToLower(name))
+	if name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// downloadImage copies an image to the path, the file extension is derived from the content type
+func (d *directoryPreviewAdapter) downloadImage(image domain.ImagePath, path string) error {
+	imageReader, contentType, err := openImage(d.client, image)
+	if err != nil {
+		return err
+	}
+	defer imageReader.Close()
+
+	file, err := os.Create(path + imageExtension(contentType))
+	if err != nil {
+		return fmt.Errorf("failed to create image file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err = io.Copy(file, imageReader); err != nil {
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectoryPreviewAdapter_RenderPreview(t *testing.T) {
+	mockClient := newMockHttpClient(t)
+	mockClient.On("Get", "https://test.com/test.jpg").Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"image/jpeg"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte("image data"))),
+	}, nil)
+
+	directory := t.TempDir()
+	previewAdapter := NewDirectoryPreviewAdapter(directory, mockClient)
+
+	// A stale image of an earlier preview is removed
+	require.NoError(t, os.MkdirAll(filepath.Join(directory, "science", "twitter-art"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "science", "twitter-art", "image-2.png"), []byte("old"), 0o644))
+
+	err := previewAdapter.RenderPreview(context.Background(), domain.PostPreview{
+		AdapterName: "Twitter",
+		Channel:     "science",
+		AdapterID:   "twitter-art",
+		Text:        "Test Article https://example.com",
+		Replies:     []string{"Created by DALL-E with the prompt:\n\nA test prompt"},
+		Images:      []domain.GeneratedImage{{Path: "https://test.com/test.jpg", Prompt: "A test prompt"}},
+	})
+	require.NoError(t, err)
+
+	post, err := os.ReadFile(filepath.Join(directory, "science", "twitter-art", "post.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "===== Twitter =====\n\n"+
+		"Text:\nTest Article https://example.com\n\n"+
+		"Reply 1:\nCreated by DALL-E with the prompt:\n\nA test prompt\n\n"+
+		"Images:\n1. https://test.com/test.jpg\n   Prompt: A test prompt\n\n", string(post))
+
+	image, err := os.ReadFile(filepath.Join(directory, "science", "twitter-art", "image-1.jpg"))
+	require.NoError(t, err)
+	require.Equal(t, "image data", string(image))
+	require.NoFileExists(t, filepath.Join(directory, "science", "twitter-art", "image-2.png"))
+
+	// Previews of the unnamed channel without an adapter id are named after the adapter
+	err = previewAdapter.RenderPreview(context.Background(), domain.PostPreview{AdapterName: "Twitter", Text: "Test Article"})
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(directory, "_main", "twitter", "post.txt"))
+}
@@ -0,0 +1,41 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+)
+
+type writerPreviewAdapter struct {
+	writer io.Writer
+}
+
+// NewWriterPreviewAdapter creates a preview adapter that writes the previews as text, e.g. to stdout
+func NewWriterPreviewAdapter(writer io.Writer) ports.PreviewAdapter {
+	return &writerPreviewAdapter{writer: writer}
+}
+
+func (w *writerPreviewAdapter) RenderPreview(_ context.Context, preview domain.PostPreview) error {
+	_, err := io.WriteString(w.writer, formatPreview(preview))
+	return err
+}
+
+// formatPreview formats a preview as human-readable text
+func formatPreview(preview domain.PostPreview) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "===== %s =====\n\n", preview.AdapterName)
+	fmt.Fprintf(&sb, "Text:\n%s\n\n", preview.Text)
+	for i, reply := range preview.Replies {
+		fmt.Fprintf(&sb, "Reply %d:\n%s\n\n", i+1, reply)
+	}
+	sb.WriteString("Images:\n")
+	for i, image := range preview.Images {
+		fmt.Fprintf(&sb, "%d. %s\n   Prompt: %s\n", i+1, image.Path, image.Prompt)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,216 @@
+package adapters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+)
+
+type fileSystemRepositoryAdapter struct {
+	directory string
+	client    httpClient // Used for downloading images
+}
+
+// NewFileSystemRepositoryAdapter creates a repository that stores everything as json files and images in a directory
+func NewFileSystemRepositoryAdapter(directory string, httpClient httpClient) ports.RepositoryAdapter {
+	return &fileSystemRepositoryAdapter{
+		directory: directory,
+		client:    httpClient,
+	}
+}
+
+func (f *fileSystemRepositoryAdapter) SavePost(_ context.Context, post domain.Post) error {
+	return writeJSONFile(filepath.Join(f.directory, "posts", strconv.FormatInt(time.Now().UnixNano(), 10)+".json"), post)
+}
+
+func (f *fileSystemRepositoryAdapter) StoreImage(_ context.Context, image domain.GeneratedImage) (domain.GeneratedImage, error) {
+	imageReader, contentType, err := openImage(f.client, image.Path)
+	if err != nil {
+		return domain.GeneratedImage{}, err
+	}
+	defer imageReader.Close()
+
+	directory, err := filepath.Abs(filepath.Join(f.directory, "images"))
+	if err != nil {
+		return domain.GeneratedImage{}, err
+	}
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return domain.GeneratedImage{}, fmt.Errorf("failed to create image directory: %w", err)
+	}
+
+	// Storing the same image twice results in the same path
+	hash := sha256.Sum256([]byte(image.Path))
+	path := filepath.Join(directory, hex.EncodeToString(hash[:8])+imageExtension(contentType))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return domain.GeneratedImage{}, fmt.Errorf("failed to create image file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, imageReader); err != nil {
+		return domain.GeneratedImage{}, fmt.Errorf("failed to store image: %w", err)
+	}
+	// The generated image stays public until its url expires, the stored copy is only public when it is served at the
+	// public image base url
+	if isRemoteImage(image.Path) {
+		image.URL = string(image.Path)
+	}
+	image.Path = domain.ImagePath(path)
+	return image, nil
+}
+
+func (f *fileSystemRepositoryAdapter) SaveDraft(_ context.Context, draft domain.Draft) error {
+	return writeJSONFile(f.draftPath(draft.ID), draft)
+}
+
+func (f *fileSystemRepositoryAdapter) GetDraft(_ context.Context, id string) (domain.Draft, error) {
+	var draft domain.Draft
+	err := readJSONFile(f.draftPath(id), &draft)
+	if errors.Is(err, os.ErrNotExist) {
+		return domain.Draft{}, fmt.Errorf("draft %s: %w", id, domain.ErrNotFound)
+	}
+	return draft, err
+}
+
+func (f *fileSystemRepositoryAdapter) ListDrafts(_ context.Context, status domain.DraftStatus) ([]domain.Draft, error) {
+	paths, err := filepath.Glob(filepath.Join(f.directory, "drafts", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	drafts := make([]domain.Draft, 0, len(paths))
+	for _, path := range paths {
+		var draft domain.Draft
+		if err := readJSONFile(path, &draft); err != nil {
+			return nil, err
+		}
+		if draft.Status == status {
+			drafts = append(drafts, draft)
+		}
+	}
+
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].CreatedAt.Before(drafts[j].CreatedAt)
+	})
+	return drafts, nil
+}
+
+func (f *fileSystemRepositoryAdapter) SaveRun(_ context.Context, run domain.Run) error {
+	return writeJSONFile(f.runPath(run.ID), run)
+}
+
+func (f *fileSystemRepositoryAdapter) GetRun(_ context.Context, id string) (domain.Run, error) {
+	var run domain.Run
+	err := readJSONFile(f.runPath(id), &run)
+	if errors.Is(err, os.ErrNotExist) {
+		return domain.Run{}, fmt.Errorf("run %s: %w", id, domain.ErrNotFound)
+	}
+	return run, err
+}
+
+func (f *fileSystemRepositoryAdapter) ListRuns(_ context.Context, status domain.RunStatus) ([]domain.Run, error) {
+	paths, err := filepath.Glob(filepath.Join(f.directory, "runs", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]domain.Run, 0, len(paths))
+	for _, path := range paths {
+		var run domain.Run
+		if err := readJSONFile(path, &run); err != nil {
+			return nil, err
+		}
+		if run.Status == status {
+			runs = append(runs, run)
+		}
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.Before(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
+
+func (f *fileSystemRepositoryAdapter) SaveAccessToken(_ context.Context, name string, token domain.AccessToken) error {
+	return writeTokenFile(f.tokenPath(name), token)
+}
+
+func (f *fileSystemRepositoryAdapter) GetAccessToken(_ context.Context, name string) (domain.AccessToken, error) {
+	var token domain.AccessToken
+	err := readJSONFile(f.tokenPath(name), &token)
+	if errors.Is(err, os.ErrNotExist) {
+		return domain.AccessToken{}, fmt.Errorf("access token %s: %w", name, domain.ErrNotFound)
+	}
+	return token, err
+}
+
+// draftPath uses the base of the id, which prevents ids like ../../something from escaping the repository directory
+func (f *fileSystemRepositoryAdapter) draftPath(id string) string {
+	return filepath.Join(f.directory, "drafts", filepath.Base(id)+".json")
+}
+
+func (f *fileSystemRepositoryAdapter) runPath(id string) string {
+	return filepath.Join(f.directory, "runs", filepath.Base(id)+".json")
+}
+
+func (f *fileSystemRepositoryAdapter) tokenPath(name string) string {
+	return filepath.Join(f.directory, "tokens", filepath.Base(name)+".json")
+}
+
+// writeJSONFile writes the value as json, the file is replaced atomically so readers never see a partial write
+func writeJSONFile(path string, value interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func readJSONFile(path string, value interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// writeTokenFile stores a token so only the current user can read it
+func writeTokenFile(path string, token domain.AccessToken) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write token: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
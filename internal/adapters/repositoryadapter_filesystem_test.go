@@ -0,0 +1,83 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSystemRepositoryAdapter_Drafts(t *testing.T) {
+	ctx := context.Background()
+	repository := NewFileSystemRepositoryAdapter(t.TempDir(), newMockHttpClient(t))
+
+	older := domain.Draft{ID: "older", Status: domain.DraftStatusPending, CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	newer := domain.Draft{ID: "newer", Status: domain.DraftStatusPending, CreatedAt: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)}
+	rejected := domain.Draft{ID: "rejected", Status: domain.DraftStatusRejected, CreatedAt: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)}
+	for _, draft := range []domain.Draft{newer, rejected, older} {
+		require.NoError(t, repository.SaveDraft(ctx, draft))
+	}
+
+	drafts, err := repository.ListDrafts(ctx, domain.DraftStatusPending)
+	require.NoError(t, err)
+	require.Equal(t, []domain.Draft{older, newer}, drafts)
+
+	draft, err := repository.GetDraft(ctx, "rejected")
+	require.NoError(t, err)
+	require.Equal(t, rejected, draft)
+
+	_, err = repository.GetDraft(ctx, "missing")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestFileSystemRepositoryAdapter_AccessTokens(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	repository := NewFileSystemRepositoryAdapter(directory, newMockHttpClient(t))
+
+	token := domain.AccessToken{Value: "token", Origin: "origin", RefreshedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	require.NoError(t, repository.SaveAccessToken(ctx, "threads-user-id", token))
+
+	stored, err := repository.GetAccessToken(ctx, "threads-user-id")
+	require.NoError(t, err)
+	require.Equal(t, token, stored)
+
+	// Only the current user can read the tokens
+	info, err := os.Stat(filepath.Join(directory, "tokens", "threads-user-id.json"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	_, err = repository.GetAccessToken(ctx, "instagram-user-id")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestFileSystemRepositoryAdapter_StoreImage(t *testing.T) {
+	mockClient := newMockHttpClient(t)
+	mockClient.On("Get", "https://test.com/test.jpg").Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"image/jpeg"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte("image data"))),
+	}, nil)
+
+	directory := t.TempDir()
+	repository := NewFileSystemRepositoryAdapter(directory, mockClient)
+
+	image, err := repository.StoreImage(context.Background(), domain.GeneratedImage{Path: "https://test.com/test.jpg", Prompt: "prompt"})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(directory, "images"), filepath.Dir(string(image.Path)))
+	require.Equal(t, ".jpg", filepath.Ext(string(image.Path)))
+	require.Equal(t, "prompt", image.Prompt)
+	// The generated image is published from its original url until it expires
+	require.Equal(t, "https://test.com/test.jpg", image.URL)
+
+	data, err := os.ReadFile(string(image.Path))
+	require.NoError(t, err)
+	require.Equal(t, "image data", string(data))
+}
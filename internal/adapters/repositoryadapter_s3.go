@@ -0,0 +1,379 @@
+package adapters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+)
+
+// imageURLExpiry is how long the presigned url of a stored image can be downloaded, it is presigned again whenever a
+// run or draft is read
+const imageURLExpiry = 24 * time.Hour
+
+var (
+	runStatuses   = []domain.RunStatus{domain.RunStatusInProgress, domain.RunStatusCompleted}
+	draftStatuses = []domain.DraftStatus{domain.DraftStatusPending, domain.DraftStatusPublished, domain.DraftStatusRejected}
+)
+
+type s3RepositoryAdapter struct {
+	client *awsClient
+	// bucketURL is the url of the bucket without a trailing slash
+	bucketURL string
+	prefix    string
+	// cacheDirectory contains the stored images, the social media adapters publish images from the local filesystem
+	cacheDirectory string
+	httpClient     httpClient // Used for downloading images
+}
+
+// NewS3RepositoryAdapter creates a repository that stores everything as json objects and images in an S3 bucket, e.g.
+// for the lambda whose filesystem does not outlive an invocation. The key of an object is prefix + its path, runs and
+// drafts are stored under their status so listing them does not read every object. Images are also written to the
+// cache directory and restored from the bucket when a stored run or draft refers to an image missing from it. The
+// stored images get a presigned url, so the social medias that download images themselves can publish them.
+func NewS3RepositoryAdapter(config AWSConfig, bucket string, prefix string, cacheDirectory string, httpClient httpClient) ports.RepositoryAdapter {
+	bucketURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, config.Region)
+	if config.Endpoint != "" {
+		// Stubs of S3 usually only support path style urls
+		bucketURL = strings.TrimSuffix(config.Endpoint, "/") + "/" + bucket
+	}
+	return &s3RepositoryAdapter{
+		client:         newAWSClient("s3", config, httpClient),
+		bucketURL:      bucketURL,
+		prefix:         prefix,
+		cacheDirectory: cacheDirectory,
+		httpClient:     httpClient,
+	}
+}
+
+func (s *s3RepositoryAdapter) SavePost(ctx context.Context, post domain.Post) error {
+	return s.putJSON(ctx, s.key("posts", strconv.FormatInt(time.Now().UnixNano(), 10)+".json"), post)
+}
+
+func (s *s3RepositoryAdapter) StoreImage(ctx context.Context, image domain.GeneratedImage) (domain.GeneratedImage, error) {
+	imageReader, contentType, err := openImage(s.httpClient, image.Path)
+	if err != nil {
+		return domain.GeneratedImage{}, err
+	}
+	defer imageReader.Close()
+
+	data, err := io.ReadAll(imageReader)
+	if err != nil {
+		return domain.GeneratedImage{}, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	// Storing the same image twice results in the same path
+	hash := sha256.Sum256([]byte(image.Path))
+	name := hex.EncodeToString(hash[:8]) + imageExtension(contentType)
+	if _, err := s.client.send(ctx, http.MethodPut, s.objectURL(s.key("images", name)), data, imageContentType(filepath.Ext(name))); err != nil {
+		return domain.GeneratedImage{}, fmt.Errorf("failed to store image: %w", err)
+	}
+	if image.Path, err = s.cacheImage(name, data); err != nil {
+		return domain.GeneratedImage{}, err
+	}
+	if image.URL, err = s.imageURL(name); err != nil {
+		return domain.GeneratedImage{}, err
+	}
+	return image, nil
+}
+
+func (s *s3RepositoryAdapter) SaveDraft(ctx context.Context, draft domain.Draft) error {
+	if err := s.putJSON(ctx, s.draftKey(draft.Status, draft.ID), draft); err != nil {
+		return err
+	}
+	// The draft moved when its status changed
+	for _, status := range draftStatuses {
+		if status != draft.Status {
+			if err := s.deleteObject(ctx, s.draftKey(status, draft.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *s3RepositoryAdapter) GetDraft(ctx context.Context, id string) (domain.Draft, error) {
+	for _, status := range draftStatuses {
+		var draft domain.Draft
+		err := s.getJSON(ctx, s.draftKey(status, id), &draft)
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return domain.Draft{}, err
+		}
+		return draft, s.restoreDraftImages(ctx, draft)
+	}
+	return domain.Draft{}, fmt.Errorf("draft %s: %w", id, domain.ErrNotFound)
+}
+
+func (s *s3RepositoryAdapter) ListDrafts(ctx context.Context, status domain.DraftStatus) ([]domain.Draft, error) {
+	keys, err := s.listKeys(ctx, s.key("drafts", string(status))+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	drafts := make([]domain.Draft, 0, len(keys))
+	for _, key := range keys {
+		var draft domain.Draft
+		if err := s.getJSON(ctx, key, &draft); err != nil {
+			return nil, err
+		}
+		if err := s.restoreDraftImages(ctx, draft); err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, draft)
+	}
+
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].CreatedAt.Before(drafts[j].CreatedAt)
+	})
+	return drafts, nil
+}
+
+func (s *s3RepositoryAdapter) SaveRun(ctx context.Context, run domain.Run) error {
+	if err := s.putJSON(ctx, s.runKey(run.Status, run.ID), run); err != nil {
+		return err
+	}
+	for _, status := range runStatuses {
+		if status != run.Status {
+			if err := s.deleteObject(ctx, s.runKey(status, run.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *s3RepositoryAdapter) GetRun(ctx context.Context, id string) (domain.Run, error) {
+	for _, status := range runStatuses {
+		var run domain.Run
+		err := s.getJSON(ctx, s.runKey(status, id), &run)
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return domain.Run{}, err
+		}
+		return run, s.restoreImages(ctx, run.Images)
+	}
+	return domain.Run{}, fmt.Errorf("run %s: %w", id, domain.ErrNotFound)
+}
+
+func (s *s3RepositoryAdapter) ListRuns(ctx context.Context, status domain.RunStatus) ([]domain.Run, error) {
+	keys, err := s.listKeys(ctx, s.key("runs", string(status))+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]domain.Run, 0, len(keys))
+	for _, key := range keys {
+		var run domain.Run
+		if err := s.getJSON(ctx, key, &run); err != nil {
+			return nil, err
+		}
+		if err := s.restoreImages(ctx, run.Images); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.Before(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
+
+func (s *s3RepositoryAdapter) SaveAccessToken(ctx context.Context, name string, token domain.AccessToken) error {
+	return s.putJSON(ctx, s.key("tokens", name+".json"), token)
+}
+
+func (s *s3RepositoryAdapter) GetAccessToken(ctx context.Context, name string) (domain.AccessToken, error) {
+	var token domain.AccessToken
+	if err := s.getJSON(ctx, s.key("tokens", name+".json"), &token); err != nil {
+		return domain.AccessToken{}, err
+	}
+	return token, nil
+}
+
+// key joins the path to the prefix, the base of the last element is used so ids cannot point outside their directory
+func (s *s3RepositoryAdapter) key(elements ...string) string {
+	elements[len(elements)-1] = path.Base(elements[len(elements)-1])
+	return s.prefix + path.Join(elements...)
+}
+
+func (s *s3RepositoryAdapter) draftKey(status domain.DraftStatus, id string) string {
+	return s.key("drafts", string(status), id+".json")
+}
+
+func (s *s3RepositoryAdapter) runKey(status domain.RunStatus, id string) string {
+	return s.key("runs", string(status), id+".json")
+}
+
+func (s *s3RepositoryAdapter) objectURL(key string) string {
+	return s.bucketURL + "/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+func (s *s3RepositoryAdapter) putJSON(ctx context.Context, key string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", path.Base(key), err)
+	}
+	if _, err := s.client.send(ctx, http.MethodPut, s.objectURL(key), data, "application/json"); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return nil
+}
+
+// getJSON returns an error wrapping domain.ErrNotFound when the object does not exist
+func (s *s3RepositoryAdapter) getJSON(ctx context.Context, key string, value interface{}) error {
+	data, err := s.getObject(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", path.Base(key), err)
+	}
+	return nil
+}
+
+func (s *s3RepositoryAdapter) getObject(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.client.send(ctx, http.MethodGet, s.objectURL(key), nil, "")
+	var statusErr *awsStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", key, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// deleteObject succeeds when the object does not exist
+func (s *s3RepositoryAdapter) deleteObject(ctx context.Context, key string) error {
+	if _, err := s.client.send(ctx, http.MethodDelete, s.objectURL(key), nil, ""); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// listKeys returns the keys of all objects starting with the prefix, following the continuation tokens of
+// ListObjectsV2
+func (s *s3RepositoryAdapter) listKeys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	continuationToken := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		data, err := s.client.send(ctx, http.MethodGet, s.bucketURL+"/?"+query.Encode(), nil, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal list of %s: %w", prefix, err)
+		}
+		for _, content := range result.Contents {
+			keys = append(keys, content.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+func (s *s3RepositoryAdapter) imageDirectory() (string, error) {
+	return filepath.Abs(filepath.Join(s.cacheDirectory, "images"))
+}
+
+func (s *s3RepositoryAdapter) cacheImage(name string, data []byte) (domain.ImagePath, error) {
+	directory, err := s.imageDirectory()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create image directory: %w", err)
+	}
+	imagePath := filepath.Join(directory, name)
+	if err := os.WriteFile(imagePath, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to cache image: %w", err)
+	}
+	return domain.ImagePath(imagePath), nil
+}
+
+// imageURL presigns the url of the stored image with the name
+func (s *s3RepositoryAdapter) imageURL(name string) (string, error) {
+	imageURL, err := s.client.presign(s.objectURL(s.key("images", name)), imageURLExpiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign image url: %w", err)
+	}
+	return imageURL, nil
+}
+
+func (s *s3RepositoryAdapter) restoreDraftImages(ctx context.Context, draft domain.Draft) error {
+	if err := s.restoreImages(ctx, draft.Post.Images); err != nil {
+		return err
+	}
+	for _, preview := range draft.Previews {
+		if err := s.restoreImages(ctx, preview.Images); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreImages downloads the stored images missing from the cache directory, e.g. because they were stored by an
+// earlier lambda invocation, and presigns their urls again since the stored ones may have expired. Images that were
+// not stored by the repository are left alone.
+func (s *s3RepositoryAdapter) restoreImages(ctx context.Context, images []domain.GeneratedImage) error {
+	directory, err := s.imageDirectory()
+	if err != nil {
+		return err
+	}
+	for i, image := range images {
+		imagePath := string(image.Path)
+		if imagePath == "" || filepath.Dir(imagePath) != directory {
+			continue
+		}
+		if images[i].URL, err = s.imageURL(filepath.Base(imagePath)); err != nil {
+			return err
+		}
+		if _, err := os.Stat(imagePath); err == nil {
+			continue
+		}
+		data, err := s.getObject(ctx, s.key("images", filepath.Base(imagePath)))
+		if err != nil {
+			return fmt.Errorf("failed to restore image: %w", err)
+		}
+		if _, err := s.cacheImage(filepath.Base(imagePath), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
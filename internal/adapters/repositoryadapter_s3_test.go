@@ -0,0 +1,173 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// newStubS3Server stubs an S3 bucket named bucket with path style urls, keeping the objects in the map
+func newStubS3Server(t *testing.T, objects map[string][]byte) *httptest.Server {
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+		require.Contains(t, r.Header.Get("Authorization"), "/s3/aws4_request")
+		require.NotEmpty(t, r.Header.Get("X-Amz-Content-Sha256"))
+
+		mu.Lock()
+		defer mu.Unlock()
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/bucket/":
+			require.Equal(t, "2", r.URL.Query().Get("list-type"))
+			var keys []string
+			for key := range objects {
+				if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			fmt.Fprint(w, `<ListBucketResult><IsTruncated>false</IsTruncated>`)
+			for _, key := range keys {
+				fmt.Fprintf(w, `<Contents><Key>%s</Key></Contents>`, key)
+			}
+			fmt.Fprint(w, `</ListBucketResult>`)
+		case r.Method == http.MethodGet:
+			data, exists := objects[key]
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+				return
+			}
+			_, _ = w.Write(data)
+		case r.Method == http.MethodPut:
+			data, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			objects[key] = data
+		case r.Method == http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestS3RepositoryAdapter_Runs(t *testing.T) {
+	ctx := context.Background()
+	objects := map[string][]byte{}
+	server := newStubS3Server(t, objects)
+	repository := NewS3RepositoryAdapter(testAWSConfig(server.URL), "bucket", "content-generator/", t.TempDir(), server.Client())
+
+	older := domain.Run{ID: "older", Status: domain.RunStatusInProgress, CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	newer := domain.Run{ID: "newer", Status: domain.RunStatusInProgress, CreatedAt: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)}
+	for _, run := range []domain.Run{newer, older} {
+		require.NoError(t, repository.SaveRun(ctx, run))
+	}
+
+	runs, err := repository.ListRuns(ctx, domain.RunStatusInProgress)
+	require.NoError(t, err)
+	require.Equal(t, []domain.Run{older, newer}, runs)
+
+	// Completing a run moves it out of the runs in progress
+	newer.Status = domain.RunStatusCompleted
+	require.NoError(t, repository.SaveRun(ctx, newer))
+	require.NotContains(t, objects, "content-generator/runs/in_progress/newer.json")
+
+	runs, err = repository.ListRuns(ctx, domain.RunStatusInProgress)
+	require.NoError(t, err)
+	require.Equal(t, []domain.Run{older}, runs)
+
+	run, err := repository.GetRun(ctx, "newer")
+	require.NoError(t, err)
+	require.Equal(t, newer, run)
+
+	_, err = repository.GetRun(ctx, "missing")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestS3RepositoryAdapter_Drafts(t *testing.T) {
+	ctx := context.Background()
+	server := newStubS3Server(t, map[string][]byte{})
+	repository := NewS3RepositoryAdapter(testAWSConfig(server.URL), "bucket", "", t.TempDir(), server.Client())
+
+	pending := domain.Draft{ID: "pending", Status: domain.DraftStatusPending, CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	rejected := domain.Draft{ID: "rejected", Status: domain.DraftStatusRejected, CreatedAt: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)}
+	for _, draft := range []domain.Draft{pending, rejected} {
+		require.NoError(t, repository.SaveDraft(ctx, draft))
+	}
+
+	drafts, err := repository.ListDrafts(ctx, domain.DraftStatusPending)
+	require.NoError(t, err)
+	require.Equal(t, []domain.Draft{pending}, drafts)
+
+	draft, err := repository.GetDraft(ctx, "rejected")
+	require.NoError(t, err)
+	require.Equal(t, rejected, draft)
+
+	_, err = repository.GetDraft(ctx, "missing")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+// TestS3RepositoryAdapter_RestoresImages stores an image like one lambda invocation and reads the run like the next
+// one, whose filesystem no longer contains the image
+func TestS3RepositoryAdapter_AccessTokens(t *testing.T) {
+	ctx := context.Background()
+	objects := map[string][]byte{}
+	server := newStubS3Server(t, objects)
+	repository := NewS3RepositoryAdapter(testAWSConfig(server.URL), "bucket", "content-generator/", t.TempDir(), server.Client())
+
+	token := domain.AccessToken{Value: "token", Origin: "origin", RefreshedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	require.NoError(t, repository.SaveAccessToken(ctx, "threads-user-id", token))
+	require.Contains(t, objects, "content-generator/tokens/threads-user-id.json")
+
+	stored, err := repository.GetAccessToken(ctx, "threads-user-id")
+	require.NoError(t, err)
+	require.Equal(t, token, stored)
+
+	_, err = repository.GetAccessToken(ctx, "instagram-user-id")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestS3RepositoryAdapter_RestoresImages(t *testing.T) {
+	ctx := context.Background()
+	objects := map[string][]byte{}
+	server := newStubS3Server(t, objects)
+
+	imagePath := filepath.Join(t.TempDir(), "generated.jpg")
+	require.NoError(t, os.WriteFile(imagePath, []byte("image data"), 0o644))
+
+	repository := NewS3RepositoryAdapter(testAWSConfig(server.URL), "bucket", "", t.TempDir(), server.Client())
+	storedImage, err := repository.StoreImage(ctx, domain.GeneratedImage{Path: domain.ImagePath(imagePath), Prompt: "prompt"})
+	require.NoError(t, err)
+	storedPath := string(storedImage.Path)
+	require.Equal(t, ".jpg", filepath.Ext(storedPath))
+	require.Equal(t, "prompt", storedImage.Prompt)
+	require.Equal(t, []byte("image data"), objects["images/"+filepath.Base(storedPath)])
+	// The social medias that download images themselves publish the stored image from its presigned url
+	require.True(t, strings.HasPrefix(storedImage.URL, server.URL+"/bucket/images/"+filepath.Base(storedPath)+"?"))
+	require.Contains(t, storedImage.URL, "X-Amz-Signature=")
+
+	run := domain.Run{ID: "run", Status: domain.RunStatusInProgress, Images: []domain.GeneratedImage{{Path: storedImage.Path, URL: "expired"}}}
+	require.NoError(t, repository.SaveRun(ctx, run))
+	require.NoError(t, os.Remove(storedPath))
+
+	run, err = repository.GetRun(ctx, "run")
+	require.NoError(t, err)
+	require.Equal(t, storedImage.URL, run.Images[0].URL)
+	data, err := os.ReadFile(storedPath)
+	require.NoError(t, err)
+	require.Equal(t, "image data", string(data))
+}
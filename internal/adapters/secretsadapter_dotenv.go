@@ -0,0 +1,32 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/joho/godotenv"
+)
+
+type dotenvSecretsAdapter struct {
+	path    string
+	secrets map[string]string
+}
+
+// NewDotenvSecretsAdapter creates an adapter reading secrets from a .env file, without adding them to the environment
+func NewDotenvSecretsAdapter(path string) (ports.SecretsAdapter, error) {
+	secrets, err := godotenv.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return &dotenvSecretsAdapter{path: path, secrets: secrets}, nil
+}
+
+func (d *dotenvSecretsAdapter) GetSecret(_ context.Context, name string) (string, error) {
+	value, exists := d.secrets[name]
+	if !exists {
+		return "", fmt.Errorf("%s in %s: %w", name, d.path, domain.ErrNotFound)
+	}
+	return value, nil
+}
@@ -0,0 +1,31 @@
+package adapters
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDotenvSecretsAdapter_GetSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte("OPENAI_KEY=sk-123\nINSTAGRAM_PASSWORD=\"p@ss word\"\n"), 0600))
+
+	adapter, err := NewDotenvSecretsAdapter(path)
+	require.NoError(t, err)
+
+	value, err := adapter.GetSecret(context.Background(), "INSTAGRAM_PASSWORD")
+	require.NoError(t, err)
+	require.Equal(t, "p@ss word", value)
+	_, isSet := os.LookupEnv("INSTAGRAM_PASSWORD")
+	require.False(t, isSet)
+
+	_, err = adapter.GetSecret(context.Background(), "TWITTER_API_KEY")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+
+	_, err = NewDotenvSecretsAdapter(filepath.Join(t.TempDir(), ".env"))
+	require.Error(t, err)
+}
@@ -0,0 +1,27 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+)
+
+type envSecretsAdapter struct {
+	prefix string
+}
+
+// NewEnvSecretsAdapter creates an adapter reading secrets from the environment variables named prefix + name
+func NewEnvSecretsAdapter(prefix string) ports.SecretsAdapter {
+	return &envSecretsAdapter{prefix: prefix}
+}
+
+func (e *envSecretsAdapter) GetSecret(_ context.Context, name string) (string, error) {
+	value, exists := os.LookupEnv(e.prefix + name)
+	if !exists {
+		return "", fmt.Errorf("environment variable %s: %w", e.prefix+name, domain.ErrNotFound)
+	}
+	return value, nil
+}
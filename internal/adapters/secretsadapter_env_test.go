@@ -0,0 +1,21 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvSecretsAdapter_GetSecret(t *testing.T) {
+	t.Setenv("SECRET_OPENAI_KEY", "sk-123")
+	adapter := NewEnvSecretsAdapter("SECRET_")
+
+	value, err := adapter.GetSecret(context.Background(), "OPENAI_KEY")
+	require.NoError(t, err)
+	require.Equal(t, "sk-123", value)
+
+	_, err = adapter.GetSecret(context.Background(), "SECRET_THAT_DOES_NOT_EXIST")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+}
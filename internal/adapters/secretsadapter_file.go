@@ -0,0 +1,38 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+)
+
+type fileSecretsAdapter struct {
+	directory string
+}
+
+// NewFileSecretsAdapter creates an adapter reading each secret from the file with its name in the directory, the way
+// Docker and Kubernetes mount secrets
+func NewFileSecretsAdapter(directory string) ports.SecretsAdapter {
+	return &fileSecretsAdapter{directory: directory}
+}
+
+func (f *fileSecretsAdapter) GetSecret(_ context.Context, name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(f.directory, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("secret file %s: %w", name, domain.ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file %s: %w", name, err)
+	}
+	// Files written by editors and echo end with a newline that is not part of the secret
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,27 @@
+package adapters
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSecretsAdapter_GetSecret(t *testing.T) {
+	directory := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "OPENAI_KEY"), []byte("sk-123\n"), 0600))
+	adapter := NewFileSecretsAdapter(directory)
+
+	value, err := adapter.GetSecret(context.Background(), "OPENAI_KEY")
+	require.NoError(t, err)
+	require.Equal(t, "sk-123", value)
+
+	_, err = adapter.GetSecret(context.Background(), "TWITTER_API_KEY")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+
+	_, err = adapter.GetSecret(context.Background(), "../OPENAI_KEY")
+	require.EqualError(t, err, `invalid secret name "../OPENAI_KEY"`)
+}
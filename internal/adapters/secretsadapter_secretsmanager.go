@@ -0,0 +1,55 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+)
+
+type secretsManagerSecretsAdapter struct {
+	client *awsClient
+	prefix string
+}
+
+// NewSecretsManagerSecretsAdapter creates an adapter reading secrets from AWS Secrets Manager, the id of the secret is
+// prefix + name. Secrets Manager usually stores several values in one JSON secret, a value is selected with
+// name#key, e.g. content-generator#OPENAI_KEY.
+func NewSecretsManagerSecretsAdapter(config AWSConfig, prefix string, httpClient httpClient) ports.SecretsAdapter {
+	return &secretsManagerSecretsAdapter{
+		client: newAWSClient("secretsmanager", config, httpClient),
+		prefix: prefix,
+	}
+}
+
+func (s *secretsManagerSecretsAdapter) GetSecret(ctx context.Context, name string) (string, error) {
+	secretID, key, selectsKey := strings.Cut(s.prefix+name, "#")
+	var output struct {
+		SecretString string `json:"SecretString"`
+	}
+	err := s.client.call(ctx, "secretsmanager.GetSecretValue", map[string]any{"SecretId": secretID}, &output)
+	var awsErr *awsError
+	if errors.As(err, &awsErr) && awsErr.Type == "ResourceNotFoundException" {
+		return "", fmt.Errorf("secret %s: %w", secretID, domain.ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s: %w", secretID, err)
+	}
+	if !selectsKey {
+		return output.SecretString, nil
+	}
+
+	var values map[string]string
+	if err = json.Unmarshal([]byte(output.SecretString), &values); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object of strings: %w", secretID, err)
+	}
+	value, exists := values[key]
+	if !exists {
+		return "", fmt.Errorf("key %s of secret %s: %w", key, secretID, domain.ErrNotFound)
+	}
+	return value, nil
+}
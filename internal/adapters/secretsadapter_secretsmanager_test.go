@@ -0,0 +1,31 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretsManagerSecretsAdapter_GetSecret(t *testing.T) {
+	server := newStubAWSServer(t, "secretsmanager.GetSecretValue", "SecretId", map[string]string{
+		"content-generator":        `{"SecretString": "{\"OPENAI_KEY\": \"sk-123\"}"}`,
+		"content-generator-openai": `{"SecretString": "sk-456"}`,
+	}, "ResourceNotFoundException")
+	adapter := NewSecretsManagerSecretsAdapter(testAWSConfig(server.URL), "content-generator", server.Client())
+
+	value, err := adapter.GetSecret(context.Background(), "#OPENAI_KEY")
+	require.NoError(t, err)
+	require.Equal(t, "sk-123", value)
+
+	value, err = adapter.GetSecret(context.Background(), "-openai")
+	require.NoError(t, err)
+	require.Equal(t, "sk-456", value)
+
+	_, err = adapter.GetSecret(context.Background(), "#TWITTER_API_KEY")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+
+	_, err = adapter.GetSecret(context.Background(), "-twitter")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+}
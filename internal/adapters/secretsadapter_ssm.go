@@ -0,0 +1,42 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+)
+
+type ssmSecretsAdapter struct {
+	client *awsClient
+	prefix string
+}
+
+// NewSSMSecretsAdapter creates an adapter reading secrets from the SSM Parameter Store, the name of the parameter is
+// prefix + name, e.g. /content-generator/OPENAI_KEY. SecureString parameters are decrypted.
+func NewSSMSecretsAdapter(config AWSConfig, prefix string, httpClient httpClient) ports.SecretsAdapter {
+	return &ssmSecretsAdapter{
+		client: newAWSClient("ssm", config, httpClient),
+		prefix: prefix,
+	}
+}
+
+func (s *ssmSecretsAdapter) GetSecret(ctx context.Context, name string) (string, error) {
+	input := map[string]any{"Name": s.prefix + name, "WithDecryption": true}
+	var output struct {
+		Parameter struct {
+			Value string `json:"Value"`
+		} `json:"Parameter"`
+	}
+	err := s.client.call(ctx, "AmazonSSM.GetParameter", input, &output)
+	var awsErr *awsError
+	if errors.As(err, &awsErr) && awsErr.Type == "ParameterNotFound" {
+		return "", fmt.Errorf("parameter %s: %w", s.prefix+name, domain.ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get parameter %s: %w", s.prefix+name, err)
+	}
+	return output.Parameter.Value, nil
+}
@@ -0,0 +1,23 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSMSecretsAdapter_GetSecret(t *testing.T) {
+	server := newStubAWSServer(t, "AmazonSSM.GetParameter", "Name", map[string]string{
+		"/content-generator/OPENAI_KEY": `{"Parameter": {"Name": "/content-generator/OPENAI_KEY", "Type": "SecureString", "Value": "sk-123"}}`,
+	}, "ParameterNotFound")
+	adapter := NewSSMSecretsAdapter(testAWSConfig(server.URL), "/content-generator/", server.Client())
+
+	value, err := adapter.GetSecret(context.Background(), "OPENAI_KEY")
+	require.NoError(t, err)
+	require.Equal(t, "sk-123", value)
+
+	_, err = adapter.GetSecret(context.Background(), "TWITTER_API_KEY")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+}
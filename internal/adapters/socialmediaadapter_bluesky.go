@@ -0,0 +1,362 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png" // DALL-E images are PNGs, which are re-encoded when they exceed the blob size limit
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+const (
+	// blueskyMaxGraphemes is the length limit of a Bluesky post
+	blueskyMaxGraphemes = 300
+	// blueskyMaxBlobBytes is the size limit of an image blob
+	blueskyMaxBlobBytes = 1_000_000
+	// blueskyMaxAltTextCharacters keeps the alt text well within what the Bluesky app displays
+	blueskyMaxAltTextCharacters = 2000
+)
+
+// BlueskyConfig configures the Bluesky adapter
+type BlueskyConfig struct {
+	// Service is the base url of the personal data server, defaults to https://bsky.social
+	Service string
+	// Identifier is the handle or DID of the account, e.g. news.bsky.social
+	Identifier string
+	// AppPassword is an app password created in the Bluesky settings, not the account password
+	AppPassword string
+}
+
+type blueskyAdapter struct {
+	config     BlueskyConfig
+	httpClient httpClient // Used for the Bluesky API and downloading images
+	logger     logger.Logger
+	now        func() time.Time
+}
+
+type blueskySession struct {
+	AccessJwt string `json:"accessJwt"`
+	Did       string `json:"did"`
+	Handle    string `json:"handle"`
+}
+
+// blueskyStrongRef references a record, used for replies
+type blueskyStrongRef struct {
+	URI string `json:"uri"`
+	Cid string `json:"cid"`
+}
+
+type blueskyPost struct {
+	Type      string          `json:"$type"`
+	Text      string          `json:"text"`
+	CreatedAt string          `json:"createdAt"`
+	Facets    []blueskyFacet  `json:"facets,omitempty"`
+	Embed     *blueskyEmbed   `json:"embed,omitempty"`
+	Reply     *blueskyReplyTo `json:"reply,omitempty"`
+}
+
+type blueskyFacet struct {
+	Index struct {
+		ByteStart int `json:"byteStart"`
+		ByteEnd   int `json:"byteEnd"`
+	} `json:"index"`
+	Features []blueskyFacetFeature `json:"features"`
+}
+
+type blueskyFacetFeature struct {
+	Type string `json:"$type"`
+	URI  string `json:"uri"`
+}
+
+type blueskyEmbed struct {
+	Type   string              `json:"$type"`
+	Images []blueskyEmbedImage `json:"images"`
+}
+
+type blueskyEmbedImage struct {
+	Image json.RawMessage `json:"image"`
+	Alt   string          `json:"alt"`
+}
+
+type blueskyReplyTo struct {
+	Root   blueskyStrongRef `json:"root"`
+	Parent blueskyStrongRef `json:"parent"`
+}
+
+func NewBlueskySocialMediaAdapter(config BlueskyConfig, httpClient httpClient, logger logger.Logger) ports.SocialMediaAdapter {
+	config.Service = strings.TrimSuffix(config.Service, "/")
+	if config.Service == "" {
+		config.Service = "https://bsky.social"
+	}
+	return &blueskyAdapter{
+		config:     config,
+		httpClient: httpClient,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+func (b *blueskyAdapter) PublishImagePost(ctx context.Context, post domain.Post) (domain.PublishedPost, error) {
+	preview := b.PreviewImagePost(post)
+
+	session, err := b.createSession(ctx)
+	if err != nil {
+		return domain.PublishedPost{}, err
+	}
+
+	embed := &blueskyEmbed{Type: "app.bsky.embed.images"}
+	for _, generatedImage := range preview.Images {
+		blob, err := b.uploadBlob(ctx, session, generatedImage.Path)
+		if err != nil {
+			return domain.PublishedPost{}, err
+		}
+		embed.Images = append(embed.Images, blueskyEmbedImage{Image: blob, Alt: truncateRunes(generatedImage.Prompt, blueskyMaxAltTextCharacters)})
+	}
+
+	root, err := b.createPost(ctx, session, blueskyPost{
+		Text:   preview.Text,
+		Facets: createLinkFacets(preview.Text, post.NewsArticle.Url),
+		Embed:  embed,
+	})
+	if err != nil {
+		return domain.PublishedPost{}, err
+	}
+	publishedPost := domain.PublishedPost{ID: root.URI, URL: blueskyPostURL(session.Handle, root.URI)}
+
+	_, err = b.createPost(ctx, session, blueskyPost{
+		Text:  preview.Replies[0],
+		Reply: &blueskyReplyTo{Root: root, Parent: root},
+	})
+	if err != nil {
+		return publishedPost, fmt.Errorf("failed to reply to bluesky post: %w", err)
+	}
+	return publishedPost, nil
+}
+
+func (b *blueskyAdapter) PreviewImagePost(post domain.Post) domain.PostPreview {
+	images := post.Images
+	if len(images) > domain.MaxImagesPerPost {
+		b.logger.Warn("Bluesky only supports up to 4 images per post, extra images are dropped", "images", len(images))
+		images = images[:domain.MaxImagesPerPost]
+	}
+
+	return domain.PostPreview{
+		AdapterName: b.GetName(),
+		Text:        b.truncateString(post.NewsArticle.Title + " " + post.NewsArticle.Url),
+		Replies:     []string{b.truncateString(createPromptReply(post.ImageGeneratorName, images))},
+		Images:      images,
+	}
+}
+
+func (b *blueskyAdapter) GetName() string {
+	return "Bluesky"
+}
+
+func (b *blueskyAdapter) createSession(ctx context.Context) (blueskySession, error) {
+	jsonBytes, err := json.Marshal(map[string]string{"identifier": b.config.Identifier, "password": b.config.AppPassword})
+	if err != nil {
+		return blueskySession{}, fmt.Errorf("failed to marshal session data: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.config.Service+"/xrpc/com.atproto.server.createSession", bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return blueskySession{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var session blueskySession
+	if err = b.do(req, &session); err != nil {
+		return blueskySession{}, fmt.Errorf("failed to create bluesky session: %w", err)
+	}
+	return session, nil
+}
+
+// uploadBlob uploads an image and returns the blob reference to embed in a post
+func (b *blueskyAdapter) uploadBlob(ctx context.Context, session blueskySession, imagePath domain.ImagePath) (json.RawMessage, error) {
+	imageReader, contentType, err := openImage(b.httpClient, imagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer imageReader.Close()
+
+	imgData, err := io.ReadAll(imageReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image data: %w", err)
+	}
+	if len(imgData) > blueskyMaxBlobBytes {
+		b.logger.Debug("Image exceeds the bluesky blob size limit, re-encoding it as a JPEG", "bytes", len(imgData))
+		imgData, err = shrinkJPEG(imgData, blueskyMaxBlobBytes)
+		if err != nil {
+			return nil, err
+		}
+		contentType = "image/jpeg"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.config.Service+"/xrpc/com.atproto.repo.uploadBlob", bytes.NewReader(imgData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+session.AccessJwt)
+
+	var response struct {
+		Blob json.RawMessage `json:"blob"`
+	}
+	if err = b.do(req, &response); err != nil {
+		return nil, fmt.Errorf("failed to upload image to bluesky: %w", err)
+	}
+	return response.Blob, nil
+}
+
+func (b *blueskyAdapter) createPost(ctx context.Context, session blueskySession, post blueskyPost) (blueskyStrongRef, error) {
+	post.Type = "app.bsky.feed.post"
+	post.CreatedAt = b.now().UTC().Format(time.RFC3339)
+
+	jsonBytes, err := json.Marshal(map[string]any{
+		"repo":       session.Did,
+		"collection": "app.bsky.feed.post",
+		"record":     post,
+	})
+	if err != nil {
+		return blueskyStrongRef{}, fmt.Errorf("failed to marshal post data: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.config.Service+"/xrpc/com.atproto.repo.createRecord", bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return blueskyStrongRef{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+session.AccessJwt)
+
+	var ref blueskyStrongRef
+	if err = b.do(req, &ref); err != nil {
+		return blueskyStrongRef{}, fmt.Errorf("failed to post to bluesky: %w", err)
+	}
+	return ref, nil
+}
+
+// do sends a request, decoding the response body into v when the request succeeded
+func (b *blueskyAdapter) do(req *http.Request, v any) error {
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		b.logger.Error("Bluesky request failed", "url", req.URL.String(), "response body", string(bodyBytes))
+		return fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	if err = json.Unmarshal(bodyBytes, v); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// truncateString shortens a string to the 300 grapheme limit of a Bluesky post.
+func (b *blueskyAdapter) truncateString(s string) string {
+	graphemes := splitGraphemes(s)
+
+	if len(graphemes) > blueskyMaxGraphemes {
+		b.logger.Warn("Bluesky post was truncated to 300 graphemes", "full post", s)
+		graphemes = graphemes[:blueskyMaxGraphemes]
+	}
+
+	return strings.Join(graphemes, "")
+}
+
+// createLinkFacets marks the url in the text as a link, facets index the UTF-8 bytes of the text.
+// No facet is created when the url was cut off by truncation.
+func createLinkFacets(text string, url string) []blueskyFacet {
+	start := strings.LastIndex(text, url)
+	if url == "" || start == -1 {
+		return nil
+	}
+	var facet blueskyFacet
+	facet.Index.ByteStart = start
+	facet.Index.ByteEnd = start + len(url)
+	facet.Features = []blueskyFacetFeature{{Type: "app.bsky.richtext.facet#link", URI: url}}
+	return []blueskyFacet{facet}
+}
+
+// blueskyPostURL converts the AT URI of a post, at://<did>/app.bsky.feed.post/<rkey>, to its url in the Bluesky app
+func blueskyPostURL(handle string, uri string) string {
+	rkey := uri[strings.LastIndex(uri, "/")+1:]
+	return fmt.Sprintf("https://bsky.app/profile/%s/post/%s", handle, rkey)
+}
+
+// splitGraphemes approximates splitting a string into user-perceived characters. Combining marks, variation selectors,
+// emoji modifiers and zero width joiner sequences stay attached to the preceding character and regional indicators
+// are paired into flags.
+func splitGraphemes(s string) []string {
+	graphemes := make([]string, 0, utf8.RuneCountInString(s))
+	joinNext := false
+	regionalIndicators := 0
+	for i, r := range s {
+		size := utf8.RuneLen(r)
+		extends := unicode.In(r, unicode.Mn, unicode.Me) ||
+			(r >= 0xFE00 && r <= 0xFE0F) || // variation selectors
+			(r >= 0x1F3FB && r <= 0x1F3FF) || // emoji skin tone modifiers
+			(r >= 0xE0020 && r <= 0xE007F) || // emoji tag sequences
+			r == '\u200d'
+		isRegionalIndicator := r >= 0x1F1E6 && r <= 0x1F1FF
+		pairsFlag := isRegionalIndicator && regionalIndicators%2 == 1
+
+		if len(graphemes) > 0 && (extends || joinNext || pairsFlag) {
+			graphemes[len(graphemes)-1] += s[i : i+size]
+		} else {
+			graphemes = append(graphemes, s[i:i+size])
+		}
+
+		joinNext = r == '\u200d'
+		if isRegionalIndicator {
+			regionalIndicators++
+		} else {
+			regionalIndicators = 0
+		}
+	}
+	return graphemes
+}
+
+// truncateRunes shortens a string to at most max characters
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) > max {
+		return string(runes[:max])
+	}
+	return s
+}
+
+// shrinkJPEG re-encodes an image as a JPEG, lowering the quality until it is at most maxBytes large
+func shrinkJPEG(imgData []byte, maxBytes int) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(imgData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	for quality := 90; quality > 0; quality -= 20 {
+		var buf bytes.Buffer
+		if err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, fmt.Errorf("failed to encode image: %w", err)
+		}
+		if buf.Len() <= maxBytes {
+			return buf.Bytes(), nil
+		}
+	}
+	return nil, fmt.Errorf("image is larger than %d bytes even at the lowest quality", maxBytes)
+}
@@ -0,0 +1,139 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlueskyAdapter_PublishImagePost(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "image.png")
+	require.NoError(t, os.WriteFile(imagePath, []byte("image data"), 0o644))
+
+	var (
+		uploads []string
+		records []blueskyPost
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/xrpc/com.atproto.server.createSession", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, map[string]string{"identifier": "news.bsky.social", "password": "app-password"}, body)
+		w.Write([]byte(`{"accessJwt": "jwt", "did": "did:plc:news", "handle": "news.bsky.social"}`))
+	})
+	mux.HandleFunc("/xrpc/com.atproto.repo.uploadBlob", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer jwt", r.Header.Get("Authorization"))
+		require.Equal(t, "image/png", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		uploads = append(uploads, string(body))
+		w.Write([]byte(`{"blob": {"$type": "blob", "ref": {"$link": "bafkrei"}, "mimeType": "image/png", "size": 10}}`))
+	})
+	mux.HandleFunc("/xrpc/com.atproto.repo.createRecord", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer jwt", r.Header.Get("Authorization"))
+		var body struct {
+			Repo       string      `json:"repo"`
+			Collection string      `json:"collection"`
+			Record     blueskyPost `json:"record"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, "did:plc:news", body.Repo)
+		require.Equal(t, "app.bsky.feed.post", body.Collection)
+		records = append(records, body.Record)
+		if len(records) == 1 {
+			w.Write([]byte(`{"uri": "at://did:plc:news/app.bsky.feed.post/3k2a", "cid": "cid1"}`))
+			return
+		}
+		w.Write([]byte(`{"uri": "at://did:plc:news/app.bsky.feed.post/3k2b", "cid": "cid2"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	adapter := NewBlueskySocialMediaAdapter(BlueskyConfig{
+		Service:     server.URL,
+		Identifier:  "news.bsky.social",
+		AppPassword: "app-password",
+	}, server.Client(), logger.NewTestLogger())
+	adapter.(*blueskyAdapter).now = func() time.Time { return time.Date(2023, 8, 1, 8, 0, 0, 0, time.UTC) }
+
+	publishedPost, err := adapter.PublishImagePost(context.Background(), domain.Post{
+		NewsArticle:        domain.NewsArticle{Title: "Café reopens", Url: "https://example.com/cafe"},
+		ImageGeneratorName: "DALL-E",
+		Images:             []domain.GeneratedImage{{Path: domain.ImagePath(imagePath), Prompt: "A test prompt"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, domain.PublishedPost{
+		ID:  "at://did:plc:news/app.bsky.feed.post/3k2a",
+		URL: "https://bsky.app/profile/news.bsky.social/post/3k2a",
+	}, publishedPost)
+	require.Equal(t, []string{"image data"}, uploads)
+	require.Len(t, records, 2)
+
+	post := records[0]
+	require.Equal(t, "app.bsky.feed.post", post.Type)
+	require.Equal(t, "2023-08-01T08:00:00Z", post.CreatedAt)
+	require.Equal(t, "Café reopens https://example.com/cafe", post.Text)
+	require.Len(t, post.Facets, 1)
+	// "Café reopens " is 14 bytes, the é takes two
+	require.Equal(t, 14, post.Facets[0].Index.ByteStart)
+	require.Equal(t, 38, post.Facets[0].Index.ByteEnd)
+	require.Equal(t, []blueskyFacetFeature{{Type: "app.bsky.richtext.facet#link", URI: "https://example.com/cafe"}}, post.Facets[0].Features)
+	require.Len(t, post.Embed.Images, 1)
+	require.Equal(t, "A test prompt", post.Embed.Images[0].Alt)
+	require.JSONEq(t, `{"$type": "blob", "ref": {"$link": "bafkrei"}, "mimeType": "image/png", "size": 10}`, string(post.Embed.Images[0].Image))
+
+	reply := records[1]
+	require.Equal(t, "Created by DALL-E with the prompt:\n\nA test prompt", reply.Text)
+	require.Nil(t, reply.Embed)
+	require.Equal(t, &blueskyReplyTo{
+		Root:   blueskyStrongRef{URI: "at://did:plc:news/app.bsky.feed.post/3k2a", Cid: "cid1"},
+		Parent: blueskyStrongRef{URI: "at://did:plc:news/app.bsky.feed.post/3k2a", Cid: "cid1"},
+	}, reply.Reply)
+}
+
+func TestBlueskyAdapter_TruncateString(t *testing.T) {
+	adapter := NewBlueskySocialMediaAdapter(BlueskyConfig{}, nil, logger.NewTestLogger()).(*blueskyAdapter)
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Short",
+			input:    "Hello",
+			expected: "Hello",
+		},
+		{
+			name:     "ASCII",
+			input:    strings.Repeat("a", 310),
+			expected: strings.Repeat("a", 300),
+		},
+		{
+			name:     "Combining Marks",
+			input:    strings.Repeat("e\u0301", 310),
+			expected: strings.Repeat("e\u0301", 300),
+		},
+		{
+			name:     "Emoji Sequences",
+			input:    strings.Repeat("👩‍👩‍👧🇳🇱👍🏽", 110),
+			expected: strings.Repeat("👩‍👩‍👧🇳🇱👍🏽", 100),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, adapter.truncateString(tc.input))
+		})
+	}
+}
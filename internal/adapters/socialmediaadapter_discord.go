@@ -0,0 +1,146 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+const (
+	// discordMaxEmbedTitleCharacters and discordMaxEmbedDescriptionCharacters are the length limits of an embed
+	discordMaxEmbedTitleCharacters       = 256
+	discordMaxEmbedDescriptionCharacters = 4096
+)
+
+type discordAdapter struct {
+	webhookURL string
+	httpClient httpClient // Used for the webhook and downloading images
+	logger     logger.Logger
+}
+
+type discordMessage struct {
+	Embeds      []discordEmbed      `json:"embeds"`
+	Attachments []discordAttachment `json:"attachments"`
+}
+
+type discordEmbed struct {
+	Title       string `json:"title,omitempty"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+	Image       struct {
+		URL string `json:"url"`
+	} `json:"image"`
+}
+
+type discordAttachment struct {
+	ID          int    `json:"id"`
+	Filename    string `json:"filename"`
+	Description string `json:"description"`
+}
+
+// NewDiscordSocialMediaAdapter creates an adapter posting to a Discord channel through a webhook
+func NewDiscordSocialMediaAdapter(webhookURL string, httpClient httpClient, logger logger.Logger) ports.SocialMediaAdapter {
+	return &discordAdapter{
+		webhookURL: webhookURL,
+		httpClient: httpClient,
+		logger:     logger,
+	}
+}
+
+func (d *discordAdapter) PublishImagePost(ctx context.Context, post domain.Post) (domain.PublishedPost, error) {
+	preview := d.PreviewImagePost(post)
+
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+
+	// Embeds sharing a url are shown as a single embed with an image gallery
+	var message discordMessage
+	for i, image := range preview.Images {
+		imageReader, contentType, err := openImage(d.httpClient, image.Path)
+		if err != nil {
+			return domain.PublishedPost{}, err
+		}
+		filename := fmt.Sprintf("image-%d%s", i+1, imageExtension(contentType))
+		fw, err := w.CreateFormFile(fmt.Sprintf("files[%d]", i), filename)
+		if err == nil {
+			_, err = io.Copy(fw, imageReader)
+		}
+		imageReader.Close()
+		if err != nil {
+			return domain.PublishedPost{}, fmt.Errorf("failed to read image data: %w", err)
+		}
+
+		embed := discordEmbed{URL: post.NewsArticle.Url}
+		if i == 0 {
+			embed.Title = truncateRunes(post.NewsArticle.Title, discordMaxEmbedTitleCharacters)
+			embed.Description = truncateRunes(preview.Replies[0], discordMaxEmbedDescriptionCharacters)
+		}
+		embed.Image.URL = "attachment://" + filename
+		message.Embeds = append(message.Embeds, embed)
+		message.Attachments = append(message.Attachments, discordAttachment{ID: i, Filename: filename, Description: image.Prompt})
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return domain.PublishedPost{}, fmt.Errorf("failed to marshal discord message: %w", err)
+	}
+	if err = w.WriteField("payload_json", string(payload)); err != nil {
+		return domain.PublishedPost{}, err
+	}
+	w.Close()
+
+	// Waiting makes discord respond with the created message instead of no content
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.webhookURL+"?wait=true", &b)
+	if err != nil {
+		return domain.PublishedPost{}, fmt.Errorf("failed to create request: %w", withoutURL(err))
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	// The webhook url is the credential of the webhook
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return domain.PublishedPost{}, fmt.Errorf("failed to send request: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		d.logger.Error("failed to post discord message", "response body", string(bodyBytes))
+		return domain.PublishedPost{}, fmt.Errorf("failed to post discord message, status code: %d", resp.StatusCode)
+	}
+
+	var response struct {
+		ID string `json:"id"`
+	}
+	if err = json.Unmarshal(bodyBytes, &response); err != nil {
+		return domain.PublishedPost{}, fmt.Errorf("failed to unmarshal discord response: %w", err)
+	}
+	return domain.PublishedPost{ID: response.ID}, nil
+}
+
+func (d *discordAdapter) PreviewImagePost(post domain.Post) domain.PostPreview {
+	images := post.Images
+	if len(images) > domain.MaxImagesPerPost {
+		d.logger.Warn("Discord only shows up to 4 images per embed, extra images are dropped", "images", len(images))
+		images = images[:domain.MaxImagesPerPost]
+	}
+
+	return domain.PostPreview{
+		AdapterName: d.GetName(),
+		Text:        post.NewsArticle.Title + " " + post.NewsArticle.Url,
+		Replies:     []string{createPromptReply(post.ImageGeneratorName, images)},
+		Images:      images,
+	}
+}
+
+func (d *discordAdapter) GetName() string {
+	return "Discord"
+}
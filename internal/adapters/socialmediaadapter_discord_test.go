@@ -0,0 +1,86 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscordAdapter_PublishImagePost(t *testing.T) {
+	imageDirectory := t.TempDir()
+	for i := 1; i <= 2; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(imageDirectory, fmt.Sprintf("%d.jpg", i)), []byte(fmt.Sprintf("image %d", i)), 0o644))
+	}
+
+	var (
+		message discordMessage
+		files   = map[string]string{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/webhooks/123/token", r.URL.Path)
+		require.Equal(t, "true", r.URL.Query().Get("wait"))
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		require.NoError(t, json.Unmarshal([]byte(r.FormValue("payload_json")), &message))
+		for field, headers := range r.MultipartForm.File {
+			file, err := headers[0].Open()
+			require.NoError(t, err)
+			data, _ := io.ReadAll(file)
+			files[field+" "+headers[0].Filename] = string(data)
+		}
+		w.Write([]byte(`{"id": "1138", "channel_id": "42"}`))
+	}))
+	defer server.Close()
+
+	adapter := NewDiscordSocialMediaAdapter(server.URL+"/api/webhooks/123/token", server.Client(), logger.NewTestLogger())
+	publishedPost, err := adapter.PublishImagePost(context.Background(), domain.Post{
+		NewsArticle:        domain.NewsArticle{Title: "Test Article", Url: "https://example.com"},
+		ImageGeneratorName: "DALL-E",
+		Images: []domain.GeneratedImage{
+			{Path: domain.ImagePath(filepath.Join(imageDirectory, "1.jpg")), Prompt: "First prompt"},
+			{Path: domain.ImagePath(filepath.Join(imageDirectory, "2.jpg")), Prompt: "Second prompt"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, domain.PublishedPost{ID: "1138"}, publishedPost)
+	require.Equal(t, map[string]string{"files[0] image-1.jpg": "image 1", "files[1] image-2.jpg": "image 2"}, files)
+
+	expected := discordMessage{
+		Embeds: []discordEmbed{
+			{Title: "Test Article", URL: "https://example.com", Description: "Created by DALL-E with the prompts:\n\n1. First prompt\n2. Second prompt"},
+			{URL: "https://example.com"},
+		},
+		Attachments: []discordAttachment{
+			{ID: 0, Filename: "image-1.jpg", Description: "First prompt"},
+			{ID: 1, Filename: "image-2.jpg", Description: "Second prompt"},
+		},
+	}
+	expected.Embeds[0].Image.URL = "attachment://image-1.jpg"
+	expected.Embeds[1].Image.URL = "attachment://image-2.jpg"
+	require.Equal(t, expected, message)
+}
+
+func TestDiscordAdapter_PublishImagePost_ErrorWithoutWebhookURL(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "1.jpg")
+	require.NoError(t, os.WriteFile(imagePath, []byte("image"), 0o644))
+	// Nothing listens on the address of a closed server
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	adapter := NewDiscordSocialMediaAdapter(server.URL+"/api/webhooks/123/SECRET", server.Client(), logger.NewTestLogger())
+	_, err := adapter.PublishImagePost(context.Background(), domain.Post{
+		NewsArticle: domain.NewsArticle{Title: "Title"},
+		Images:      []domain.GeneratedImage{{Path: domain.ImagePath(imagePath), Prompt: "A test prompt"}},
+	})
+	require.ErrorContains(t, err, "failed to send request")
+	require.NotContains(t, err.Error(), "SECRET")
+}
@@ -0,0 +1,327 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+// galleryFeedItems is the number of most recent posts in the feed
+const galleryFeedItems = 50
+
+// GalleryConfig configures the static gallery
+type GalleryConfig struct {
+	// Directory the gallery is written to, it can be served by any static file server
+	Directory string
+	// BaseURL is the url the directory is served at, used for the absolute links in the feed
+	BaseURL string
+	// Title of the gallery page and feed
+	Title string
+}
+
+// galleryEntry is a published post in the index of the gallery
+type galleryEntry struct {
+	ID                 string         `json:"id"`
+	Title              string         `json:"title"`
+	ArticleURL         string         `json:"articleUrl"`
+	Source             string         `json:"source"`
+	ImageGeneratorName string         `json:"imageGeneratorName"`
+	Images             []galleryImage `json:"images"`
+	PublishedAt        time.Time      `json:"publishedAt"`
+}
+
+type galleryImage struct {
+	File   string `json:"file"`
+	Prompt string `json:"prompt"`
+	// Size and ContentType are needed for the feed enclosures
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType"`
+}
+
+type galleryAdapter struct {
+	config     GalleryConfig
+	httpClient httpClient // Used for downloading images
+	logger     logger.Logger
+	now        func() time.Time
+	// mu serializes updates of the index, which is read, modified and rewritten
+	mu sync.Mutex
+}
+
+// NewGallerySocialMediaAdapter creates an adapter that publishes to a self-hosted static gallery with an RSS feed,
+// which archives every post independently of the social medias
+func NewGallerySocialMediaAdapter(config GalleryConfig, httpClient httpClient, logger logger.Logger) ports.SocialMediaAdapter {
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	if config.Title == "" {
+		config.Title = "Generated news content"
+	}
+	return &galleryAdapter{
+		config:     config,
+		httpClient: httpClient,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+func (g *galleryAdapter) PublishImagePost(_ context.Context, post domain.Post) (domain.PublishedPost, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entries, err := g.readIndex()
+	if err != nil {
+		return domain.PublishedPost{}, err
+	}
+
+	publishedAt := g.now().UTC()
+	entry := galleryEntry{
+		ID:                 g.uniqueID(entries, publishedAt),
+		Title:              post.NewsArticle.Title,
+		ArticleURL:         post.NewsArticle.Url,
+		Source:             post.NewsArticle.Source,
+		ImageGeneratorName: post.ImageGeneratorName,
+		PublishedAt:        publishedAt,
+	}
+	for i, image := range post.Images {
+		galleryImage, err := g.copyImage(image, fmt.Sprintf("%s-%d", entry.ID, i+1))
+		if err != nil {
+			return domain.PublishedPost{}, err
+		}
+		entry.Images = append(entry.Images, galleryImage)
+	}
+
+	// The newest post is shown first
+	entries = append([]galleryEntry{entry}, entries...)
+	if err = writeJSONFile(filepath.Join(g.config.Directory, "index.json"), entries); err != nil {
+		return domain.PublishedPost{}, fmt.Errorf("failed to write gallery index: %w", err)
+	}
+	if err = g.writePage(entries); err != nil {
+		return domain.PublishedPost{}, err
+	}
+	if err = g.writeFeed(entries); err != nil {
+		return domain.PublishedPost{}, err
+	}
+
+	return domain.PublishedPost{ID: entry.ID, URL: g.config.BaseURL + "/#" + entry.ID}, nil
+}
+
+func (g *galleryAdapter) PreviewImagePost(post domain.Post) domain.PostPreview {
+	return domain.PostPreview{
+		AdapterName: g.GetName(),
+		Text:        post.NewsArticle.Title + " " + post.NewsArticle.Url,
+		Replies:     []string{createPromptReply(post.ImageGeneratorName, post.Images)},
+		Images:      post.Images,
+	}
+}
+
+func (g *galleryAdapter) GetName() string {
+	return "Gallery"
+}
+
+func (g *galleryAdapter) readIndex() ([]galleryEntry, error) {
+	var entries []galleryEntry
+	err := readJSONFile(filepath.Join(g.config.Directory, "index.json"), &entries)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read gallery index: %w", err)
+	}
+	return entries, nil
+}
+
+// uniqueID returns an id based on the publishing time, posts published within the same second get a suffix
+func (g *galleryAdapter) uniqueID(entries []galleryEntry, publishedAt time.Time) string {
+	base := publishedAt.Format("20060102-150405")
+	id := base
+	for suffix := 2; ; suffix++ {
+		taken := false
+		for _, entry := range entries {
+			if entry.ID == id {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, suffix)
+	}
+}
+
+func (g *galleryAdapter) copyImage(image domain.GeneratedImage, name string) (galleryImage, error) {
+	imageReader, contentType, err := openImage(g.httpClient, image.Path)
+	if err != nil {
+		return galleryImage{}, err
+	}
+	defer imageReader.Close()
+
+	file := filepath.Join("images", name+imageExtension(contentType))
+	if err = os.MkdirAll(filepath.Join(g.config.Directory, "images"), 0o755); err != nil {
+		return galleryImage{}, fmt.Errorf("failed to create directory: %w", err)
+	}
+	out, err := os.Create(filepath.Join(g.config.Directory, file))
+	if err != nil {
+		return galleryImage{}, fmt.Errorf("failed to create image file: %w", err)
+	}
+	defer out.Close()
+
+	size, err := io.Copy(out, imageReader)
+	if err != nil {
+		return galleryImage{}, fmt.Errorf("failed to write image: %w", err)
+	}
+	return galleryImage{
+		File:        filepath.ToSlash(file),
+		Prompt:      image.Prompt,
+		Size:        size,
+		ContentType: imageContentType(filepath.Ext(file)),
+	}, nil
+}
+
+var galleryPageTemplate = template.Must(template.New("gallery").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="alternate" type="application/rss+xml" title="{{.Title}}" href="feed.xml">
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 0 auto; padding: 1em; }
+article { margin-bottom: 3em; }
+.images { display: grid; grid-template-columns: repeat(auto-fit, minmax(220px, 1fr)); gap: 0.5em; }
+img { width: 100%; }
+figcaption, time { color: #555; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Entries}}<article id="{{.ID}}">
+<h2><a href="{{.ArticleURL}}">{{.Title}}</a></h2>
+<time datetime="{{.PublishedAt.Format "2006-01-02T15:04:05Z07:00"}}">{{.PublishedAt.Format "January 2, 2006"}}</time>{{if .Source}} · {{.Source}}{{end}}
+<div class="images">
+{{range .Images}}<figure>
+<a href="{{.File}}"><img src="{{.File}}" alt="{{.Prompt}}" loading="lazy"></a>
+<figcaption>{{.Prompt}}</figcaption>
+</figure>
+{{end}}</div>
+<p>Created by {{.ImageGeneratorName}}</p>
+</article>
+{{end}}</body>
+</html>
+`))
+
+// writePage regenerates the html page of the gallery
+func (g *galleryAdapter) writePage(entries []galleryEntry) error {
+	var buf bytes.Buffer
+	err := galleryPageTemplate.Execute(&buf, struct {
+		Title   string
+		Entries []galleryEntry
+	}{g.config.Title, entries})
+	if err != nil {
+		return fmt.Errorf("failed to render gallery page: %w", err)
+	}
+	return writeFileAtomically(filepath.Join(g.config.Directory, "index.html"), buf.Bytes())
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Description string        `xml:"description"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// writeFeed regenerates the RSS feed with the most recent posts
+func (g *galleryAdapter) writeFeed(entries []galleryEntry) error {
+	if len(entries) > galleryFeedItems {
+		entries = entries[:galleryFeedItems]
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       g.config.Title,
+			Link:        g.config.BaseURL + "/",
+			Description: g.config.Title,
+		},
+	}
+	if len(entries) > 0 {
+		feed.Channel.LastBuildDate = entries[0].PublishedAt.Format(time.RFC1123Z)
+	}
+	for _, entry := range entries {
+		var description strings.Builder
+		for _, image := range entry.Images {
+			fmt.Fprintf(&description, `<p><img src="%s" alt="%s"></p>`, template.HTMLEscapeString(g.absoluteURL(image.File)), template.HTMLEscapeString(image.Prompt))
+		}
+		fmt.Fprintf(&description, `<p>Created by %s, based on <a href="%s">%s</a></p>`,
+			template.HTMLEscapeString(entry.ImageGeneratorName), template.HTMLEscapeString(entry.ArticleURL), template.HTMLEscapeString(entry.Title))
+
+		item := rssItem{
+			Title:       entry.Title,
+			Link:        g.config.BaseURL + "/#" + entry.ID,
+			GUID:        rssGUID{Value: entry.ID},
+			PubDate:     entry.PublishedAt.Format(time.RFC1123Z),
+			Description: description.String(),
+		}
+		if len(entry.Images) > 0 {
+			// RSS allows a single enclosure per item
+			image := entry.Images[0]
+			item.Enclosure = &rssEnclosure{URL: g.absoluteURL(image.File), Length: image.Size, Type: image.ContentType}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal gallery feed: %w", err)
+	}
+	return writeFileAtomically(filepath.Join(g.config.Directory, "feed.xml"), append([]byte(xml.Header), data...))
+}
+
+func (g *galleryAdapter) absoluteURL(file string) string {
+	return g.config.BaseURL + "/" + (&url.URL{Path: file}).EscapedPath()
+}
+
+// writeFileAtomically replaces a file without readers ever seeing it partially written
+func writeFileAtomically(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return os.Rename(tmpPath, path)
+}
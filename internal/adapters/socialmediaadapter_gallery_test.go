@@ -0,0 +1,80 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGalleryAdapter_PublishImagePost(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "image.jpg")
+	require.NoError(t, os.WriteFile(imagePath, []byte("image data"), 0o644))
+
+	directory := t.TempDir()
+	adapter := NewGallerySocialMediaAdapter(GalleryConfig{Directory: directory, BaseURL: "https://gallery.example.com/"}, nil, logger.NewTestLogger())
+	adapter.(*galleryAdapter).now = func() time.Time { return time.Date(2023, 8, 1, 8, 0, 0, 0, time.UTC) }
+
+	posts := []domain.Post{
+		{
+			NewsArticle:        domain.NewsArticle{Title: "First Article", Url: "https://example.com/1", Source: "New York Times"},
+			ImageGeneratorName: "DALL-E",
+			Images:             []domain.GeneratedImage{{Path: domain.ImagePath(imagePath), Prompt: "First prompt"}},
+		},
+		{
+			NewsArticle:        domain.NewsArticle{Title: "Second <Article>", Url: "https://example.com/2", Source: "New York Times"},
+			ImageGeneratorName: "DALL-E",
+			Images:             []domain.GeneratedImage{{Path: domain.ImagePath(imagePath), Prompt: "Second prompt"}},
+		},
+	}
+	var publishedPosts []domain.PublishedPost
+	for _, post := range posts {
+		publishedPost, err := adapter.PublishImagePost(context.Background(), post)
+		require.NoError(t, err)
+		publishedPosts = append(publishedPosts, publishedPost)
+	}
+	// Both posts are published within the same second
+	require.Equal(t, []domain.PublishedPost{
+		{ID: "20230801-080000", URL: "https://gallery.example.com/#20230801-080000"},
+		{ID: "20230801-080000-2", URL: "https://gallery.example.com/#20230801-080000-2"},
+	}, publishedPosts)
+
+	image, err := os.ReadFile(filepath.Join(directory, "images", "20230801-080000-2-1.jpg"))
+	require.NoError(t, err)
+	require.Equal(t, "image data", string(image))
+
+	indexData, err := os.ReadFile(filepath.Join(directory, "index.json"))
+	require.NoError(t, err)
+	var entries []galleryEntry
+	require.NoError(t, json.Unmarshal(indexData, &entries))
+	require.Len(t, entries, 2)
+	require.Equal(t, "20230801-080000-2", entries[0].ID, "the newest post comes first")
+	require.Equal(t, []galleryImage{{File: "images/20230801-080000-2-1.jpg", Prompt: "Second prompt", Size: 10, ContentType: "image/jpeg"}}, entries[0].Images)
+
+	page, err := os.ReadFile(filepath.Join(directory, "index.html"))
+	require.NoError(t, err)
+	require.Contains(t, string(page), `<h2><a href="https://example.com/2">Second &lt;Article&gt;</a></h2>`)
+	require.Contains(t, string(page), `<img src="images/20230801-080000-1.jpg" alt="First prompt" loading="lazy">`)
+	require.Less(t, strings.Index(string(page), "Second"), strings.Index(string(page), "First Article"))
+
+	feedData, err := os.ReadFile(filepath.Join(directory, "feed.xml"))
+	require.NoError(t, err)
+	var feed rssFeed
+	require.NoError(t, xml.Unmarshal(feedData, &feed))
+	require.Equal(t, "Generated news content", feed.Channel.Title)
+	require.Len(t, feed.Channel.Items, 2)
+	item := feed.Channel.Items[0]
+	require.Equal(t, "Second <Article>", item.Title)
+	require.Equal(t, "https://gallery.example.com/#20230801-080000-2", item.Link)
+	require.Equal(t, "Tue, 01 Aug 2023 08:00:00 +0000", item.PubDate)
+	require.Equal(t, &rssEnclosure{URL: "https://gallery.example.com/images/20230801-080000-2-1.jpg", Length: 10, Type: "image/jpeg"}, item.Enclosure)
+	require.Contains(t, item.Description, `<img src="https://gallery.example.com/images/20230801-080000-2-1.jpg" alt="Second prompt">`)
+}
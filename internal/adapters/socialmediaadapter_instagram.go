@@ -6,8 +6,9 @@ import (
 	"fmt"
 	"image"
 	"image/jpeg"
+	"io"
 	"net/http"
-	"os"
+	"strings"
 
 	"github.com/BaronBonet/content-generator/internal/core/domain"
 	"github.com/BaronBonet/content-generator/internal/core/ports"
@@ -29,72 +30,85 @@ func NewInstagramSocialMediaAdapter(logger logger.Logger, username string, passw
 	}
 }
 
-func (i *instagramAdapter) PublishImagePost(ctx context.Context, imagePath domain.ImagePath, prompt string, imageGeneratorName string, newsArticle domain.NewsArticle) error {
+func (i *instagramAdapter) PublishImagePost(ctx context.Context, post domain.Post) (domain.PublishedPost, error) {
 	i.logger.Debug("Trying to login to instagram")
 	insta := goinsta.New(i.username, i.password)
 	err := insta.Login()
 	i.logger.Debug("Logged into instagram")
 	if err != nil {
-		return fmt.Errorf("failed to login to Instagram: %w", err)
+		return domain.PublishedPost{}, fmt.Errorf("failed to login to Instagram: %w", err)
 	}
-	resp, err := http.Get(string(imagePath))
+
+	readers := make([]io.Reader, 0, len(post.Images))
+	for _, generatedImage := range post.Images {
+		reader, err := i.downloadJPEG(generatedImage.Path)
+		if err != nil {
+			return domain.PublishedPost{}, err
+		}
+		readers = append(readers, reader)
+	}
+
+	caption := createInstagramCaption(post)
+	i.logger.Debug("Uploading images with caption", "caption", caption, "images", len(readers))
+	options := &goinsta.UploadOptions{Caption: caption}
+	if len(readers) == 1 {
+		options.File = readers[0]
+	} else {
+		// Multiple images are published as a carousel album
+		options.Album = readers
+	}
+	item, err := insta.Upload(options)
+	if err != nil {
+		return domain.PublishedPost{}, fmt.Errorf("failed to upload image: %w", err)
+	}
+	return domain.PublishedPost{ID: item.GetID(), URL: fmt.Sprintf("https://www.instagram.com/p/%s/", item.Code)}, nil
+}
+
+// downloadJPEG downloads an image and re-encodes it as a JPEG, which is the format instagram expects
+func (i *instagramAdapter) downloadJPEG(imagePath domain.ImagePath) (io.Reader, error) {
+	imageReader, _, err := openImage(http.DefaultClient, imagePath)
 	if err != nil {
-		return fmt.Errorf("failed to download image: %w", err)
+		return nil, err
 	}
+	defer imageReader.Close()
 	i.logger.Debug("Downloaded image")
 
-	img, _, err := image.Decode(resp.Body)
+	img, _, err := image.Decode(imageReader)
 	if err != nil {
-		return fmt.Errorf("failed to decode image: %w", err)
+		return nil, fmt.Errorf("failed to decode image: %w", err)
 	}
 
 	var buf bytes.Buffer
 	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
 	if err != nil {
-		return fmt.Errorf("failed to encode image: %w", err)
+		return nil, fmt.Errorf("failed to encode image: %w", err)
 	}
 
-	reader := bytes.NewReader(buf.Bytes()) // convert buf to io.Reader
 	i.logger.Debug("Converted image to io.Reader")
+	return bytes.NewReader(buf.Bytes()), nil
+}
 
-	defer resp.Body.Close()
-	caption := createInstagramCaption(prompt, imageGeneratorName, newsArticle)
-	i.logger.Debug("Uploading image with caption", "caption", caption)
-	_, err = insta.Upload(
-		&goinsta.UploadOptions{
-			File:    reader,
-			Caption: caption,
-		},
-	)
-	if err != nil {
-		return fmt.Errorf("failed to upload image: %w", err)
+func (i *instagramAdapter) PreviewImagePost(post domain.Post) domain.PostPreview {
+	return domain.PostPreview{
+		AdapterName: i.GetName(),
+		Text:        createInstagramCaption(post),
+		Images:      post.Images,
 	}
-	return nil
 }
 
 func (i *instagramAdapter) GetName() string {
 	return "Instagram"
 }
 
-func createInstagramCaption(prompt string, imageGeneratorName string, newsArticle domain.NewsArticle) string {
-	return fmt.Sprintf("%s \n\nCreated by %s with the prompt:\n\n%s\n\nGenerated from the %s article at: %s",
-		newsArticle.Title, imageGeneratorName, prompt, newsArticle.Source, newsArticle.Url)
-}
-
-func NewInstagramAdapterFromEnv(logger logger.Logger) (ports.SocialMediaAdapter, error) {
-	keys := []string{"INSTAGRAM_USERNAME", "INSTAGRAM_PASSWORD"}
-
-	values := make(map[string]string, len(keys))
-	for _, key := range keys {
-		value, exists := os.LookupEnv(key)
-		if !exists {
-			return nil, fmt.Errorf("environment variable %s not set", key)
+func createInstagramCaption(post domain.Post) string {
+	prompts := "with the prompt:\n\n" + post.Images[0].Prompt
+	if len(post.Images) > 1 {
+		numbered := make([]string, len(post.Images))
+		for i, generatedImage := range post.Images {
+			numbered[i] = fmt.Sprintf("%d. %s", i+1, generatedImage.Prompt)
 		}
-		values[key] = value
+		prompts = "with the prompts:\n\n" + strings.Join(numbered, "\n")
 	}
-	return NewInstagramSocialMediaAdapter(
-		logger,
-		values["INSTAGRAM_USERNAME"],
-		values["INSTAGRAM_PASSWORD"],
-	), nil
+	return fmt.Sprintf("%s \n\nCreated by %s %s\n\nGenerated from the %s article at: %s",
+		post.NewsArticle.Title, post.ImageGeneratorName, prompts, post.NewsArticle.Source, post.NewsArticle.Url)
 }
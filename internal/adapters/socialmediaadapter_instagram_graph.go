@@ -0,0 +1,67 @@
+package adapters
+
+import (
+	"context"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+// instagramMaxCaptionCharacters is the length limit of an Instagram caption
+const instagramMaxCaptionCharacters = 2200
+
+// instagramGraphAdapter publishes through the official Instagram Graph API, unlike instagramAdapter it does not log in
+// with a username and password, which Instagram may flag as suspicious
+type instagramGraphAdapter struct {
+	client *metaGraphClient
+	logger logger.Logger
+}
+
+func NewInstagramGraphSocialMediaAdapter(config MetaGraphConfig, httpClient httpClient, logger logger.Logger) ports.SocialMediaAdapter {
+	return &instagramGraphAdapter{
+		client: newMetaGraphClient(instagramGraphAPI, config, httpClient, logger),
+		logger: logger,
+	}
+}
+
+func (i *instagramGraphAdapter) PublishImagePost(ctx context.Context, post domain.Post) (domain.PublishedPost, error) {
+	preview := i.PreviewImagePost(post)
+
+	mediaID, err := i.client.publishImages(ctx, preview.Images, preview.Text)
+	if err != nil {
+		return domain.PublishedPost{}, err
+	}
+
+	permalink, err := i.client.permalink(ctx, mediaID)
+	if err != nil {
+		// The post is published, only its url is unknown
+		i.logger.Warn("Could not get the url of the instagram post", "error", err)
+	}
+	return domain.PublishedPost{ID: mediaID, URL: permalink}, nil
+}
+
+func (i *instagramGraphAdapter) PreviewImagePost(post domain.Post) domain.PostPreview {
+	images := post.Images
+	if len(images) > domain.MaxImagesPerPost {
+		i.logger.Warn("Only up to 4 images are published to instagram, extra images are dropped", "images", len(images))
+		images = images[:domain.MaxImagesPerPost]
+	}
+	post.Images = images
+
+	caption := createInstagramCaption(post)
+	if len([]rune(caption)) > instagramMaxCaptionCharacters {
+		i.logger.Warn("Instagram caption was truncated to 2200 characters", "full caption", caption)
+		caption = truncateRunes(caption, instagramMaxCaptionCharacters)
+	}
+
+	return domain.PostPreview{
+		AdapterName: i.GetName(),
+		Text:        caption,
+		Images:      images,
+	}
+}
+
+func (i *instagramGraphAdapter) GetName() string {
+	return "Instagram"
+}
@@ -0,0 +1,79 @@
+package adapters
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstagramGraphAdapter_PublishImagePost(t *testing.T) {
+	post := domain.Post{
+		NewsArticle:        domain.NewsArticle{Title: "Test Article", Url: "https://example.com", Source: "New York Times"},
+		ImageGeneratorName: "DALL-E",
+	}
+
+	testCases := []struct {
+		name               string
+		images             []domain.GeneratedImage
+		expectedContainers []url.Values
+		expectedPublished  int
+	}{
+		{
+			name:   "Single Image",
+			images: []domain.GeneratedImage{{Path: "https://test.com/1.png", Prompt: "First prompt"}},
+			expectedContainers: []url.Values{
+				{
+					"caption":   {"Test Article \n\nCreated by DALL-E with the prompt:\n\nFirst prompt\n\nGenerated from the New York Times article at: https://example.com"},
+					"image_url": {"https://test.com/1.png"},
+					"alt_text":  {"First prompt"},
+				},
+			},
+		},
+		{
+			name: "Carousel",
+			images: []domain.GeneratedImage{
+				{Path: "https://test.com/1.png", Prompt: "First prompt"},
+				{Path: "data/images/2.png", Prompt: "Second prompt"},
+			},
+			expectedContainers: []url.Values{
+				{"is_carousel_item": {"true"}, "image_url": {"https://test.com/1.png"}, "alt_text": {"First prompt"}},
+				{"is_carousel_item": {"true"}, "image_url": {"https://cdn.example.com/2.png"}, "alt_text": {"Second prompt"}},
+				{
+					"caption":    {"Test Article \n\nCreated by DALL-E with the prompts:\n\n1. First prompt\n2. Second prompt\n\nGenerated from the New York Times article at: https://example.com"},
+					"media_type": {"CAROUSEL"},
+					"children":   {"container-1,container-2"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake, server := newFakeMetaGraphServer(t, instagramGraphAPI)
+			defer server.Close()
+
+			adapter := NewInstagramGraphSocialMediaAdapter(MetaGraphConfig{
+				UserID:             "user-id",
+				AccessToken:        "token",
+				PublicImageBaseURL: "https://cdn.example.com",
+			}, nil, logger.NewTestLogger())
+			useFakeServer(adapter.(*instagramGraphAdapter).client, server)
+
+			post := post
+			post.Images = tc.images
+			publishedPost, err := adapter.PublishImagePost(context.Background(), post)
+			require.NoError(t, err)
+			require.Equal(t, domain.PublishedPost{ID: "media-1", URL: "https://example.com/p/media-1/"}, publishedPost)
+			require.Equal(t, tc.expectedContainers, fake.containers)
+			// Only the post itself is published, carousel items are published as part of it
+			require.Equal(t, []url.Values{tc.expectedContainers[len(tc.expectedContainers)-1]}, fake.published)
+			// The configured token is refreshed once it is a day old
+			require.Equal(t, 0, fake.refreshes)
+			require.Equal(t, "token", fake.tokens[len(fake.tokens)-1])
+		})
+	}
+}
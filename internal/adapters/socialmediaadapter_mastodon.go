@@ -0,0 +1,253 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+const (
+	// mastodonMaxCharacters is the default status length limit of a Mastodon server
+	mastodonMaxCharacters = 500
+	// mastodonMaxAltTextCharacters is the length limit of a media description
+	mastodonMaxAltTextCharacters = 1500
+)
+
+// MastodonConfig configures the Mastodon adapter
+type MastodonConfig struct {
+	// Server is the base url of the Mastodon instance, e.g. https://mastodon.social
+	Server      string
+	AccessToken string
+	// Visibility of the statuses, one of public, unlisted, private or direct. Defaults to public.
+	Visibility string
+	// ContentWarning is shown instead of the status until the reader expands it, no content warning is used when empty
+	ContentWarning string
+}
+
+type mastodonAdapter struct {
+	config       MastodonConfig
+	httpClient   httpClient // Used for the Mastodon API and downloading images
+	logger       logger.Logger
+	pollInterval time.Duration
+}
+
+type mastodonStatus struct {
+	Status      string   `json:"status"`
+	MediaIDs    []string `json:"media_ids,omitempty"`
+	Visibility  string   `json:"visibility,omitempty"`
+	SpoilerText string   `json:"spoiler_text,omitempty"`
+	Sensitive   bool     `json:"sensitive,omitempty"`
+	InReplyToID string   `json:"in_reply_to_id,omitempty"`
+}
+
+type mastodonStatusResponse struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+type mastodonAttachment struct {
+	ID  string  `json:"id"`
+	URL *string `json:"url"`
+}
+
+func NewMastodonSocialMediaAdapter(config MastodonConfig, httpClient httpClient, logger logger.Logger) ports.SocialMediaAdapter {
+	config.Server = strings.TrimSuffix(config.Server, "/")
+	if config.Visibility == "" {
+		config.Visibility = "public"
+	}
+	return &mastodonAdapter{
+		config:       config,
+		httpClient:   httpClient,
+		logger:       logger,
+		pollInterval: time.Second,
+	}
+}
+
+func (m *mastodonAdapter) PublishImagePost(ctx context.Context, post domain.Post) (domain.PublishedPost, error) {
+	preview := m.PreviewImagePost(post)
+
+	mediaIDs := make([]string, 0, len(preview.Images))
+	for _, image := range preview.Images {
+		mediaID, err := m.uploadImage(ctx, image)
+		if err != nil {
+			return domain.PublishedPost{}, err
+		}
+		mediaIDs = append(mediaIDs, mediaID)
+	}
+
+	status, err := m.createStatus(ctx, mastodonStatus{
+		Status:      preview.Text,
+		MediaIDs:    mediaIDs,
+		Visibility:  m.config.Visibility,
+		SpoilerText: m.config.ContentWarning,
+		Sensitive:   m.config.ContentWarning != "",
+	})
+	if err != nil {
+		return domain.PublishedPost{}, err
+	}
+	publishedPost := domain.PublishedPost{ID: status.ID, URL: status.URL}
+
+	_, err = m.createStatus(ctx, mastodonStatus{
+		Status:      preview.Replies[0],
+		Visibility:  m.config.Visibility,
+		SpoilerText: m.config.ContentWarning,
+		InReplyToID: status.ID,
+	})
+	if err != nil {
+		return publishedPost, fmt.Errorf("failed to reply to mastodon status: %w", err)
+	}
+	return publishedPost, nil
+}
+
+func (m *mastodonAdapter) PreviewImagePost(post domain.Post) domain.PostPreview {
+	images := post.Images
+	if len(images) > domain.MaxImagesPerPost {
+		m.logger.Warn("Mastodon only supports up to 4 images per status, extra images are dropped", "images", len(images))
+		images = images[:domain.MaxImagesPerPost]
+	}
+
+	return domain.PostPreview{
+		AdapterName: m.GetName(),
+		Text:        m.truncateString(post.NewsArticle.Title + " " + post.NewsArticle.Url),
+		Replies:     []string{m.truncateString(createPromptReply(post.ImageGeneratorName, images))},
+		Images:      images,
+	}
+}
+
+func (m *mastodonAdapter) GetName() string {
+	return "Mastodon"
+}
+
+// uploadImage uploads an image with its prompt as alt text and returns the media ID once the server processed it
+func (m *mastodonAdapter) uploadImage(ctx context.Context, image domain.GeneratedImage) (string, error) {
+	imageReader, contentType, err := openImage(m.httpClient, image.Path)
+	if err != nil {
+		return "", err
+	}
+	defer imageReader.Close()
+
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	fw, err := w.CreateFormFile("file", "image"+imageExtension(contentType))
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(fw, imageReader); err != nil {
+		return "", fmt.Errorf("failed to read image data: %w", err)
+	}
+	altText := []rune(image.Prompt)
+	if len(altText) > mastodonMaxAltTextCharacters {
+		altText = altText[:mastodonMaxAltTextCharacters]
+	}
+	if err = w.WriteField("description", string(altText)); err != nil {
+		return "", err
+	}
+	w.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.config.Server+"/api/v2/media", &b)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	var attachment mastodonAttachment
+	statusCode, err := m.do(req, &attachment)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload image to mastodon: %w", err)
+	}
+	// Large media is processed asynchronously, in which case the url is not set yet
+	if statusCode == http.StatusAccepted || attachment.URL == nil {
+		return attachment.ID, m.waitForProcessing(ctx, attachment.ID)
+	}
+	return attachment.ID, nil
+}
+
+// waitForProcessing polls the media attachment until the server finished processing it
+func (m *mastodonAdapter) waitForProcessing(ctx context.Context, mediaID string) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("mastodon media %s was not processed in time: %w", mediaID, ctx.Err())
+		case <-time.After(m.pollInterval):
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.config.Server+"/api/v1/media/"+mediaID, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		var attachment mastodonAttachment
+		statusCode, err := m.do(req, &attachment)
+		if err != nil {
+			return fmt.Errorf("failed to get mastodon media status: %w", err)
+		}
+		if statusCode == http.StatusOK && attachment.URL != nil {
+			return nil
+		}
+		m.logger.Debug("Waiting for mastodon to process media", "media id", mediaID)
+	}
+}
+
+func (m *mastodonAdapter) createStatus(ctx context.Context, status mastodonStatus) (mastodonStatusResponse, error) {
+	jsonBytes, err := json.Marshal(status)
+	if err != nil {
+		return mastodonStatusResponse{}, fmt.Errorf("failed to marshal status data: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.config.Server+"/api/v1/statuses", bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return mastodonStatusResponse{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var response mastodonStatusResponse
+	if _, err = m.do(req, &response); err != nil {
+		return mastodonStatusResponse{}, fmt.Errorf("failed to post mastodon status: %w", err)
+	}
+	return response, nil
+}
+
+// do authenticates and sends a request, decoding the response body into v when the request succeeded
+func (m *mastodonAdapter) do(req *http.Request, v any) (int, error) {
+	req.Header.Set("Authorization", "Bearer "+m.config.AccessToken)
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		m.logger.Error("Mastodon request failed", "url", req.URL.String(), "response body", string(bodyBytes))
+		return resp.StatusCode, fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	if err = json.Unmarshal(bodyBytes, v); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// truncateString shortens a string to the status length limit of a Mastodon server.
+func (m *mastodonAdapter) truncateString(s string) string {
+	runeStr := []rune(s)
+
+	if len(runeStr) > mastodonMaxCharacters {
+		m.logger.Warn("Mastodon status was truncated to 500 characters", "full status", s)
+		runeStr = runeStr[:mastodonMaxCharacters]
+	}
+
+	return string(runeStr)
+}
@@ -0,0 +1,146 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMastodonServer records the requests made to the Mastodon API, media is processed asynchronously after one poll
+type fakeMastodonServer struct {
+	mu           sync.Mutex
+	descriptions []string
+	mediaPolls   int
+	statuses     []mastodonStatus
+	failStatus   bool
+}
+
+func (f *fakeMastodonServer) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/media", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		file, _, err := r.FormFile("file")
+		require.NoError(t, err)
+		file.Close()
+
+		f.mu.Lock()
+		f.descriptions = append(f.descriptions, r.FormValue("description"))
+		id := fmt.Sprintf("media-%d", len(f.descriptions))
+		f.mu.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, `{"id": %q, "url": null}`, id)
+	})
+	mux.HandleFunc("/api/v1/media/", func(w http.ResponseWriter, r *http.Request) {
+		id := filepath.Base(r.URL.Path)
+		f.mu.Lock()
+		f.mediaPolls++
+		processed := f.mediaPolls%2 == 0
+		f.mu.Unlock()
+
+		if !processed {
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprintf(w, `{"id": %q, "url": null}`, id)
+			return
+		}
+		fmt.Fprintf(w, `{"id": %q, "url": "https://files.example.com/%s.png"}`, id, id)
+	})
+	mux.HandleFunc("/api/v1/statuses", func(w http.ResponseWriter, r *http.Request) {
+		var status mastodonStatus
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&status))
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.failStatus {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"error": "Validation failed"}`)
+			return
+		}
+		f.statuses = append(f.statuses, status)
+		id := fmt.Sprintf("%d", len(f.statuses))
+		fmt.Fprintf(w, `{"id": %q, "url": "https://mastodon.example.com/@news/%s"}`, id, id)
+	})
+	return mux
+}
+
+func TestMastodonAdapter_PublishImagePost(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "image.png")
+	require.NoError(t, os.WriteFile(imagePath, []byte("image data"), 0o644))
+
+	post := domain.Post{
+		NewsArticle:        domain.NewsArticle{Title: "Test Article", Url: "https://example.com"},
+		ImageGeneratorName: "DALL-E",
+		Images: []domain.GeneratedImage{
+			{Path: domain.ImagePath(imagePath), Prompt: "First prompt"},
+			{Path: domain.ImagePath(imagePath), Prompt: "Second prompt"},
+		},
+	}
+
+	testCases := []struct {
+		name             string
+		config           MastodonConfig
+		failStatus       bool
+		expectedStatuses []mastodonStatus
+		expectedPost     domain.PublishedPost
+		expectedError    string
+	}{
+		{
+			name:   "Success",
+			config: MastodonConfig{AccessToken: "token"},
+			expectedStatuses: []mastodonStatus{
+				{Status: "Test Article https://example.com", MediaIDs: []string{"media-1", "media-2"}, Visibility: "public"},
+				{Status: "Created by DALL-E with the prompts:\n\n1. First prompt\n2. Second prompt", Visibility: "public", InReplyToID: "1"},
+			},
+			expectedPost: domain.PublishedPost{ID: "1", URL: "https://mastodon.example.com/@news/1"},
+		},
+		{
+			name:   "Visibility And Content Warning",
+			config: MastodonConfig{AccessToken: "token", Visibility: "unlisted", ContentWarning: "AI generated"},
+			expectedStatuses: []mastodonStatus{
+				{Status: "Test Article https://example.com", MediaIDs: []string{"media-1", "media-2"}, Visibility: "unlisted", SpoilerText: "AI generated", Sensitive: true},
+				{Status: "Created by DALL-E with the prompts:\n\n1. First prompt\n2. Second prompt", Visibility: "unlisted", SpoilerText: "AI generated", InReplyToID: "1"},
+			},
+			expectedPost: domain.PublishedPost{ID: "1", URL: "https://mastodon.example.com/@news/1"},
+		},
+		{
+			name:          "Status Error",
+			config:        MastodonConfig{AccessToken: "token"},
+			failStatus:    true,
+			expectedError: "failed to post mastodon status: status code: 422",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeMastodonServer{failStatus: tc.failStatus}
+			server := httptest.NewServer(fake.handler(t))
+			defer server.Close()
+
+			tc.config.Server = server.URL + "/"
+			adapter := NewMastodonSocialMediaAdapter(tc.config, server.Client(), logger.NewTestLogger())
+			adapter.(*mastodonAdapter).pollInterval = time.Millisecond
+
+			publishedPost, err := adapter.PublishImagePost(context.Background(), post)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedPost, publishedPost)
+			require.Equal(t, []string{"First prompt", "Second prompt"}, fake.descriptions)
+			require.Equal(t, 4, fake.mediaPolls)
+			require.Equal(t, tc.expectedStatuses, fake.statuses)
+		})
+	}
+}
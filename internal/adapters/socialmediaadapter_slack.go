@@ -0,0 +1,138 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+const (
+	// slackMaxTextCharacters is the length limit of a Block Kit text object
+	slackMaxTextCharacters = 3000
+	// slackMaxAltTextCharacters is the length limit of the alt text of an image block
+	slackMaxAltTextCharacters = 2000
+)
+
+type slackAdapter struct {
+	webhookURL         string
+	publicImageBaseURL string
+	httpClient         httpClient
+	logger             logger.Logger
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// slackBlock is a Block Kit layout block, only the fields of the section, image and context blocks are used
+type slackBlock struct {
+	Type     string       `json:"type"`
+	Text     *slackText   `json:"text,omitempty"`
+	ImageURL string       `json:"image_url,omitempty"`
+	AltText  string       `json:"alt_text,omitempty"`
+	Elements []*slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// NewSlackSocialMediaAdapter creates an adapter posting to a Slack channel through an incoming webhook. Incoming
+// webhooks cannot upload files, so images stored on the local filesystem must be served at publicImageBaseURL.
+func NewSlackSocialMediaAdapter(webhookURL string, publicImageBaseURL string, httpClient httpClient, logger logger.Logger) ports.SocialMediaAdapter {
+	return &slackAdapter{
+		webhookURL:         webhookURL,
+		publicImageBaseURL: publicImageBaseURL,
+		httpClient:         httpClient,
+		logger:             logger,
+	}
+}
+
+func (s *slackAdapter) PublishImagePost(ctx context.Context, post domain.Post) (domain.PublishedPost, error) {
+	preview := s.PreviewImagePost(post)
+
+	message := slackMessage{
+		// The text is shown in notifications
+		Text: preview.Text,
+		Blocks: []slackBlock{{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: truncateRunes(fmt.Sprintf("*<%s|%s>*", post.NewsArticle.Url, escapeSlackText(post.NewsArticle.Title)), slackMaxTextCharacters)},
+		}},
+	}
+	for _, image := range preview.Images {
+		imageURL, err := publicImageURL(s.publicImageBaseURL, image)
+		if err != nil {
+			return domain.PublishedPost{}, fmt.Errorf("slack can only show images at a public url: %w", err)
+		}
+		message.Blocks = append(message.Blocks, slackBlock{
+			Type:     "image",
+			ImageURL: imageURL,
+			AltText:  truncateRunes(image.Prompt, slackMaxAltTextCharacters),
+		})
+	}
+	message.Blocks = append(message.Blocks, slackBlock{
+		Type:     "context",
+		Elements: []*slackText{{Type: "mrkdwn", Text: truncateRunes(escapeSlackText(preview.Replies[0]), slackMaxTextCharacters)}},
+	})
+
+	jsonBytes, err := json.Marshal(message)
+	if err != nil {
+		return domain.PublishedPost{}, fmt.Errorf("failed to marshal slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return domain.PublishedPost{}, fmt.Errorf("failed to create request: %w", withoutURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// The webhook url is the credential of the webhook
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return domain.PublishedPost{}, fmt.Errorf("failed to send request: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		s.logger.Error("failed to post slack message", "response body", string(bodyBytes))
+		return domain.PublishedPost{}, fmt.Errorf("failed to post slack message, status code: %d", resp.StatusCode)
+	}
+
+	// Incoming webhooks do not return anything identifying the message
+	return domain.PublishedPost{}, nil
+}
+
+func (s *slackAdapter) PreviewImagePost(post domain.Post) domain.PostPreview {
+	images := post.Images
+	if len(images) > domain.MaxImagesPerPost {
+		s.logger.Warn("Only up to 4 images are posted to slack, extra images are dropped", "images", len(images))
+		images = images[:domain.MaxImagesPerPost]
+	}
+
+	return domain.PostPreview{
+		AdapterName: s.GetName(),
+		Text:        post.NewsArticle.Title + " " + post.NewsArticle.Url,
+		Replies:     []string{createPromptReply(post.ImageGeneratorName, images)},
+		Images:      images,
+	}
+}
+
+func (s *slackAdapter) GetName() string {
+	return "Slack"
+}
+
+// escapeSlackText escapes the characters Slack uses for links and mentions
+func escapeSlackText(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
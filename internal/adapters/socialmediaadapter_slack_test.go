@@ -0,0 +1,90 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlackAdapter_PublishImagePost(t *testing.T) {
+	post := domain.Post{
+		NewsArticle:        domain.NewsArticle{Title: "Q&A: <What> happened", Url: "https://example.com"},
+		ImageGeneratorName: "DALL-E",
+		Images:             []domain.GeneratedImage{{Path: "data/images/abc.png", Prompt: "A test prompt"}},
+	}
+
+	testCases := []struct {
+		name               string
+		publicImageBaseURL string
+		statusCode         int
+		expectedMessage    string
+		expectedError      string
+	}{
+		{
+			name:               "Success",
+			publicImageBaseURL: "https://cdn.example.com",
+			statusCode:         http.StatusOK,
+			expectedMessage: `{
+				"text": "Q&A: <What> happened https://example.com",
+				"blocks": [
+					{"type": "section", "text": {"type": "mrkdwn", "text": "*<https://example.com|Q&amp;A: &lt;What&gt; happened>*"}},
+					{"type": "image", "image_url": "https://cdn.example.com/abc.png", "alt_text": "A test prompt"},
+					{"type": "context", "elements": [{"type": "mrkdwn", "text": "Created by DALL-E with the prompt:\n\nA test prompt"}]}
+				]
+			}`,
+		},
+		{
+			name:          "Local Image Without Public URL",
+			expectedError: "slack can only show images at a public url: data/images/abc.png is a local file and no public image base url is configured",
+		},
+		{
+			name:               "Webhook Error",
+			publicImageBaseURL: "https://cdn.example.com",
+			statusCode:         http.StatusNotFound,
+			expectedError:      "failed to post slack message, status code: 404",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var message json.RawMessage
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+				w.WriteHeader(tc.statusCode)
+				w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			adapter := NewSlackSocialMediaAdapter(server.URL, tc.publicImageBaseURL, server.Client(), logger.NewTestLogger())
+			publishedPost, err := adapter.PublishImagePost(context.Background(), post)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, domain.PublishedPost{}, publishedPost)
+			require.JSONEq(t, tc.expectedMessage, string(message))
+		})
+	}
+}
+
+func TestSlackAdapter_PublishImagePost_ErrorWithoutWebhookURL(t *testing.T) {
+	// Nothing listens on the address of a closed server
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	adapter := NewSlackSocialMediaAdapter(server.URL+"/services/T000/B000/SECRET", "https://cdn.example.com", server.Client(), logger.NewTestLogger())
+	_, err := adapter.PublishImagePost(context.Background(), domain.Post{
+		NewsArticle: domain.NewsArticle{Title: "Title"},
+		Images:      []domain.GeneratedImage{{Path: "https://example.com/image.png", Prompt: "A test prompt"}},
+	})
+	require.ErrorContains(t, err, "failed to send request")
+	require.NotContains(t, err.Error(), "SECRET")
+}
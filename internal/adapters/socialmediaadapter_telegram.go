@@ -0,0 +1,243 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+const (
+	// telegramMaxCaptionCharacters is the length limit of a photo caption, not counting the HTML markup
+	telegramMaxCaptionCharacters = 1024
+	// telegramMaxMessageCharacters is the length limit of a text message, not counting the HTML markup
+	telegramMaxMessageCharacters = 4096
+)
+
+type telegramAdapter struct {
+	apiURL     string
+	chatID     string
+	httpClient httpClient // Used for the Bot API and downloading images
+	logger     logger.Logger
+}
+
+type telegramMessage struct {
+	MessageID int64 `json:"message_id"`
+	Chat      struct {
+		Username string `json:"username"`
+	} `json:"chat"`
+}
+
+type telegramInputMedia struct {
+	Type      string `json:"type"`
+	Media     string `json:"media"`
+	Caption   string `json:"caption,omitempty"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
+
+// NewTelegramSocialMediaAdapter creates an adapter posting to a chat or channel the bot is a member of
+func NewTelegramSocialMediaAdapter(botToken string, chatID string, httpClient httpClient, logger logger.Logger) ports.SocialMediaAdapter {
+	return &telegramAdapter{
+		apiURL:     "https://api.telegram.org/bot" + botToken,
+		chatID:     chatID,
+		httpClient: httpClient,
+		logger:     logger,
+	}
+}
+
+func (t *telegramAdapter) PublishImagePost(ctx context.Context, post domain.Post) (domain.PublishedPost, error) {
+	preview := t.PreviewImagePost(post)
+
+	var (
+		message telegramMessage
+		err     error
+	)
+	if len(preview.Images) == 1 {
+		message, err = t.sendPhoto(ctx, preview.Images[0], preview.Text)
+	} else {
+		message, err = t.sendMediaGroup(ctx, preview.Images, preview.Text)
+	}
+	if err != nil {
+		return domain.PublishedPost{}, err
+	}
+
+	publishedPost := domain.PublishedPost{ID: strconv.FormatInt(message.MessageID, 10)}
+	// Only messages in public channels and groups have a link
+	if message.Chat.Username != "" {
+		publishedPost.URL = fmt.Sprintf("https://t.me/%s/%d", message.Chat.Username, message.MessageID)
+	}
+
+	for _, reply := range preview.Replies {
+		if err = t.sendMessage(ctx, reply, message.MessageID); err != nil {
+			return publishedPost, fmt.Errorf("failed to send the rest of the telegram caption: %w", err)
+		}
+	}
+	return publishedPost, nil
+}
+
+// PreviewImagePost formats the caption as HTML, when the prompts do not fit in the caption they are sent as a reply
+func (t *telegramAdapter) PreviewImagePost(post domain.Post) domain.PostPreview {
+	images := post.Images
+	if len(images) > domain.MaxImagesPerPost {
+		t.logger.Warn("Only up to 4 images are sent to telegram, extra images are dropped", "images", len(images))
+		images = images[:domain.MaxImagesPerPost]
+	}
+
+	title := truncateRunes(post.NewsArticle.Title, telegramMaxCaptionCharacters)
+	link := fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(post.NewsArticle.Url), html.EscapeString(title))
+	promptReply := createPromptReply(post.ImageGeneratorName, images)
+
+	preview := domain.PostPreview{
+		AdapterName: t.GetName(),
+		Images:      images,
+	}
+	if len([]rune(title+"\n\n"+promptReply)) <= telegramMaxCaptionCharacters {
+		preview.Text = link + "\n\n" + html.EscapeString(promptReply)
+		return preview
+	}
+
+	preview.Text = link
+	if len([]rune(promptReply)) > telegramMaxMessageCharacters {
+		t.logger.Warn("Telegram message was truncated to 4096 characters", "full message", promptReply)
+		promptReply = truncateRunes(promptReply, telegramMaxMessageCharacters)
+	}
+	preview.Replies = []string{html.EscapeString(promptReply)}
+	return preview
+}
+
+func (t *telegramAdapter) GetName() string {
+	return "Telegram"
+}
+
+func (t *telegramAdapter) sendPhoto(ctx context.Context, image domain.GeneratedImage, caption string) (telegramMessage, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	if err := t.writeImage(w, "photo", image); err != nil {
+		return telegramMessage{}, err
+	}
+	fields := map[string]string{"chat_id": t.chatID, "caption": caption, "parse_mode": "HTML"}
+	for name, value := range fields {
+		if err := w.WriteField(name, value); err != nil {
+			return telegramMessage{}, err
+		}
+	}
+	w.Close()
+
+	var message telegramMessage
+	if err := t.call(ctx, "sendPhoto", w.FormDataContentType(), &b, &message); err != nil {
+		return telegramMessage{}, fmt.Errorf("failed to send telegram photo: %w", err)
+	}
+	return message, nil
+}
+
+// sendMediaGroup sends the images as an album, the caption of the first image is shown as the caption of the album
+func (t *telegramAdapter) sendMediaGroup(ctx context.Context, images []domain.GeneratedImage, caption string) (telegramMessage, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	media := make([]telegramInputMedia, len(images))
+	for i, image := range images {
+		name := fmt.Sprintf("photo%d", i)
+		if err := t.writeImage(w, name, image); err != nil {
+			return telegramMessage{}, err
+		}
+		media[i] = telegramInputMedia{Type: "photo", Media: "attach://" + name}
+	}
+	media[0].Caption = caption
+	media[0].ParseMode = "HTML"
+
+	mediaJSON, err := json.Marshal(media)
+	if err != nil {
+		return telegramMessage{}, fmt.Errorf("failed to marshal telegram media: %w", err)
+	}
+	if err = w.WriteField("chat_id", t.chatID); err != nil {
+		return telegramMessage{}, err
+	}
+	if err = w.WriteField("media", string(mediaJSON)); err != nil {
+		return telegramMessage{}, err
+	}
+	w.Close()
+
+	var messages []telegramMessage
+	if err = t.call(ctx, "sendMediaGroup", w.FormDataContentType(), &b, &messages); err != nil {
+		return telegramMessage{}, fmt.Errorf("failed to send telegram media group: %w", err)
+	}
+	if len(messages) == 0 {
+		return telegramMessage{}, fmt.Errorf("telegram did not return the sent media group")
+	}
+	return messages[0], nil
+}
+
+func (t *telegramAdapter) sendMessage(ctx context.Context, text string, replyToMessageID int64) error {
+	jsonBytes, err := json.Marshal(map[string]any{
+		"chat_id":    t.chatID,
+		"text":       text,
+		"parse_mode": "HTML",
+		"reply_parameters": map[string]any{
+			"message_id": replyToMessageID,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal telegram message: %w", err)
+	}
+
+	var message telegramMessage
+	return t.call(ctx, "sendMessage", "application/json", bytes.NewReader(jsonBytes), &message)
+}
+
+func (t *telegramAdapter) writeImage(w *multipart.Writer, name string, image domain.GeneratedImage) error {
+	imageReader, contentType, err := openImage(t.httpClient, image.Path)
+	if err != nil {
+		return err
+	}
+	defer imageReader.Close()
+
+	fw, err := w.CreateFormFile(name, name+imageExtension(contentType))
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(fw, imageReader); err != nil {
+		return fmt.Errorf("failed to read image data: %w", err)
+	}
+	return nil
+}
+
+// call calls a Bot API method and decodes its result into v
+func (t *telegramAdapter) call(ctx context.Context, method string, contentType string, body io.Reader, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.apiURL+"/"+method, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	// The url contains the bot token
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to unmarshal response, status code: %d", resp.StatusCode)
+	}
+	if !response.OK {
+		return fmt.Errorf("status code: %d, %s", resp.StatusCode, response.Description)
+	}
+	if err = json.Unmarshal(response.Result, v); err != nil {
+		return fmt.Errorf("failed to unmarshal result: %w", err)
+	}
+	return nil
+}
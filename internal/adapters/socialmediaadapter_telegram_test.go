@@ -0,0 +1,152 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelegramAdapter_PublishImagePost(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "image.png")
+	require.NoError(t, os.WriteFile(imagePath, []byte("image data"), 0o644))
+	article := domain.NewsArticle{Title: "Rock & Roll <live>", Url: "https://example.com/?a=1&b=2"}
+	longPrompt := strings.Repeat("a", 1100)
+
+	type request struct {
+		method  string
+		caption string
+		files   []string
+		message map[string]any
+	}
+
+	testCases := []struct {
+		name             string
+		images           []domain.GeneratedImage
+		expectedRequests []request
+	}{
+		{
+			name:   "Caption Fits",
+			images: []domain.GeneratedImage{{Path: domain.ImagePath(imagePath), Prompt: "A test prompt"}},
+			expectedRequests: []request{
+				{
+					method:  "sendPhoto",
+					caption: "<a href=\"https://example.com/?a=1&amp;b=2\">Rock &amp; Roll &lt;live&gt;</a>\n\nCreated by DALL-E with the prompt:\n\nA test prompt",
+					files:   []string{"photo"},
+				},
+			},
+		},
+		{
+			name:   "Caption Overflows Into Reply",
+			images: []domain.GeneratedImage{{Path: domain.ImagePath(imagePath), Prompt: longPrompt}},
+			expectedRequests: []request{
+				{
+					method:  "sendPhoto",
+					caption: "<a href=\"https://example.com/?a=1&amp;b=2\">Rock &amp; Roll &lt;live&gt;</a>",
+					files:   []string{"photo"},
+				},
+				{
+					method: "sendMessage",
+					message: map[string]any{
+						"chat_id":          "@news",
+						"text":             "Created by DALL-E with the prompt:\n\n" + longPrompt,
+						"parse_mode":       "HTML",
+						"reply_parameters": map[string]any{"message_id": float64(7)},
+					},
+				},
+			},
+		},
+		{
+			name: "Media Group",
+			images: []domain.GeneratedImage{
+				{Path: domain.ImagePath(imagePath), Prompt: "First prompt"},
+				{Path: domain.ImagePath(imagePath), Prompt: "Second prompt"},
+			},
+			expectedRequests: []request{
+				{
+					method:  "sendMediaGroup",
+					caption: "<a href=\"https://example.com/?a=1&amp;b=2\">Rock &amp; Roll &lt;live&gt;</a>\n\nCreated by DALL-E with the prompts:\n\n1. First prompt\n2. Second prompt",
+					files:   []string{"photo0", "photo1"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests []request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method := strings.TrimPrefix(r.URL.Path, "/bottoken/")
+				received := request{method: method}
+				switch method {
+				case "sendPhoto":
+					require.NoError(t, r.ParseMultipartForm(1<<20))
+					require.Equal(t, "@news", r.FormValue("chat_id"))
+					require.Equal(t, "HTML", r.FormValue("parse_mode"))
+					received.caption = r.FormValue("caption")
+					received.files = []string{"photo"}
+					require.Len(t, r.MultipartForm.File["photo"], 1)
+					fmt.Fprint(w, `{"ok": true, "result": {"message_id": 7, "chat": {"id": -100, "username": "news"}}}`)
+				case "sendMediaGroup":
+					require.NoError(t, r.ParseMultipartForm(1<<20))
+					var media []telegramInputMedia
+					require.NoError(t, json.Unmarshal([]byte(r.FormValue("media")), &media))
+					received.caption = media[0].Caption
+					for _, m := range media {
+						name := strings.TrimPrefix(m.Media, "attach://")
+						require.Len(t, r.MultipartForm.File[name], 1)
+						received.files = append(received.files, name)
+					}
+					fmt.Fprint(w, `{"ok": true, "result": [{"message_id": 7, "chat": {"id": -100, "username": "news"}}, {"message_id": 8}]}`)
+				case "sendMessage":
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&received.message))
+					fmt.Fprint(w, `{"ok": true, "result": {"message_id": 9}}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+					fmt.Fprint(w, `{"ok": false, "description": "Not Found"}`)
+				}
+				requests = append(requests, received)
+			}))
+			defer server.Close()
+
+			adapter := NewTelegramSocialMediaAdapter("token", "@news", server.Client(), logger.NewTestLogger())
+			adapter.(*telegramAdapter).apiURL = server.URL + "/bottoken"
+
+			publishedPost, err := adapter.PublishImagePost(context.Background(), domain.Post{
+				NewsArticle:        article,
+				ImageGeneratorName: "DALL-E",
+				Images:             tc.images,
+			})
+			require.NoError(t, err)
+			require.Equal(t, domain.PublishedPost{ID: "7", URL: "https://t.me/news/7"}, publishedPost)
+			require.Equal(t, tc.expectedRequests, requests)
+		})
+	}
+}
+
+func TestTelegramAdapter_PublishImagePost_ErrorWithoutToken(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "image.png")
+	require.NoError(t, os.WriteFile(imagePath, []byte("image data"), 0o644))
+	// Nothing listens on the address of a closed server
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	adapter := NewTelegramSocialMediaAdapter("SECRET", "@news", server.Client(), logger.NewTestLogger())
+	adapter.(*telegramAdapter).apiURL = server.URL + "/botSECRET"
+
+	_, err := adapter.PublishImagePost(context.Background(), domain.Post{
+		NewsArticle: domain.NewsArticle{Title: "Title"},
+		Images:      []domain.GeneratedImage{{Path: domain.ImagePath(imagePath), Prompt: "A test prompt"}},
+	})
+	require.ErrorContains(t, err, "failed to send request")
+	require.NotContains(t, err.Error(), "SECRET")
+}
@@ -0,0 +1,74 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+// threadsMaxCharacters is the length limit of a Threads post
+const threadsMaxCharacters = 500
+
+type threadsAdapter struct {
+	client *metaGraphClient
+	logger logger.Logger
+}
+
+func NewThreadsSocialMediaAdapter(config MetaGraphConfig, httpClient httpClient, logger logger.Logger) ports.SocialMediaAdapter {
+	return &threadsAdapter{
+		client: newMetaGraphClient(threadsGraphAPI, config, httpClient, logger),
+		logger: logger,
+	}
+}
+
+func (t *threadsAdapter) PublishImagePost(ctx context.Context, post domain.Post) (domain.PublishedPost, error) {
+	preview := t.PreviewImagePost(post)
+
+	mediaID, err := t.client.publishImages(ctx, preview.Images, preview.Text)
+	if err != nil {
+		return domain.PublishedPost{}, err
+	}
+
+	permalink, err := t.client.permalink(ctx, mediaID)
+	if err != nil {
+		// The post is published, only its url is unknown
+		t.logger.Warn("Could not get the url of the threads post", "error", err)
+	}
+	publishedPost := domain.PublishedPost{ID: mediaID, URL: permalink}
+
+	if _, err = t.client.publishText(ctx, preview.Replies[0], mediaID); err != nil {
+		return publishedPost, fmt.Errorf("failed to reply to threads post: %w", err)
+	}
+	return publishedPost, nil
+}
+
+func (t *threadsAdapter) PreviewImagePost(post domain.Post) domain.PostPreview {
+	images := post.Images
+	if len(images) > domain.MaxImagesPerPost {
+		t.logger.Warn("Only up to 4 images are published to threads, extra images are dropped", "images", len(images))
+		images = images[:domain.MaxImagesPerPost]
+	}
+
+	return domain.PostPreview{
+		AdapterName: t.GetName(),
+		Text:        t.truncateString(post.NewsArticle.Title + " " + post.NewsArticle.Url),
+		Replies:     []string{t.truncateString(createPromptReply(post.ImageGeneratorName, images))},
+		Images:      images,
+	}
+}
+
+func (t *threadsAdapter) GetName() string {
+	return "Threads"
+}
+
+// truncateString shortens a string to the 500 character limit of a Threads post.
+func (t *threadsAdapter) truncateString(s string) string {
+	if len([]rune(s)) > threadsMaxCharacters {
+		t.logger.Warn("Threads post was truncated to 500 characters", "full post", s)
+		return truncateRunes(s, threadsMaxCharacters)
+	}
+	return s
+}
@@ -0,0 +1,41 @@
+package adapters
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreadsAdapter_PublishImagePost(t *testing.T) {
+	fake, server := newFakeMetaGraphServer(t, threadsGraphAPI)
+	defer server.Close()
+
+	adapter := NewThreadsSocialMediaAdapter(MetaGraphConfig{UserID: "user-id", AccessToken: "token"}, nil, logger.NewTestLogger())
+	useFakeServer(adapter.(*threadsAdapter).client, server)
+
+	publishedPost, err := adapter.PublishImagePost(context.Background(), domain.Post{
+		NewsArticle:        domain.NewsArticle{Title: "Test Article", Url: "https://example.com"},
+		ImageGeneratorName: "DALL-E",
+		Images:             []domain.GeneratedImage{{Path: "https://test.com/1.png", Prompt: "A test prompt"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, domain.PublishedPost{ID: "media-1", URL: "https://example.com/p/media-1/"}, publishedPost)
+	require.Equal(t, []url.Values{
+		{
+			"text":       {"Test Article https://example.com"},
+			"media_type": {"IMAGE"},
+			"image_url":  {"https://test.com/1.png"},
+			"alt_text":   {"A test prompt"},
+		},
+		{
+			"text":        {"Created by DALL-E with the prompt:\n\nA test prompt"},
+			"media_type":  {"TEXT"},
+			"reply_to_id": {"media-1"},
+		},
+	}, fake.published)
+	require.Equal(t, map[string]int{"container-1": 2, "container-2": 2}, fake.polls)
+}
@@ -9,18 +9,45 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/BaronBonet/content-generator/internal/core/domain"
 	"github.com/BaronBonet/content-generator/internal/core/ports"
 	"github.com/BaronBonet/go-logger/logger"
-	"github.com/dghubble/oauth1"
+)
+
+const (
+	twitterUploadURL   = "https://upload.twitter.com/1.1/media/upload.json"
+	twitterMetadataURL = "https://upload.twitter.com/1.1/media/metadata/create.json"
+	// twitterUploadChunkSize is the size of the chunks media is uploaded in, twitter accepts chunks of up to 5MB
+	twitterUploadChunkSize = 4 * 1024 * 1024
+	// twitterMaxAltTextCharacters is the length limit of the alt text of an image
+	twitterMaxAltTextCharacters = 1000
 )
 
 type twitterAdapter struct {
 	httpOAuthClient httpClient
 	httpClient      httpClient // Used for downloading images
 	logger          logger.Logger
+	// checkAfterUnit is the unit of the time twitter asks to wait before checking the status of uploaded media
+	checkAfterUnit time.Duration
+}
+
+type twitterUploadResponse struct {
+	MediaIDString  string                 `json:"media_id_string"`
+	ProcessingInfo *twitterProcessingInfo `json:"processing_info"`
+}
+
+type twitterProcessingInfo struct {
+	// State is one of pending, in_progress, failed or succeeded
+	State          string `json:"state"`
+	CheckAfterSecs int    `json:"check_after_secs"`
+	Error          *struct {
+		Message string `json:"message"`
+	} `json:"error"`
 }
 
 type tweet struct {
@@ -35,35 +62,64 @@ func NewTwitterSocialMediaAdapter(httpOAuthClient httpClient, httpClient httpCli
 		httpOAuthClient: httpOAuthClient,
 		httpClient:      httpClient,
 		logger:          logger,
+		checkAfterUnit:  time.Second,
 	}
 }
 
-func (t *twitterAdapter) PublishImagePost(ctx context.Context, image domain.ImagePath, prompt string, imageGeneratorName string, newsArticle domain.NewsArticle) error {
-	mediaID, err := t.uploadImage(ctx, string(image))
-	if err != nil {
-		return err
+func (t *twitterAdapter) PublishImagePost(ctx context.Context, post domain.Post) (domain.PublishedPost, error) {
+	preview := t.PreviewImagePost(post)
+
+	mediaIDs := make([]string, 0, len(preview.Images))
+	for _, image := range preview.Images {
+		mediaID, err := t.uploadImage(ctx, image)
+		if err != nil {
+			return domain.PublishedPost{}, err
+		}
+		mediaIDs = append(mediaIDs, mediaID)
 	}
 
-	tweetID, err := t.createTweet(ctx, t.truncateString(newsArticle.Title+" "+newsArticle.Url), mediaID)
+	tweetID, err := t.createTweet(ctx, preview.Text, mediaIDs)
 	if err != nil {
-		return err
+		return domain.PublishedPost{}, err
+	}
+	publishedPost := domain.PublishedPost{ID: tweetID, URL: "https://twitter.com/i/web/status/" + tweetID}
+
+	// Each reply answers the previous one, so the prompts read as a thread
+	parentID := tweetID
+	for _, reply := range preview.Replies {
+		parentID, err = t.replyToTweet(ctx, parentID, reply)
+		if err != nil {
+			return publishedPost, err
+		}
 	}
+	return publishedPost, nil
+}
 
-	reply := fmt.Sprintf("Created by %s with the prompt:\n\n%s", imageGeneratorName, prompt)
+func (t *twitterAdapter) PreviewImagePost(post domain.Post) domain.PostPreview {
+	images := post.Images
+	if len(images) > domain.MaxImagesPerPost {
+		t.logger.Warn("Twitter only supports up to 4 images per tweet, extra images are dropped", "images", len(images))
+		images = images[:domain.MaxImagesPerPost]
+	}
 
-	return t.replyToTweet(ctx, tweetID, reply)
+	return domain.PostPreview{
+		AdapterName: t.GetName(),
+		Text:        t.createTweetText(post.NewsArticle),
+		Replies:     splitTweetThread(createPromptReply(post.ImageGeneratorName, images)),
+		Images:      images,
+	}
 }
 
 func (t *twitterAdapter) GetName() string {
 	return "Twitter"
 }
 
-func (t *twitterAdapter) createTweet(ctx context.Context, tweetText, mediaID string) (string, error) {
+func (t *twitterAdapter) createTweet(ctx context.Context, tweetText string, mediaIDs []string) (string, error) {
 
 	tweetData := tweet{
 		Text: tweetText,
 	}
-	tweetData.Attachments.MediaIDs = append(tweetData.Attachments.MediaIDs, mediaID)
+	tweetData.Attachments.MediaIDs = append(tweetData.Attachments.MediaIDs, mediaIDs...)
 
 	jsonBytes, err := json.Marshal(tweetData)
 	if err != nil {
@@ -105,68 +161,204 @@ func (t *twitterAdapter) createTweet(ctx context.Context, tweetText, mediaID str
 	return response.Data.ID, nil
 }
 
-// uploadImage uploads an image to Twitter and returns the media ID, uses the v1.1 API
-func (t *twitterAdapter) uploadImage(ctx context.Context, imageURL string) (string, error) {
-	resp, err := t.httpClient.Get(imageURL)
+// uploadImage uploads an image to Twitter with the chunked upload flow of the v1.1 API, which also supports large
+// files, GIFs and videos, and returns the media ID. The prompt of the image is added as alt text.
+func (t *twitterAdapter) uploadImage(ctx context.Context, image domain.GeneratedImage) (string, error) {
+	imageReader, contentType, err := openImage(t.httpClient, image.Path)
 	if err != nil {
-		return "", fmt.Errorf("failed to download image: %w", err)
+		return "", err
 	}
-	defer resp.Body.Close()
+	defer imageReader.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to download image, status code: %d", resp.StatusCode)
+	imgData, err := io.ReadAll(imageReader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image data: %w", err)
+	}
+	// The content type of a download or the file extension is not always accurate, so it is detected from the data
+	mediaType := http.DetectContentType(imgData)
+	if !strings.HasPrefix(mediaType, "image/") && !strings.HasPrefix(mediaType, "video/") {
+		mediaType = imageContentType(imageExtension(contentType))
 	}
 
-	imgData, err := io.ReadAll(resp.Body)
+	initResponse, err := t.uploadRequest(ctx, url.Values{
+		"command":        {"INIT"},
+		"total_bytes":    {strconv.Itoa(len(imgData))},
+		"media_type":     {mediaType},
+		"media_category": {twitterMediaCategory(mediaType)},
+	}, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to read image data: %w", err)
+		return "", err
 	}
+	mediaID := initResponse.MediaIDString
 
-	var b bytes.Buffer
-	w := multipart.NewWriter(&b)
-	fw, err := w.CreateFormFile("media", "media.png")
+	for segment := 0; segment*twitterUploadChunkSize < len(imgData); segment++ {
+		chunk := imgData[segment*twitterUploadChunkSize:]
+		if len(chunk) > twitterUploadChunkSize {
+			chunk = chunk[:twitterUploadChunkSize]
+		}
+		_, err = t.uploadRequest(ctx, url.Values{
+			"command":       {"APPEND"},
+			"media_id":      {mediaID},
+			"segment_index": {strconv.Itoa(segment)},
+		}, chunk)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	finalizeResponse, err := t.uploadRequest(ctx, url.Values{"command": {"FINALIZE"}, "media_id": {mediaID}}, nil)
 	if err != nil {
 		return "", err
 	}
-	if _, err = fw.Write(imgData); err != nil {
+	if err = t.waitForProcessing(ctx, mediaID, finalizeResponse.ProcessingInfo); err != nil {
 		return "", err
 	}
-	w.Close()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://upload.twitter.com/1.1/media/upload.json", &b)
+	if image.Prompt != "" {
+		// Missing alt text does not prevent publishing
+		if err = t.createMediaMetadata(ctx, mediaID, image.Prompt); err != nil {
+			t.logger.Error("Could not add alt text to the image", "error", err)
+		}
+	}
+	return mediaID, nil
+}
 
+// twitterMediaCategory is the category of uploaded media, which determines how twitter processes it
+func twitterMediaCategory(mediaType string) string {
+	switch {
+	case mediaType == "image/gif":
+		return "tweet_gif"
+	case strings.HasPrefix(mediaType, "video/"):
+		return "tweet_video"
+	default:
+		return "tweet_image"
+	}
+}
+
+// waitForProcessing polls the status of uploaded media until twitter finished processing it, media that does not
+// need processing has no processing info
+func (t *twitterAdapter) waitForProcessing(ctx context.Context, mediaID string, processingInfo *twitterProcessingInfo) error {
+	for processingInfo != nil {
+		switch processingInfo.State {
+		case "succeeded":
+			return nil
+		case "failed":
+			message := "unknown error"
+			if processingInfo.Error != nil {
+				message = processingInfo.Error.Message
+			}
+			return fmt.Errorf("twitter failed to process media %s: %s", mediaID, message)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("twitter did not process media %s in time: %w", mediaID, ctx.Err())
+		case <-time.After(time.Duration(processingInfo.CheckAfterSecs) * t.checkAfterUnit):
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, twitterUploadURL+"?"+url.Values{"command": {"STATUS"}, "media_id": {mediaID}}.Encode(), nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		statusResponse, err := t.doUploadRequest(req)
+		if err != nil {
+			return err
+		}
+		processingInfo = statusResponse.ProcessingInfo
+	}
+	return nil
+}
+
+func (t *twitterAdapter) createMediaMetadata(ctx context.Context, mediaID string, altText string) error {
+	var metadata struct {
+		MediaID string `json:"media_id"`
+		AltText struct {
+			Text string `json:"text"`
+		} `json:"alt_text"`
+	}
+	metadata.MediaID = mediaID
+	metadata.AltText.Text = truncateRunes(altText, twitterMaxAltTextCharacters)
+
+	jsonBytes, err := json.Marshal(metadata)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to marshal media metadata: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, twitterMetadataURL, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Content-Type", "application/json")
 
-	res, err := t.httpOAuthClient.Do(req)
+	_, err = t.doUploadRequest(req)
+	return err
+}
+
+// uploadRequest sends a command of the chunked upload flow, chunks of media are sent as multipart form data
+func (t *twitterAdapter) uploadRequest(ctx context.Context, params url.Values, chunk []byte) (twitterUploadResponse, error) {
+	var (
+		body        io.Reader
+		contentType string
+	)
+	if chunk == nil {
+		body = strings.NewReader(params.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	} else {
+		var b bytes.Buffer
+		w := multipart.NewWriter(&b)
+		for name, values := range params {
+			if err := w.WriteField(name, values[0]); err != nil {
+				return twitterUploadResponse{}, err
+			}
+		}
+		fw, err := w.CreateFormFile("media", "blob")
+		if err != nil {
+			return twitterUploadResponse{}, err
+		}
+		if _, err = fw.Write(chunk); err != nil {
+			return twitterUploadResponse{}, err
+		}
+		w.Close()
+		body = &b
+		contentType = w.FormDataContentType()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, twitterUploadURL, body)
 	if err != nil {
-		return "", err
+		return twitterUploadResponse{}, err
 	}
+	req.Header.Set("Content-Type", contentType)
+	return t.doUploadRequest(req)
+}
 
+func (t *twitterAdapter) doUploadRequest(req *http.Request) (twitterUploadResponse, error) {
+	res, err := t.httpOAuthClient.Do(req)
+	if err != nil {
+		return twitterUploadResponse{}, err
+	}
 	defer res.Body.Close()
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return "", err
+		return twitterUploadResponse{}, err
 	}
 
 	if res.StatusCode >= 400 {
-		return "", errors.New(string(body))
+		return twitterUploadResponse{}, errors.New(string(body))
 	}
 
-	var data struct {
-		MediaIDString string `json:"media_id_string"`
+	// APPEND and metadata requests respond without a body
+	var data twitterUploadResponse
+	if len(bytes.TrimSpace(body)) == 0 {
+		return data, nil
 	}
-	err = json.Unmarshal(body, &data)
-	if err != nil {
-		return "", err
+	if err = json.Unmarshal(body, &data); err != nil {
+		return twitterUploadResponse{}, err
 	}
-	return data.MediaIDString, nil
+	return data, nil
 }
 
-func (t *twitterAdapter) replyToTweet(ctx context.Context, originalTweetID, replyText string) error {
+// replyToTweet replies to a tweet and returns the ID of the reply
+func (t *twitterAdapter) replyToTweet(ctx context.Context, originalTweetID, replyText string) (string, error) {
 	type replyTweet struct {
 		Text  string `json:"text"`
 		Reply struct {
@@ -175,63 +367,62 @@ func (t *twitterAdapter) replyToTweet(ctx context.Context, originalTweetID, repl
 	}
 
 	replyData := replyTweet{
-		Text: t.truncateString(replyText),
+		Text: replyText,
 	}
 	replyData.Reply.InReplyToTweetID = originalTweetID
 
 	jsonBytes, err := json.Marshal(replyData)
 	if err != nil {
-		return fmt.Errorf("failed to marshal reply data: %w", err)
+		return "", fmt.Errorf("failed to marshal reply data: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.twitter.com/2/tweets", bytes.NewBuffer(jsonBytes))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := t.httpOAuthClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return "", fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	bodyBytes, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
 		t.logger.Error("failed to post tweet reply", "response body", string(bodyBytes))
-		return fmt.Errorf("failed to post reply tweet, status code: %d", resp.StatusCode)
+		return "", fmt.Errorf("failed to post reply tweet, status code: %d", resp.StatusCode)
 	}
 
-	return nil
+	var response struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err = json.Unmarshal(bodyBytes, &response); err != nil {
+		return "", fmt.Errorf("failed to unmarshal reply response: %w", err)
+	}
+	return response.Data.ID, nil
 }
 
-// truncateString breaks a string up into a chunk of size up to 280.
-func (t *twitterAdapter) truncateString(s string) string {
-	runeStr := []rune(s) // Convert to runes for proper handling of special characters
-
-	if len(runeStr) > 280 {
-		t.logger.Warn("Tweet was truncated to 280 characters", "full tweet", s)
-		runeStr = runeStr[:280]
+// createPromptReply describes how the images were created, listing the prompt of each image when there are several.
+func createPromptReply(imageGeneratorName string, images []domain.GeneratedImage) string {
+	if len(images) == 1 {
+		return fmt.Sprintf("Created by %s with the prompt:\n\n%s", imageGeneratorName, images[0].Prompt)
 	}
-
-	return string(runeStr)
+	prompts := make([]string, len(images))
+	for i, image := range images {
+		prompts[i] = fmt.Sprintf("%d. %s", i+1, image.Prompt)
+	}
+	return fmt.Sprintf("Created by %s with the prompts:\n\n%s", imageGeneratorName, strings.Join(prompts, "\n"))
 }
 
-// NewTwitterAdapterFromEnv is a helper function to create a TwitterAdapter from environment variables
-func NewTwitterAdapterFromEnv(logger logger.Logger) (ports.SocialMediaAdapter, error) {
-	keys := []string{"TWITTER_API_KEY", "TWITTER_API_KEY_SECRET", "TWITTER_ACCESS_TOKEN", "TWITTER_ACCESS_TOKEN_SECRET"}
-
-	values := make(map[string]string, len(keys))
-	for _, key := range keys {
-		value, exists := os.LookupEnv(key)
-		if !exists {
-			return nil, fmt.Errorf("environment variable %s not set", key)
-		}
-		values[key] = value
+// createTweetText links the article, the title is shortened when the tweet would be too long so the link stays intact
+func (t *twitterAdapter) createTweetText(article domain.NewsArticle) string {
+	text := article.Title + " " + article.Url
+	if twitterWeightedLength(text) <= twitterMaxWeightedLength {
+		return text
 	}
-
-	config := oauth1.NewConfig(values["TWITTER_API_KEY"], values["TWITTER_API_KEY_SECRET"])
-	token := oauth1.NewToken(values["TWITTER_ACCESS_TOKEN"], values["TWITTER_ACCESS_TOKEN_SECRET"])
-
-	return NewTwitterSocialMediaAdapter(config.Client(oauth1.NoContext, token), http.DefaultClient, logger), nil
+	t.logger.Warn("Tweet was too long, the title is shortened", "full tweet", text)
+	return truncateTweet(article.Title, twitterMaxWeightedLength-twitterWeightedLength(" "+article.Url)) + " " + article.Url
 }
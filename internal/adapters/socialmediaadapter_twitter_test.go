@@ -5,9 +5,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/BaronBonet/content-generator/internal/core/domain"
 	"github.com/BaronBonet/content-generator/internal/infrastructure"
@@ -27,6 +32,7 @@ func TestTwitterAdapter_PublishImagePost(t *testing.T) {
 		errorResponse error
 		newsArticle   domain.NewsArticle
 		prompt        string
+		images        []domain.GeneratedImage
 	}
 
 	testCases := []testCase{
@@ -55,20 +61,17 @@ func TestTwitterAdapter_PublishImagePost(t *testing.T) {
 		{
 			name: "Tweet Truncated",
 			setupMocks: func(tc *testCase) {
-				mockClient.On("Get", mock.AnythingOfType("string")).Return(&http.Response{Body: io.NopCloser(bytes.NewReader([]byte(""))),
-					StatusCode: http.StatusOK,
-				}, nil).Once()
-				mockOAuthClient.On("Do", mock.Anything).Return(&http.Response{
+				mockClient.On("Get", mock.AnythingOfType("string")).Return(&http.Response{Body: io.NopCloser(bytes.NewReader([]byte("image data"))),
 					StatusCode: http.StatusOK,
-					Body:       io.NopCloser(strings.NewReader(`{"media_id_string": "12345"}`)),
 				}, nil).Once()
+				mockChunkedUpload(mockOAuthClient, "12345", false)
 				mockOAuthClient.On("Do", mock.Anything).Return(&http.Response{
 					StatusCode: http.StatusCreated,
 					Body:       io.NopCloser(bytes.NewReader([]byte(`{"data": {"id": "67890", "text": "test text"}}`))),
 				}, nil).Once()
 				mockOAuthClient.On("Do", mock.Anything).Return(&http.Response{
 					StatusCode: http.StatusCreated,
-					Body:       io.NopCloser(bytes.NewReader([]byte(""))),
+					Body:       io.NopCloser(bytes.NewReader([]byte(`{"data": {"id": "67891", "text": "reply"}}`))),
 				}, nil).Once()
 			},
 			errorResponse: nil,
@@ -77,13 +80,85 @@ func TestTwitterAdapter_PublishImagePost(t *testing.T) {
 				Url:   "https://example.com",
 			},
 		},
+		{
+			name: "Multiple Images",
+			setupMocks: func(tc *testCase) {
+				mockClient.On("Get", "https://test.com/1.png").Return(&http.Response{Body: io.NopCloser(bytes.NewReader([]byte("image data"))),
+					StatusCode: http.StatusOK,
+				}, nil).Once()
+				mockClient.On("Get", "https://test.com/2.png").Return(&http.Response{Body: io.NopCloser(bytes.NewReader([]byte("image data"))),
+					StatusCode: http.StatusOK,
+				}, nil).Once()
+				mockChunkedUpload(mockOAuthClient, "1", true)
+				mockChunkedUpload(mockOAuthClient, "2", true)
+				mockOAuthClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+					body, _ := io.ReadAll(req.Body)
+					return strings.Contains(string(body), `"media_ids":["1","2"]`)
+				})).Return(&http.Response{
+					StatusCode: http.StatusCreated,
+					Body:       io.NopCloser(bytes.NewReader([]byte(`{"data": {"id": "67890", "text": "test text"}}`))),
+				}, nil).Once()
+				mockOAuthClient.On("Do", mock.Anything).Return(&http.Response{
+					StatusCode: http.StatusCreated,
+					Body:       io.NopCloser(bytes.NewReader([]byte(`{"data": {"id": "67891", "text": "reply"}}`))),
+				}, nil).Once()
+			},
+			errorResponse: nil,
+			newsArticle:   domain.NewsArticle{Title: "Title", Url: "https://example.com"},
+			images: []domain.GeneratedImage{
+				{Path: "https://test.com/1.png", Prompt: "first prompt"},
+				{Path: "https://test.com/2.png", Prompt: "second prompt"},
+			},
+		},
+		{
+			name: "Long Prompt Thread",
+			setupMocks: func(tc *testCase) {
+				mockClient.On("Get", mock.AnythingOfType("string")).Return(&http.Response{Body: io.NopCloser(bytes.NewReader([]byte("image data"))),
+					StatusCode: http.StatusOK,
+				}, nil).Once()
+				mockChunkedUpload(mockOAuthClient, "12345", true)
+				mockOAuthClient.On("Do", mock.Anything).Return(&http.Response{
+					StatusCode: http.StatusCreated,
+					Body:       io.NopCloser(bytes.NewReader([]byte(`{"data": {"id": "67890", "text": "test text"}}`))),
+				}, nil).Once()
+				// Each part of the thread replies to the previous tweet
+				mockOAuthClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+					// Read a copy of the body, the other matchers are evaluated on the same request
+					reader, _ := req.GetBody()
+					body, _ := io.ReadAll(reader)
+					return strings.Contains(string(body), `"in_reply_to_tweet_id":"67890"`) && strings.Contains(string(body), `(1/2)`)
+				})).Return(&http.Response{
+					StatusCode: http.StatusCreated,
+					Body:       io.NopCloser(bytes.NewReader([]byte(`{"data": {"id": "67891", "text": "reply"}}`))),
+				}, nil).Once()
+				mockOAuthClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+					// Read a copy of the body, the other matchers are evaluated on the same request
+					reader, _ := req.GetBody()
+					body, _ := io.ReadAll(reader)
+					return strings.Contains(string(body), `"in_reply_to_tweet_id":"67891"`) && strings.Contains(string(body), `(2/2)`)
+				})).Return(&http.Response{
+					StatusCode: http.StatusCreated,
+					Body:       io.NopCloser(bytes.NewReader([]byte(`{"data": {"id": "67892", "text": "reply"}}`))),
+				}, nil).Once()
+			},
+			errorResponse: nil,
+			newsArticle:   domain.NewsArticle{Title: "Title", Url: "https://example.com"},
+			prompt:        strings.Repeat("A long prompt. ", 25),
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.setupMocks(&tc)
+			if tc.images == nil {
+				tc.images = []domain.GeneratedImage{{Path: "https://test.com/test.png", Prompt: tc.prompt}}
+			}
 			twitterAdapter := NewTwitterSocialMediaAdapter(mockOAuthClient, mockClient, testLogger)
-			err := twitterAdapter.PublishImagePost(context.Background(), "https://test.com/test.png", tc.prompt, "test generator", tc.newsArticle)
+			_, err := twitterAdapter.PublishImagePost(context.Background(), domain.Post{
+				NewsArticle:        tc.newsArticle,
+				Images:             tc.images,
+				ImageGeneratorName: "test generator",
+			})
 			require.Equal(t, tc.errorResponse, err)
 			mockOAuthClient.AssertExpectations(t)
 			mockClient.AssertExpectations(t)
@@ -91,3 +166,74 @@ func TestTwitterAdapter_PublishImagePost(t *testing.T) {
 		})
 	}
 }
+
+// mockChunkedUpload expects the requests of uploading a small image, which is uploaded in a single chunk
+func mockChunkedUpload(mockOAuthClient *mockHttpClient, mediaID string, withAltText bool) {
+	for _, body := range []string{fmt.Sprintf(`{"media_id_string": %q}`, mediaID), "", fmt.Sprintf(`{"media_id_string": %q}`, mediaID)} {
+		mockOAuthClient.On("Do", mock.Anything).Return(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil).Once()
+	}
+	if withAltText {
+		mockOAuthClient.On("Do", mock.Anything).Return(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil).Once()
+	}
+}
+
+func TestTwitterAdapter_UploadImage(t *testing.T) {
+	mockOAuthClient := newMockHttpClient(t)
+	// A PNG served with the wrong extension, the media type is detected from the data
+	pngData := []byte("\x89PNG\r\n\x1a\nimage data")
+	imagePath := filepath.Join(t.TempDir(), "image.jpg")
+	require.NoError(t, os.WriteFile(imagePath, pngData, 0o644))
+
+	type request struct {
+		method string
+		url    string
+		body   string
+	}
+	var requests []request
+	responses := []string{
+		`{"media_id_string": "12345"}`,
+		``,
+		`{"media_id_string": "12345", "processing_info": {"state": "pending", "check_after_secs": 1}}`,
+		`{"media_id_string": "12345", "processing_info": {"state": "succeeded"}}`,
+		``,
+	}
+	for _, response := range responses {
+		mockOAuthClient.On("Do", mock.Anything).Run(func(args mock.Arguments) {
+			req := args.Get(0).(*http.Request)
+			var body []byte
+			if req.Body != nil {
+				body, _ = io.ReadAll(req.Body)
+			}
+			if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+				_, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+				form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(1 << 20)
+				require.NoError(t, err)
+				file, _ := form.File["media"][0].Open()
+				chunk, _ := io.ReadAll(file)
+				body = []byte(fmt.Sprintf("command=%s&media_id=%s&segment_index=%s&media=%s",
+					form.Value["command"][0], form.Value["media_id"][0], form.Value["segment_index"][0], chunk))
+			}
+			requests = append(requests, request{method: req.Method, url: req.URL.String(), body: string(body)})
+		}).Return(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(response))}, nil).Once()
+	}
+
+	adapter := NewTwitterSocialMediaAdapter(mockOAuthClient, nil, logger.NewTestLogger()).(*twitterAdapter)
+	adapter.checkAfterUnit = time.Millisecond
+
+	mediaID, err := adapter.uploadImage(context.Background(), domain.GeneratedImage{Path: domain.ImagePath(imagePath), Prompt: "A test prompt"})
+	require.NoError(t, err)
+	require.Equal(t, "12345", mediaID)
+	require.Equal(t, []request{
+		{method: http.MethodPost, url: twitterUploadURL, body: "command=INIT&media_category=tweet_image&media_type=image%2Fpng&total_bytes=18"},
+		{method: http.MethodPost, url: twitterUploadURL, body: "command=APPEND&media_id=12345&segment_index=0&media=" + string(pngData)},
+		{method: http.MethodPost, url: twitterUploadURL, body: "command=FINALIZE&media_id=12345"},
+		{method: http.MethodGet, url: twitterUploadURL + "?command=STATUS&media_id=12345"},
+		{method: http.MethodPost, url: twitterMetadataURL, body: `{"media_id":"12345","alt_text":{"text":"A test prompt"}}`},
+	}, requests)
+}
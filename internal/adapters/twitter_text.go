@@ -0,0 +1,132 @@
+package adapters
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// twitterMaxWeightedLength is the length limit of a tweet, in which most non latin characters and emoji count double
+	twitterMaxWeightedLength = 280
+	// twitterTransformedURLLength is the length every url counts as, because twitter shortens urls with t.co
+	twitterTransformedURLLength = 23
+)
+
+var (
+	twitterURLPattern = regexp.MustCompile(`https?://\S+`)
+	// twitterWordPattern matches a word together with the whitespace following it, so line breaks are kept when splitting
+	twitterWordPattern = regexp.MustCompile(`\S+\s*`)
+)
+
+// twitterWeightedLength counts the length of a tweet the way twitter does, following the weighted ranges of
+// twitter-text: characters in the latin, general punctuation and similar ranges count once, other characters and
+// emoji count twice and urls count as 23 characters regardless of their length.
+func twitterWeightedLength(text string) int {
+	length := 0
+	last := 0
+	for _, loc := range twitterURLPattern.FindAllStringIndex(text, -1) {
+		length += twitterTextWeight(text[last:loc[0]]) + twitterTransformedURLLength
+		last = loc[1]
+	}
+	return length + twitterTextWeight(text[last:])
+}
+
+// twitterTextWeight is the weighted length of text without urls. Twitter normalizes text before counting, so a
+// character with combining marks or an emoji sequence counts as a single character.
+func twitterTextWeight(text string) int {
+	weight := 0
+	for _, grapheme := range splitGraphemes(text) {
+		first, size := utf8.DecodeRuneInString(grapheme)
+		if size < len(grapheme) && (strings.ContainsRune(grapheme, '\ufe0f') || strings.ContainsRune(grapheme, '\u20e3')) {
+			// Emoji presentation sequences such as keycaps count as emoji
+			weight += 2
+			continue
+		}
+		weight += twitterRuneWeight(first)
+	}
+	return weight
+}
+
+func twitterRuneWeight(r rune) int {
+	if r <= 4351 || (r >= 8192 && r <= 8205) || (r >= 8208 && r <= 8223) || (r >= 8242 && r <= 8247) {
+		return 1
+	}
+	return 2
+}
+
+// truncateTweet shortens text on a word boundary, ending it with an ellipsis, so its weighted length is at most max
+func truncateTweet(text string, max int) string {
+	if twitterWeightedLength(text) <= max {
+		return text
+	}
+	graphemes := splitGraphemes(text)
+	cut := 0
+	for cut < len(graphemes) && twitterWeightedLength(strings.Join(graphemes[:cut+1], "")+"…") <= max {
+		cut++
+	}
+	truncated := strings.Join(graphemes[:cut], "")
+	// Prefer cutting between words, unless that leaves very little
+	if i := strings.LastIndexFunc(truncated, unicode.IsSpace); i > len(truncated)/2 {
+		truncated = truncated[:i]
+	}
+	return strings.TrimRightFunc(truncated, unicode.IsSpace) + "…"
+}
+
+// splitTweetThread splits text that is too long for a single tweet into a thread of tweets on word boundaries, each
+// tweet is numbered, e.g. (1/3).
+func splitTweetThread(text string) []string {
+	text = strings.TrimSpace(text)
+	if twitterWeightedLength(text) <= twitterMaxWeightedLength {
+		return []string{text}
+	}
+
+	words := twitterWordPattern.FindAllString(text, -1)
+	// The space reserved for the numbering depends on the number of tweets, so the split is repeated until the
+	// number of digits it reserved space for is correct
+	count := 2
+	for {
+		numberingLength := len(fmt.Sprintf(" (%d/%d)", count, count))
+		tweets := packTweets(words, twitterMaxWeightedLength-numberingLength)
+		if len(fmt.Sprint(len(tweets))) <= len(fmt.Sprint(count)) {
+			for i := range tweets {
+				tweets[i] = fmt.Sprintf("%s (%d/%d)", tweets[i], i+1, len(tweets))
+			}
+			return tweets
+		}
+		count = len(tweets)
+	}
+}
+
+// packTweets greedily fills tweets of at most max weighted length with words, splitting words that are too long
+func packTweets(words []string, max int) []string {
+	var (
+		tweets  []string
+		current string
+	)
+	for _, word := range words {
+		if twitterWeightedLength(strings.TrimRightFunc(current+word, unicode.IsSpace)) <= max {
+			current += word
+			continue
+		}
+		if current != "" {
+			tweets = append(tweets, strings.TrimRightFunc(current, unicode.IsSpace))
+		}
+		current = word
+		for twitterWeightedLength(strings.TrimRightFunc(current, unicode.IsSpace)) > max {
+			graphemes := splitGraphemes(current)
+			cut := 1
+			for cut < len(graphemes) && twitterWeightedLength(strings.Join(graphemes[:cut+1], "")) <= max {
+				cut++
+			}
+			tweets = append(tweets, strings.Join(graphemes[:cut], ""))
+			current = strings.Join(graphemes[cut:], "")
+		}
+	}
+	if strings.TrimSpace(current) != "" {
+		tweets = append(tweets, strings.TrimRightFunc(current, unicode.IsSpace))
+	}
+	return tweets
+}
@@ -0,0 +1,68 @@
+package adapters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwitterWeightedLength(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{name: "ASCII", text: "Hello world", expected: 11},
+		{name: "URL", text: "Read https://www.nytimes.com/2023/08/01/world/a-very-long-article-slug.html now", expected: 5 + 23 + 4},
+		{name: "Accents", text: "Café", expected: 4},
+		{name: "Combining Mark", text: "Cafe\u0301", expected: 4},
+		{name: "CJK", text: "日本語", expected: 6},
+		{name: "Emoji", text: "👍", expected: 2},
+		{name: "Emoji Sequence", text: "👩‍👩‍👧", expected: 2},
+		{name: "Emoji Presentation", text: "❤️", expected: 2},
+		{name: "Punctuation", text: "“quoted” — …", expected: 13},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, twitterWeightedLength(tc.text))
+		})
+	}
+}
+
+func TestTruncateTweet(t *testing.T) {
+	require.Equal(t, "short title", truncateTweet("short title", 20))
+	require.Equal(t, "The quick brown…", truncateTweet("The quick brown fox jumps", 18))
+	// The ellipsis counts as two characters
+	require.Equal(t, "aaaaaaaa…", truncateTweet(strings.Repeat("a", 20), 10))
+	require.Equal(t, "日本語…", truncateTweet("日本語日本語", 9))
+}
+
+func TestSplitTweetThread(t *testing.T) {
+	t.Run("Fits In One Tweet", func(t *testing.T) {
+		require.Equal(t, []string{"Created by DALL-E with the prompt:\n\nA cat"}, splitTweetThread("Created by DALL-E with the prompt:\n\nA cat"))
+	})
+
+	t.Run("Long Text", func(t *testing.T) {
+		text := "Created by DALL-E with the prompt:\n\n" + strings.TrimSpace(strings.Repeat("An oil painting of a lighthouse in a storm. ", 20))
+		tweets := splitTweetThread(text)
+
+		require.Len(t, tweets, 4)
+		var words []string
+		for i, tweet := range tweets {
+			require.LessOrEqual(t, twitterWeightedLength(tweet), twitterMaxWeightedLength)
+			suffix := " (" + string(rune('1'+i)) + "/4)"
+			require.True(t, strings.HasSuffix(tweet, suffix), tweet)
+			words = append(words, strings.Fields(strings.TrimSuffix(tweet, suffix))...)
+		}
+		// Tweets are split between words, so no word is cut off
+		require.Equal(t, strings.Fields(text), words)
+		require.True(t, strings.HasPrefix(tweets[0], "Created by DALL-E with the prompt:\n\nAn oil painting"))
+	})
+
+	t.Run("Long Word", func(t *testing.T) {
+		tweets := splitTweetThread(strings.Repeat("a", 300))
+		require.Equal(t, []string{strings.Repeat("a", 274) + " (1/2)", strings.Repeat("a", 26) + " (2/2)"}, tweets)
+	})
+}
@@ -0,0 +1,183 @@
+// Package app builds the content service from the configuration, so every binary is wired the same way
+package app
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/BaronBonet/content-generator/internal/adapters"
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/content-generator/internal/core/service"
+	"github.com/BaronBonet/content-generator/internal/infrastructure"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+// defaultConfig reads everything from the environment variables that were used before there was a configuration file
+//
+//go:embed default_config.yaml
+var defaultConfig []byte
+
+// LoadConfig reads the configuration file at path, the default configuration is used when path is empty
+func LoadConfig(path string) (infrastructure.Config, error) {
+	if path == "" {
+		return infrastructure.ParseConfig(defaultConfig)
+	}
+	return infrastructure.LoadConfig(path)
+}
+
+// NewService creates the adapters of the configuration and a service using them. The secrets referenced by the
+// settings are looked up first. Social media adapters that are disabled or missing settings are skipped and reported
+// in the logs, the other adapters are required.
+func NewService(ctx context.Context, config infrastructure.Config, logger logger.Logger) (ports.Service, error) {
+	secretsAdapter, err := newSecretsAdapter(config.Secrets)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: %w", err)
+	}
+	config, err = newSecretResolver(secretsAdapter, logger).resolveConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up secrets: %w", err)
+	}
+
+	var errs []error
+	// The repository also stores the access tokens some social media adapters refresh
+	repositoryAdapter := newRepositoryAdapter(config.Repository)
+
+	newsAdapter, err := newAdapter(newsFactories, config.News, repositoryAdapter, logger)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("news: %w", err))
+	}
+	llmAdapter, err := newAdapter(llmFactories, config.LLM, repositoryAdapter, logger)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("llm: %w", err))
+	}
+	imageGenerationAdapter, err := newAdapter(imageGenerationFactories, config.ImageGenerator, repositoryAdapter, logger)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("imageGenerator: %w", err))
+	}
+	socialMediaAdapters, statuses, err := newSocialMediaAdapters("socialMedia", config.SocialMedia, repositoryAdapter, logger)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	topicClassifier, err := domain.ParseTopicClassifier(config.Routing.Classifier)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("routing: %w", err))
+	}
+	topics := make([]domain.Topic, 0, len(config.Routing.Topics))
+	for _, topic := range config.Routing.Topics {
+		topics = append(topics, domain.Topic{
+			Name:           topic.Name,
+			Sections:       topic.Sections,
+			PromptStyle:    topic.PromptStyle,
+			PromptTemplate: topic.PromptTemplate,
+		})
+	}
+	options := []service.Option{
+		service.WithArticleAdapter(adapters.NewWebArticleAdapter(http.DefaultClient)),
+		service.WithPublishTimeout(config.Publishing.Timeout),
+		service.WithPublishBudget(config.Publishing.Budget),
+		service.WithTopics(topicClassifier, topics),
+	}
+	if config.ContentPolicy.Enabled {
+		contentPolicy, err := newContentPolicy(config.ContentPolicy)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("contentPolicy: %w", err))
+		}
+		options = append(options, service.WithContentPolicy(contentPolicy))
+	}
+	if config.Enrichment.Enabled {
+		options = append(options, service.WithArticleEnrichment(config.Enrichment.MaxBodyLength))
+	}
+	for i, channelConfig := range config.Channels {
+		channelAdapters, channelStatuses, err := newSocialMediaAdapters(fmt.Sprintf("channels[%d].socialMedia", i), channelConfig.SocialMedia, repositoryAdapter, logger)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for j := range channelStatuses {
+			channelStatuses[j].Channel = channelConfig.Name
+		}
+		statuses = append(statuses, channelStatuses...)
+		options = append(options, service.WithChannel(domain.Channel{
+			Name:        channelConfig.Name,
+			PromptStyle: channelConfig.PromptStyle,
+			Topics:      channelConfig.Topics,
+		}, channelAdapters))
+	}
+	if err = errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	logAdapterStatuses(logger, statuses)
+
+	if config.Preview.Directory != "" {
+		options = append(options, service.WithPreviewAdapter(adapters.NewDirectoryPreviewAdapter(config.Preview.Directory, http.DefaultClient)))
+	} else {
+		options = append(options, service.WithPreviewAdapter(adapters.NewWriterPreviewAdapter(os.Stdout)))
+	}
+	if repositoryAdapter != nil {
+		options = append(options, service.WithRepositoryAdapter(repositoryAdapter))
+	}
+
+	return service.NewNewsContentService(logger, newsAdapter, llmAdapter, imageGenerationAdapter, socialMediaAdapters, options...), nil
+}
+
+// newRepositoryAdapter creates the repository drafts and checkpoints are stored in, nil when nothing is stored
+func newRepositoryAdapter(config infrastructure.RepositoryConfig) ports.RepositoryAdapter {
+	switch {
+	case config.Bucket != "":
+		cacheDirectory := config.Directory
+		if cacheDirectory == "" {
+			cacheDirectory = filepath.Join(os.TempDir(), "content-generator")
+		}
+		awsConfig := adapters.AWSConfig{
+			Region:          config.Region,
+			AccessKeyID:     config.AccessKeyID,
+			SecretAccessKey: config.SecretAccessKey,
+			SessionToken:    config.SessionToken,
+			Endpoint:        config.Endpoint,
+		}
+		return adapters.NewS3RepositoryAdapter(awsConfig, config.Bucket, config.Prefix, cacheDirectory, http.DefaultClient)
+	case config.Directory != "":
+		return adapters.NewFileSystemRepositoryAdapter(config.Directory, http.DefaultClient)
+	}
+	return nil
+}
+
+// newContentPolicy creates the content policy of the configuration, with the default categories when it has none
+func newContentPolicy(config infrastructure.ContentPolicyConfig) (domain.ContentPolicy, error) {
+	if len(config.Categories) == 0 {
+		return domain.NewContentPolicy(domain.DefaultSensitiveCategories, config.LLM, config.NeutralStyle), nil
+	}
+
+	var categories []domain.SensitiveCategory
+	var errs []error
+	for i, category := range config.Categories {
+		action, err := domain.ParsePolicyAction(category.Action)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("categories[%d]: %w", i, err))
+		}
+		categories = append(categories, domain.SensitiveCategory{Name: category.Name, Action: action, Keywords: category.Keywords})
+	}
+	return domain.NewContentPolicy(categories, config.LLM, config.NeutralStyle), errors.Join(errs...)
+}
+
+// logAdapterStatuses reports at startup which social medias are published to, and why the others are not
+func logAdapterStatuses(logger logger.Logger, statuses []AdapterStatus) {
+	enabled := 0
+	for _, status := range statuses {
+		if status.Enabled {
+			enabled++
+			logger.Info("Social media adapter enabled", "channel", status.Channel, "id", status.ID, "type", status.Type, "name", status.Name)
+		} else {
+			logger.Info("Social media adapter disabled", "channel", status.Channel, "id", status.ID, "type", status.Type, "reason", status.Reason)
+		}
+	}
+	if enabled == 0 {
+		logger.Warn("No social media adapters are enabled, posts can only be previewed or stored as drafts")
+	}
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/adapters"
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/service"
+	"github.com/BaronBonet/content-generator/internal/infrastructure"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// clearDefaultConfigEnv empties the environment variables of the default config, so the tests do not depend on the
+// environment they run in
+func clearDefaultConfigEnv(t *testing.T) {
+	for _, match := range regexp.MustCompile(`\$\{(?:secret:)?([A-Z_]+)`).FindAllStringSubmatch(string(defaultConfig), -1) {
+		t.Setenv(match[1], "")
+	}
+}
+
+func TestNewService_DefaultConfig(t *testing.T) {
+	clearDefaultConfigEnv(t)
+	for key, value := range map[string]string{
+		"NEW_YORK_TIMES_KEY":    "nytimes",
+		"OPENAI_KEY":            "openai",
+		"TWITTER_API_KEY":       "key",
+		"MASTODON_SERVER":       "https://mastodon.social",
+		"MASTODON_ACCESS_TOKEN": "token",
+		"GALLERY_DIRECTORY":     t.TempDir(),
+	} {
+		t.Setenv(key, value)
+	}
+
+	config, err := LoadConfig("")
+	require.NoError(t, err)
+	resolved, err := newSecretResolver(adapters.NewEnvSecretsAdapter(""), logger.NewTestLogger()).resolveConfig(context.Background(), config)
+	require.NoError(t, err)
+
+	_, statuses, err := newSocialMediaAdapters("socialMedia", resolved.SocialMedia, nil, logger.NewTestLogger())
+	require.NoError(t, err)
+	require.Equal(t, []AdapterStatus{
+		{ID: "instagram", Type: "instagram", Reason: "missing settings username, password"},
+		{ID: "twitter", Type: "twitter", Reason: "missing settings apiKeySecret, accessToken, accessTokenSecret"},
+		{ID: "mastodon", Type: "mastodon", Name: "Mastodon", Enabled: true},
+		{ID: "bluesky", Type: "bluesky", Reason: "missing settings identifier, appPassword"},
+		{ID: "threads", Type: "threads", Reason: "missing settings userId, accessToken"},
+		{ID: "discord", Type: "discord", Reason: "missing settings webhookUrl"},
+		{ID: "slack", Type: "slack", Reason: "missing settings webhookUrl"},
+		{ID: "telegram", Type: "telegram", Reason: "missing settings botToken, chatId"},
+		{ID: "gallery", Type: "gallery", Name: "Gallery", Enabled: true},
+	}, statuses)
+
+	_, err = NewService(context.Background(), config, logger.NewTestLogger())
+	require.NoError(t, err)
+}
+
+func TestNewSocialMediaAdapters(t *testing.T) {
+	configs := []infrastructure.AdapterConfig{
+		{Type: "discord", Enabled: true, Settings: map[string]string{"webhookUrl": "https://discord.com/api/webhooks/1/a"}},
+		{Type: "slack", Enabled: false, Settings: map[string]string{"webhookUrl": "https://hooks.slack.com/services/a"}},
+		{Type: "discord", ID: "discord-art", Enabled: true, Timeout: time.Minute, Settings: map[string]string{"webhookUrl": "https://discord.com/api/webhooks/2/b"}},
+	}
+
+	socialMediaAdapters, statuses, err := newSocialMediaAdapters("socialMedia", configs, nil, logger.NewTestLogger())
+	require.NoError(t, err)
+	require.Len(t, socialMediaAdapters, 2)
+	require.Equal(t, "Discord", socialMediaAdapters[0].GetName())
+	// Each instance keeps its own id and publish timeout
+	require.Equal(t, "discord", socialMediaAdapters[0].(service.ConfiguredAdapter).ID)
+	require.Equal(t, "discord-art", socialMediaAdapters[1].(service.ConfiguredAdapter).ID)
+	require.Equal(t, time.Minute, socialMediaAdapters[1].(service.ConfiguredAdapter).PublishTimeout)
+	require.Equal(t, []AdapterStatus{
+		{ID: "discord", Type: "discord", Name: "Discord", Enabled: true},
+		{ID: "slack", Type: "slack", Reason: "disabled in the configuration"},
+		{ID: "discord-art", Type: "discord", Name: "Discord", Enabled: true},
+	}, statuses)
+
+	_, _, err = newSocialMediaAdapters("socialMedia", []infrastructure.AdapterConfig{{Type: "myspace", Enabled: true}}, nil, logger.NewTestLogger())
+	require.EqualError(t, err, `socialMedia[0]: unknown adapter type "myspace"`)
+}
+
+func TestNewService_InvalidAdapters(t *testing.T) {
+	clearDefaultConfigEnv(t)
+	t.Setenv("OPENAI_KEY", "openai")
+
+	config, err := LoadConfig("")
+	require.NoError(t, err)
+	config.LLM.Type = "bard"
+
+	_, err = NewService(context.Background(), config, logger.NewTestLogger())
+	require.EqualError(t, err, "news: missing settings apiKey\n"+
+		"llm: unknown adapter type \"bard\"")
+}
+
+func TestNewService_Channels(t *testing.T) {
+	clearDefaultConfigEnv(t)
+	t.Setenv("NEW_YORK_TIMES_KEY", "nytimes")
+	t.Setenv("OPENAI_KEY", "openai")
+
+	config, err := LoadConfig("")
+	require.NoError(t, err)
+	config.Channels = []infrastructure.ChannelConfig{
+		{Name: "science", Topics: []string{"science"}, SocialMedia: []infrastructure.AdapterConfig{
+			{Type: "discord", Enabled: true, Settings: map[string]string{"webhookUrl": "https://discord.com/api/webhooks/1/a"}},
+		}},
+		{Name: "world", SocialMedia: []infrastructure.AdapterConfig{{Type: "myspace", Enabled: true}}},
+	}
+
+	_, err = NewService(context.Background(), config, logger.NewTestLogger())
+	require.EqualError(t, err, `channels[1].socialMedia[0]: unknown adapter type "myspace"`)
+
+	config.Channels = config.Channels[:1]
+	_, err = NewService(context.Background(), config, logger.NewTestLogger())
+	require.NoError(t, err)
+}
+
+func TestNewContentPolicy(t *testing.T) {
+	policy, err := newContentPolicy(infrastructure.ContentPolicyConfig{Enabled: true})
+	require.NoError(t, err)
+	require.Equal(t, domain.DefaultSensitiveCategories, policy.Categories())
+	require.Equal(t, domain.DefaultNeutralStyle, policy.NeutralStyle)
+
+	policy, err = newContentPolicy(infrastructure.ContentPolicyConfig{Enabled: true, Categories: []infrastructure.SensitiveCategoryConfig{
+		{Name: "elections", Keywords: []string{"ballot"}},
+		{Name: "disaster", Action: "neutral", Keywords: []string{"flood"}},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, []domain.SensitiveCategory{
+		{Name: "elections", Action: domain.PolicyActionSkip, Keywords: []string{"ballot"}},
+		{Name: "disaster", Action: domain.PolicyActionNeutral, Keywords: []string{"flood"}},
+	}, policy.Categories())
+
+	_, err = newContentPolicy(infrastructure.ContentPolicyConfig{Categories: []infrastructure.SensitiveCategoryConfig{{Name: "death", Action: "hide"}}})
+	require.EqualError(t, err, `categories[0]: invalid policy action "hide", must be one of "allow", "neutral" or "skip"`)
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/BaronBonet/content-generator/internal/adapters"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/content-generator/internal/core/service"
+	"github.com/BaronBonet/content-generator/internal/infrastructure"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/dghubble/oauth1"
+)
+
+// factory creates an adapter of one type from its settings
+type factory[T any] struct {
+	// requiredSettings have to be set, the other settings the adapter reads are optional
+	requiredSettings []string
+	create           func(settings map[string]string, repository ports.RepositoryAdapter, logger logger.Logger) T
+}
+
+// missingSettings returns the required settings that are not set or empty
+func (f factory[T]) missingSettings(settings map[string]string) []string {
+	var missing []string
+	for _, key := range f.requiredSettings {
+		if settings[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+var newsFactories = map[string]factory[ports.NewsAdapter]{
+	"nytimes": {
+		requiredSettings: []string{"apiKey"},
+		create: func(settings map[string]string, _ ports.RepositoryAdapter, _ logger.Logger) ports.NewsAdapter {
+			return adapters.NewNYTimesNewsAdapter(settings["apiKey"], http.DefaultClient)
+		},
+	},
+}
+
+var llmFactories = map[string]factory[ports.LLMAdapter]{
+	"chatgpt": {
+		requiredSettings: []string{"apiKey"},
+		create: func(settings map[string]string, _ ports.RepositoryAdapter, _ logger.Logger) ports.LLMAdapter {
+			return adapters.NewChatGPTAdapter(settings["apiKey"], http.DefaultClient)
+		},
+	},
+}
+
+var imageGenerationFactories = map[string]factory[ports.ImageGenerationAdapter]{
+	"dalle": {
+		requiredSettings: []string{"apiKey"},
+		create: func(settings map[string]string, _ ports.RepositoryAdapter, _ logger.Logger) ports.ImageGenerationAdapter {
+			return adapters.NewDalleImageGenerationAdapter(settings["apiKey"], http.DefaultClient)
+		},
+	},
+}
+
+var socialMediaFactories = map[string]factory[ports.SocialMediaAdapter]{
+	"twitter": {
+		requiredSettings: []string{"apiKey", "apiKeySecret", "accessToken", "accessTokenSecret"},
+		create: func(settings map[string]string, _ ports.RepositoryAdapter, logger logger.Logger) ports.SocialMediaAdapter {
+			config := oauth1.NewConfig(settings["apiKey"], settings["apiKeySecret"])
+			token := oauth1.NewToken(settings["accessToken"], settings["accessTokenSecret"])
+			return adapters.NewTwitterSocialMediaAdapter(config.Client(oauth1.NoContext, token), http.DefaultClient, logger)
+		},
+	},
+	"instagram": {
+		requiredSettings: []string{"username", "password"},
+		create: func(settings map[string]string, _ ports.RepositoryAdapter, logger logger.Logger) ports.SocialMediaAdapter {
+			return adapters.NewInstagramSocialMediaAdapter(logger, settings["username"], settings["password"])
+		},
+	},
+	"instagram-graph": {
+		requiredSettings: []string{"userId", "accessToken"},
+		create: func(settings map[string]string, repository ports.RepositoryAdapter, logger logger.Logger) ports.SocialMediaAdapter {
+			return adapters.NewInstagramGraphSocialMediaAdapter(metaGraphConfig(settings, repository), http.DefaultClient, logger)
+		},
+	},
+	"threads": {
+		requiredSettings: []string{"userId", "accessToken"},
+		create: func(settings map[string]string, repository ports.RepositoryAdapter, logger logger.Logger) ports.SocialMediaAdapter {
+			return adapters.NewThreadsSocialMediaAdapter(metaGraphConfig(settings, repository), http.DefaultClient, logger)
+		},
+	},
+	"mastodon": {
+		requiredSettings: []string{"server", "accessToken"},
+		create: func(settings map[string]string, _ ports.RepositoryAdapter, logger logger.Logger) ports.SocialMediaAdapter {
+			return adapters.NewMastodonSocialMediaAdapter(adapters.MastodonConfig{
+				Server:         settings["server"],
+				AccessToken:    settings["accessToken"],
+				Visibility:     settings["visibility"],
+				ContentWarning: settings["contentWarning"],
+			}, http.DefaultClient, logger)
+		},
+	},
+	"bluesky": {
+		requiredSettings: []string{"identifier", "appPassword"},
+		create: func(settings map[string]string, _ ports.RepositoryAdapter, logger logger.Logger) ports.SocialMediaAdapter {
+			return adapters.NewBlueskySocialMediaAdapter(adapters.BlueskyConfig{
+				Service:     settings["service"],
+				Identifier:  settings["identifier"],
+				AppPassword: settings["appPassword"],
+			}, http.DefaultClient, logger)
+		},
+	},
+	"discord": {
+		requiredSettings: []string{"webhookUrl"},
+		create: func(settings map[string]string, _ ports.RepositoryAdapter, logger logger.Logger) ports.SocialMediaAdapter {
+			return adapters.NewDiscordSocialMediaAdapter(settings["webhookUrl"], http.DefaultClient, logger)
+		},
+	},
+	"slack": {
+		requiredSettings: []string{"webhookUrl"},
+		create: func(settings map[string]string, _ ports.RepositoryAdapter, logger logger.Logger) ports.SocialMediaAdapter {
+			return adapters.NewSlackSocialMediaAdapter(settings["webhookUrl"], settings["publicImageBaseUrl"], http.DefaultClient, logger)
+		},
+	},
+	"telegram": {
+		requiredSettings: []string{"botToken", "chatId"},
+		create: func(settings map[string]string, _ ports.RepositoryAdapter, logger logger.Logger) ports.SocialMediaAdapter {
+			return adapters.NewTelegramSocialMediaAdapter(settings["botToken"], settings["chatId"], http.DefaultClient, logger)
+		},
+	},
+	"gallery": {
+		requiredSettings: []string{"directory"},
+		create: func(settings map[string]string, _ ports.RepositoryAdapter, logger logger.Logger) ports.SocialMediaAdapter {
+			return adapters.NewGallerySocialMediaAdapter(adapters.GalleryConfig{
+				Directory: settings["directory"],
+				BaseURL:   settings["baseUrl"],
+				Title:     settings["title"],
+			}, http.DefaultClient, logger)
+		},
+	},
+}
+
+func metaGraphConfig(settings map[string]string, repository ports.RepositoryAdapter) adapters.MetaGraphConfig {
+	return adapters.MetaGraphConfig{
+		UserID:             settings["userId"],
+		AccessToken:        settings["accessToken"],
+		Repository:         repository,
+		PublicImageBaseURL: settings["publicImageBaseUrl"],
+	}
+}
+
+// newAdapter creates an adapter the pipeline cannot run without, so missing settings are an error
+func newAdapter[T any](factories map[string]factory[T], config infrastructure.AdapterConfig, repository ports.RepositoryAdapter, logger logger.Logger) (T, error) {
+	var adapter T
+	f, exists := factories[config.Type]
+	if !exists {
+		return adapter, fmt.Errorf("unknown adapter type %q", config.Type)
+	}
+	if missing := f.missingSettings(config.Settings); len(missing) > 0 {
+		return adapter, fmt.Errorf("missing settings %s", strings.Join(missing, ", "))
+	}
+	return f.create(config.Settings, repository, logger), nil
+}
+
+// AdapterStatus reports whether a configured social media adapter is published to
+type AdapterStatus struct {
+	// Channel the adapter publishes to, empty for the social medias that are not part of a channel
+	Channel string
+	// ID identifies the adapter within its channel
+	ID   string
+	Type string
+	// Name of the adapter when it is enabled
+	Name    string
+	Enabled bool
+	// Reason the adapter is disabled
+	Reason string
+}
+
+// newSocialMediaAdapters creates the social media adapters that are enabled and have all their required settings,
+// the others are skipped so the pipeline runs with whichever social medias are configured. Unknown types are an error,
+// path is where the adapters are in the configuration.
+func newSocialMediaAdapters(path string, configs []infrastructure.AdapterConfig, repository ports.RepositoryAdapter, logger logger.Logger) ([]ports.SocialMediaAdapter, []AdapterStatus, error) {
+	var (
+		socialMediaAdapters []ports.SocialMediaAdapter
+		statuses            []AdapterStatus
+	)
+	for i, config := range configs {
+		f, exists := socialMediaFactories[config.Type]
+		if !exists {
+			return nil, nil, fmt.Errorf("%s[%d]: unknown adapter type %q", path, i, config.Type)
+		}
+		status := AdapterStatus{ID: config.AdapterID(), Type: config.Type}
+		if missing := f.missingSettings(config.Settings); !config.Enabled {
+			status.Reason = "disabled in the configuration"
+		} else if len(missing) > 0 {
+			status.Reason = "missing settings " + strings.Join(missing, ", ")
+		} else {
+			socialMediaAdapter := service.ConfiguredAdapter{
+				SocialMediaAdapter: f.create(config.Settings, repository, logger),
+				ID:                 config.AdapterID(),
+				PublishTimeout:     config.Timeout,
+			}
+			socialMediaAdapters = append(socialMediaAdapters, socialMediaAdapter)
+			status.Enabled = true
+			status.Name = socialMediaAdapter.GetName()
+		}
+		statuses = append(statuses, status)
+	}
+	return socialMediaAdapters, statuses, nil
+}
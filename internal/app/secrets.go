@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/BaronBonet/content-generator/internal/adapters"
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/content-generator/internal/infrastructure"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+// secretReferencePattern matches the references to secrets in settings, e.g. ${secret:OPENAI_KEY}
+var secretReferencePattern = regexp.MustCompile(`\$\{secret:([^}]+)\}`)
+
+// newSecretsAdapter creates the adapter the secrets are looked up with, environment variables are used by default
+func newSecretsAdapter(config infrastructure.AdapterConfig) (ports.SecretsAdapter, error) {
+	settings := config.Settings
+	switch config.Type {
+	case "", "env":
+		return adapters.NewEnvSecretsAdapter(settings["prefix"]), nil
+	case "dotenv":
+		path := settings["path"]
+		if path == "" {
+			path = ".env"
+		}
+		return adapters.NewDotenvSecretsAdapter(path)
+	case "file":
+		if settings["path"] == "" {
+			return nil, errors.New("missing settings path")
+		}
+		return adapters.NewFileSecretsAdapter(settings["path"]), nil
+	case "ssm", "secretsmanager":
+		if settings["region"] == "" {
+			return nil, errors.New("missing settings region")
+		}
+		awsConfig := adapters.AWSConfig{
+			Region:          settings["region"],
+			AccessKeyID:     settings["accessKeyId"],
+			SecretAccessKey: settings["secretAccessKey"],
+			SessionToken:    settings["sessionToken"],
+			Endpoint:        settings["endpoint"],
+		}
+		if config.Type == "ssm" {
+			return adapters.NewSSMSecretsAdapter(awsConfig, settings["prefix"], http.DefaultClient), nil
+		}
+		return adapters.NewSecretsManagerSecretsAdapter(awsConfig, settings["prefix"], http.DefaultClient), nil
+	}
+	return nil, fmt.Errorf("unknown adapter type %q", config.Type)
+}
+
+// secretResolver replaces the references to secrets in settings, every secret is only looked up once
+type secretResolver struct {
+	secrets ports.SecretsAdapter
+	logger  logger.Logger
+	cache   map[string]string
+}
+
+func newSecretResolver(secrets ports.SecretsAdapter, logger logger.Logger) *secretResolver {
+	return &secretResolver{secrets: secrets, logger: logger, cache: map[string]string{}}
+}
+
+// resolve returns a copy of the settings with the secrets they reference. Secrets that do not exist become empty, so
+// an adapter without its credentials is reported as missing settings.
+func (r *secretResolver) resolve(ctx context.Context, settings map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(settings))
+	var errs []error
+	for key, value := range settings {
+		resolved[key] = secretReferencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+			name := secretReferencePattern.FindStringSubmatch(reference)[1]
+			secret, err := r.get(ctx, name)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			return secret
+		})
+	}
+	return resolved, errors.Join(errs...)
+}
+
+func (r *secretResolver) get(ctx context.Context, name string) (string, error) {
+	if secret, exists := r.cache[name]; exists {
+		return secret, nil
+	}
+	secret, err := r.secrets.GetSecret(ctx, name)
+	if errors.Is(err, domain.ErrNotFound) {
+		r.logger.Debug("Secret not found", "name", name)
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	r.cache[name] = secret
+	return secret, nil
+}
+
+// resolveConfig returns the configuration with the secrets referenced by the settings of the adapters, the secrets of
+// disabled social media adapters are not looked up
+func (r *secretResolver) resolveConfig(ctx context.Context, config infrastructure.Config) (infrastructure.Config, error) {
+	var errs []error
+	resolveAdapter := func(name string, adapter *infrastructure.AdapterConfig) {
+		settings, err := r.resolve(ctx, adapter.Settings)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		adapter.Settings = settings
+	}
+	resolveAdapter("news", &config.News)
+	resolveAdapter("llm", &config.LLM)
+	resolveAdapter("imageGenerator", &config.ImageGenerator)
+	resolveSocialMedia := func(path string, adapters []infrastructure.AdapterConfig) []infrastructure.AdapterConfig {
+		adapters = append([]infrastructure.AdapterConfig(nil), adapters...)
+		for i := range adapters {
+			if adapters[i].Enabled {
+				resolveAdapter(fmt.Sprintf("%s[%d]", path, i), &adapters[i])
+			}
+		}
+		return adapters
+	}
+	config.SocialMedia = resolveSocialMedia("socialMedia", config.SocialMedia)
+	config.Channels = append([]infrastructure.ChannelConfig(nil), config.Channels...)
+	for i := range config.Channels {
+		config.Channels[i].SocialMedia = resolveSocialMedia(fmt.Sprintf("channels[%d].socialMedia", i), config.Channels[i].SocialMedia)
+	}
+	return config, errors.Join(errs...)
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/infrastructure"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretResolver_Resolve(t *testing.T) {
+	directory := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "OPENAI_KEY"), []byte("sk-123\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "WEBHOOK_TOKEN"), []byte("token"), 0600))
+
+	secretsAdapter, err := newSecretsAdapter(infrastructure.AdapterConfig{Type: "file", Settings: map[string]string{"path": directory}})
+	require.NoError(t, err)
+	resolver := newSecretResolver(secretsAdapter, logger.NewTestLogger())
+
+	settings, err := resolver.resolve(context.Background(), map[string]string{
+		"apiKey":     "${secret:OPENAI_KEY}",
+		"webhookUrl": "https://hooks.example.com/${secret:WEBHOOK_TOKEN}",
+		"password":   "${secret:PASSWORD_THAT_DOES_NOT_EXIST}",
+		"server":     "https://mastodon.social",
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"apiKey":     "sk-123",
+		"webhookUrl": "https://hooks.example.com/token",
+		"password":   "",
+		"server":     "https://mastodon.social",
+	}, settings)
+
+	_, err = resolver.resolve(context.Background(), map[string]string{"apiKey": "${secret:../OPENAI_KEY}"})
+	require.EqualError(t, err, `invalid secret name "../OPENAI_KEY"`)
+}
+
+func TestNewSecretsAdapter_Invalid(t *testing.T) {
+	_, err := newSecretsAdapter(infrastructure.AdapterConfig{Type: "ssm"})
+	require.EqualError(t, err, "missing settings region")
+
+	_, err = newSecretsAdapter(infrastructure.AdapterConfig{Type: "vault"})
+	require.EqualError(t, err, `unknown adapter type "vault"`)
+}
@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PolicyAction is what happens with an article about a sensitive category
+type PolicyAction string
+
+const (
+	// PolicyActionAllow publishes the article like any other
+	PolicyActionAllow PolicyAction = "allow"
+	// PolicyActionNeutral generates the images in the neutral style of the content policy instead of the usual style
+	PolicyActionNeutral PolicyAction = "neutral"
+	// PolicyActionSkip does not generate or publish anything for the article
+	PolicyActionSkip PolicyAction = "skip"
+)
+
+// ParsePolicyAction parses a policy action, an empty string is PolicyActionSkip
+func ParsePolicyAction(s string) (PolicyAction, error) {
+	switch action := PolicyAction(s); action {
+	case "":
+		return PolicyActionSkip, nil
+	case PolicyActionAllow, PolicyActionNeutral, PolicyActionSkip:
+		return action, nil
+	default:
+		return "", fmt.Errorf("invalid policy action %q, must be one of %q, %q or %q", s, PolicyActionAllow, PolicyActionNeutral, PolicyActionSkip)
+	}
+}
+
+// DefaultNeutralStyle is the style of the images of articles with the neutral action when the policy does not set one
+const DefaultNeutralStyle = "a calm and respectful editorial illustration, without people, violence or humor"
+
+// SensitiveCategory is a kind of news that should not be turned into whimsical art, e.g. disasters
+type SensitiveCategory struct {
+	Name   string
+	Action PolicyAction
+	// Keywords are matched as whole words or phrases in the title and body of the article, ignoring case
+	Keywords []string
+}
+
+// DefaultSensitiveCategories are used when the content policy does not configure its own categories
+var DefaultSensitiveCategories = []SensitiveCategory{
+	{
+		Name:   "death",
+		Action: PolicyActionSkip,
+		Keywords: []string{"dead", "death", "deaths", "died", "dies", "killed", "killing", "killings", "funeral",
+			"obituary", "suicide", "death toll", "fatal", "fatalities"},
+	},
+	{
+		Name:   "violence",
+		Action: PolicyActionSkip,
+		Keywords: []string{"shooting", "shootings", "gunman", "massacre", "murder", "murdered", "stabbing", "terror",
+			"terrorist", "bombing", "airstrike", "airstrikes", "hostage", "hostages", "assault", "genocide", "war crimes"},
+	},
+	{
+		Name:   "disaster",
+		Action: PolicyActionNeutral,
+		Keywords: []string{"earthquake", "tsunami", "hurricane", "typhoon", "tornado", "wildfire", "wildfires", "flood",
+			"flooding", "landslide", "famine", "plane crash", "derailment", "explosion", "collapse"},
+	},
+}
+
+// ContentPolicy decides how articles about sensitive categories, like disasters and deaths, are published. It is
+// created with NewContentPolicy, the categories cannot change afterwards since their keywords are compiled once.
+type ContentPolicy struct {
+	categories []SensitiveCategory
+	// UseLLM asks the LLM whether the article is about one of the categories when none of their keywords match
+	UseLLM bool
+	// NeutralStyle replaces the style of the images of articles with the neutral action
+	NeutralStyle string
+	// keywordPatterns are the compiled keywords of each category
+	keywordPatterns [][]*regexp.Regexp
+}
+
+// NewContentPolicy creates a content policy with the categories, compiling their keywords once. An empty neutral
+// style is DefaultNeutralStyle.
+func NewContentPolicy(categories []SensitiveCategory, useLLM bool, neutralStyle string) ContentPolicy {
+	if neutralStyle == "" {
+		neutralStyle = DefaultNeutralStyle
+	}
+	return ContentPolicy{
+		categories:      categories,
+		UseLLM:          useLLM,
+		NeutralStyle:    neutralStyle,
+		keywordPatterns: compileKeywords(categories),
+	}
+}
+
+// Categories returns the sensitive categories of the policy
+func (p ContentPolicy) Categories() []SensitiveCategory {
+	return append([]SensitiveCategory(nil), p.categories...)
+}
+
+// MatchKeywords returns the first category with a keyword in the title or body of the article, and the keyword
+func (p ContentPolicy) MatchKeywords(article NewsArticle) (SensitiveCategory, string, bool) {
+	text := article.Title + "\n" + article.Body
+	for i, category := range p.categories {
+		for j, keyword := range category.Keywords {
+			if p.keywordPatterns[i][j].MatchString(text) {
+				return category, keyword, true
+			}
+		}
+	}
+	return SensitiveCategory{}, "", false
+}
+
+// compileKeywords compiles the keywords of the categories to match them as whole words or phrases, ignoring case
+func compileKeywords(categories []SensitiveCategory) [][]*regexp.Regexp {
+	patterns := make([][]*regexp.Regexp, len(categories))
+	for i, category := range categories {
+		for _, keyword := range category.Keywords {
+			pattern := `(?i)\b` + strings.ReplaceAll(regexp.QuoteMeta(keyword), " ", `\s+`) + `\b`
+			patterns[i] = append(patterns[i], regexp.MustCompile(pattern))
+		}
+	}
+	return patterns
+}
+
+// PolicyDecision is the outcome of applying the content policy to an article
+type PolicyDecision struct {
+	Action PolicyAction
+	// Category the article is about, empty when it is not about any of them
+	Category string
+	// Reason explains how the category was found, e.g. the keyword that matched
+	Reason string
+}
@@ -1,6 +1,14 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxImagesPerPost is the most images a single post can contain, Twitter allows at most four media attachments.
+const MaxImagesPerPost = 4
 
 type NewsArticle struct {
 	Title  string
@@ -8,6 +16,8 @@ type NewsArticle struct {
 	Date   Date
 	Url    string
 	Source string
+	// Section is the part of the news the article was published in, e.g. "science" or "world"
+	Section string
 }
 
 type Date struct {
@@ -16,4 +26,371 @@ type Date struct {
 	Year  int
 }
 
+// NewDate returns the date of the time
+func NewDate(t time.Time) Date {
+	return Date{Day: t.Day(), Month: t.Month(), Year: t.Year()}
+}
+
+// ParseArticleText builds an article from plain text, e.g. a file a user wrote. The first non-empty line is the title,
+// a leading markdown heading marker is removed from it, and the remaining paragraphs are the body.
+func ParseArticleText(text string) (NewsArticle, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return NewsArticle{}, errors.New("the article text is empty")
+	}
+	title := strings.TrimSpace(strings.TrimLeft(lines[0], "# "))
+	if title == "" {
+		return NewsArticle{}, errors.New("the article text has no title")
+	}
+
+	var paragraphs []string
+	var paragraph []string
+	for _, line := range append(lines[1:], "") {
+		if line = strings.TrimSpace(line); line != "" {
+			paragraph = append(paragraph, line)
+			continue
+		}
+		if len(paragraph) > 0 {
+			paragraphs = append(paragraphs, strings.Join(paragraph, " "))
+			paragraph = nil
+		}
+	}
+	return NewsArticle{Title: title, Body: strings.Join(paragraphs, "\n\n")}, nil
+}
+
+// Channel is a named group of social media accounts, e.g. a science account on several social medias. Each channel
+// has its own style and only publishes news about its topics.
+type Channel struct {
+	Name string
+	// PromptStyle is the style the images of the channel are generated in, e.g. "cyberpunk"
+	PromptStyle string
+	// Topics the channel publishes about, the channel publishes the main article when empty
+	Topics []string
+}
+
+// Covers returns true when the channel publishes about articles of the topic
+func (c Channel) Covers(topic string) bool {
+	if len(c.Topics) == 0 {
+		return true
+	}
+	for _, channelTopic := range c.Topics {
+		if strings.EqualFold(channelTopic, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// TopicClassifier decides how the topic of an article is determined
+type TopicClassifier string
+
+const (
+	// TopicClassifierSection uses the topic with the section of the article, or the section itself when no topic has it
+	TopicClassifierSection TopicClassifier = "section"
+	// TopicClassifierLLM asks the LLM which of the topics the article is about
+	TopicClassifierLLM TopicClassifier = "llm"
+)
+
+// ParseTopicClassifier parses a topic classifier, an empty string is TopicClassifierSection
+func ParseTopicClassifier(s string) (TopicClassifier, error) {
+	switch classifier := TopicClassifier(s); classifier {
+	case "":
+		return TopicClassifierSection, nil
+	case TopicClassifierSection, TopicClassifierLLM:
+		return classifier, nil
+	default:
+		return "", fmt.Errorf("invalid topic classifier %q, must be one of %q or %q", s, TopicClassifierSection, TopicClassifierLLM)
+	}
+}
+
+// Topic groups the articles that are published the same way, e.g. technology articles in a cyberpunk style
+type Topic struct {
+	Name string
+	// Sections of the news that are about the topic, used by the section classifier
+	Sections []string
+	// PromptStyle is the style the images of articles about the topic are generated in, it overrides the style of
+	// the channel
+	PromptStyle string
+	// PromptTemplate replaces the request for the image prompt sent to the LLM, it is a text/template executed with
+	// the NewsArticle
+	PromptTemplate string
+}
+
+// HasSection returns true when articles of the section are about the topic
+func (t Topic) HasSection(section string) bool {
+	if len(t.Sections) == 0 {
+		return strings.EqualFold(t.Name, section)
+	}
+	for _, topicSection := range t.Sections {
+		if strings.EqualFold(topicSection, section) {
+			return true
+		}
+	}
+	return false
+}
+
 type ImagePath string
+
+// GeneratedImage is an image together with the prompt that was used to generate it
+type GeneratedImage struct {
+	Path   ImagePath
+	Prompt string
+	// URL is where a third party, e.g. a social media that downloads the image itself, can get a stored image. Empty
+	// when the image is only available at its path.
+	URL string
+}
+
+// Post is what gets published to social media, one or more generated images about a news article
+type Post struct {
+	NewsArticle        NewsArticle
+	Images             []GeneratedImage
+	ImageGeneratorName string
+}
+
+// PostPreview is what a social media adapter would publish for a post
+type PostPreview struct {
+	AdapterName string
+	// Channel and AdapterID identify the adapter, the name is the same for every account of a social media. They are
+	// set by the service.
+	Channel   string
+	AdapterID string
+	// Text is the main text of the post, e.g. the tweet or the instagram caption
+	Text string
+	// Replies are posted as replies to the main post, in order
+	Replies []string
+	Images  []GeneratedImage
+}
+
+// PublishedPost identifies a post on a social media service
+type PublishedPost struct {
+	ID  string
+	URL string
+}
+
+type PublishStatus string
+
+const (
+	PublishStatusPublished PublishStatus = "published"
+	PublishStatusFailed    PublishStatus = "failed"
+	// PublishStatusTimedOut means the adapter did not finish before its deadline, the post may still have been published
+	PublishStatusTimedOut PublishStatus = "timed_out"
+	// PublishStatusCancelled means the run was cancelled before the adapter finished
+	PublishStatusCancelled PublishStatus = "cancelled"
+)
+
+// PublishResult is the outcome of publishing a post with one social media adapter
+type PublishResult struct {
+	AdapterName string
+	// AdapterID identifies the adapter within the channel, the name is the same for every account of a social media
+	AdapterID string
+	Status    PublishStatus
+	PostID    string
+	URL       string
+	// Error is the reason publishing failed, it is a string so the result can be serialized
+	Error string
+}
+
+// Retryable returns true when publishing failed before anything was posted, so publishing again can not create a
+// duplicate post. Adapters that fail halfway still return the post they published, and an adapter that timed out or
+// was cancelled may have published the post after all.
+func (r PublishResult) Retryable() bool {
+	return r.Status == PublishStatusFailed && r.PostID == ""
+}
+
+// RunResult describes what a content generation run created, and where it was published
+type RunResult struct {
+	ID string
+	// Channel is the name of the channel the post was created for
+	Channel string
+	Post    Post
+	// DraftID is set when the post was stored as a draft waiting for approval instead of being published
+	DraftID string
+	// Previews are set for dry runs, which render the posts instead of publishing them
+	Previews     []PostPreview
+	Publications []PublishResult
+	// ContentPolicy is the decision of the content policy about the article, empty when there is no content policy
+	ContentPolicy PolicyDecision
+	// Resumable is set when the run did not finish and its checkpoint was saved, so it can be resumed by its id
+	Resumable bool
+}
+
+// FailurePolicy decides when failing to publish to social media makes a run fail
+type FailurePolicy string
+
+const (
+	// FailurePolicyIgnore never fails the run because of social media, retrying would create duplicate posts
+	FailurePolicyIgnore FailurePolicy = "ignore"
+	// FailurePolicyAny fails the run when publishing failed for any of the social medias
+	FailurePolicyAny FailurePolicy = "any"
+	// FailurePolicyAll fails the run only when publishing failed for all social medias
+	FailurePolicyAll FailurePolicy = "all"
+)
+
+// ParseFailurePolicy parses a failure policy, an empty string is FailurePolicyIgnore
+func ParseFailurePolicy(s string) (FailurePolicy, error) {
+	switch policy := FailurePolicy(s); policy {
+	case "":
+		return FailurePolicyIgnore, nil
+	case FailurePolicyIgnore, FailurePolicyAny, FailurePolicyAll:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid failure policy %q, must be one of %q, %q or %q", s, FailurePolicyIgnore, FailurePolicyAny, FailurePolicyAll)
+	}
+}
+
+// Err returns an error listing the failed publications when the run failed according to the policy
+func (r RunResult) Err(policy FailurePolicy) error {
+	var failures []string
+	for _, publication := range r.Publications {
+		if publication.Status != PublishStatusPublished {
+			failures = append(failures, fmt.Sprintf("%s: %s", publication.AdapterName, publication.Error))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	switch policy {
+	case FailurePolicyAny:
+	case FailurePolicyAll:
+		if len(failures) < len(r.Publications) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return fmt.Errorf("publishing failed for %d of %d social medias: %s", len(failures), len(r.Publications), strings.Join(failures, "; "))
+}
+
+type RunStatus string
+
+const (
+	RunStatusInProgress RunStatus = "in_progress"
+	RunStatusCompleted  RunStatus = "completed"
+)
+
+// Run is the checkpoint of a content generation run, it is saved after every stage so that a run which was
+// interrupted can be resumed without repeating the stages that already succeeded.
+type Run struct {
+	ID      string
+	Status  RunStatus
+	Channel string
+	// Topic the article was classified as, empty when it has none
+	Topic   string
+	Options GenerateOptions
+	// ArticleFetched is set once Article contains the news article
+	ArticleFetched bool
+	Article        NewsArticle
+	// ContentPolicy is set once the content policy decided about the article
+	ContentPolicy PolicyDecision
+	// ArticleEnriched is set once the body of Article is replaced with the story, when articles are enriched
+	ArticleEnriched bool
+	// Images has an entry per image to generate, the prompt is empty until it is created and the path is empty
+	// until the image is generated
+	Images             []GeneratedImage
+	ImagesStored       bool
+	ImageGeneratorName string
+	DraftID            string
+	// Publications contains the latest result for each social media the run tried to publish to
+	Publications []PublishResult
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// IsPublished returns true when the run published, or may have published, to the social media, so publishing again
+// could create a duplicate post
+func (r Run) IsPublished(adapterID string) bool {
+	return isPublished(r.Publications, adapterID)
+}
+
+func isPublished(publications []PublishResult, adapterID string) bool {
+	for _, publication := range publications {
+		if publication.AdapterID == adapterID && !publication.Retryable() {
+			return true
+		}
+	}
+	return false
+}
+
+// Post returns the post of the run, only complete once all images are generated
+func (r Run) Post() Post {
+	return Post{
+		NewsArticle:        r.Article,
+		Images:             r.Images,
+		ImageGeneratorName: r.ImageGeneratorName,
+	}
+}
+
+type DraftStatus string
+
+const (
+	DraftStatusPending   DraftStatus = "pending"
+	DraftStatusPublished DraftStatus = "published"
+	DraftStatusRejected  DraftStatus = "rejected"
+)
+
+// Draft is a post waiting for a human to approve or reject it before it is published
+type Draft struct {
+	ID     string
+	Status DraftStatus
+	// Channel is the name of the channel the draft is published to
+	Channel string
+	Post    Post
+	// Previews are what each social media adapter will publish once the draft is approved
+	Previews []PostPreview
+	// Publications contains the latest result for each social media approving the draft tried to publish to, the
+	// draft stays pending while one of them can be retried
+	Publications []PublishResult
+	CreatedAt    time.Time
+}
+
+// IsPublished returns true when approving the draft published, or may have published, to the social media, so
+// publishing again could create a duplicate post
+func (d Draft) IsPublished(adapterID string) bool {
+	return isPublished(d.Publications, adapterID)
+}
+
+// AccessToken is a credential of a social media that expires unless it is refreshed while it is in use
+type AccessToken struct {
+	Value string
+	// Origin identifies the configured token the token was refreshed from without containing it, so a stored token is
+	// replaced when another token is configured
+	Origin      string
+	RefreshedAt time.Time
+	ExpiresAt   time.Time
+}
+
+// GenerateOptions changes how content is generated for a news article
+type GenerateOptions struct {
+	// ImageCount is the number of images to generate, each depicting a different part of the story. Defaults to one.
+	ImageCount int
+	// ImageStyles generates one image per style e.g. "watercolor painting", takes precedence over ImageCount.
+	ImageStyles []string
+	// DryRun renders what would be published instead of publishing to social media
+	DryRun bool
+	// RequireApproval stores the post as a pending draft, which is only published once it is approved
+	RequireApproval bool
+	// Resume continues the most recent incomplete run instead of starting a new one, if there is one
+	Resume bool
+	// Channels only generates content for the channels with the names, instead of for every channel
+	Channels []string
+	// ArticleURL generates content for the article at the url instead of the news of the moment, every channel
+	// publishes it whatever its topics
+	ArticleURL string
+	// Prompt is used as the prompt of the images instead of asking the LLM for one, the styles are ignored
+	Prompt string
+}
+
+// NumberOfImages returns how many images should be generated for the options
+func (o GenerateOptions) NumberOfImages() int {
+	if len(o.ImageStyles) > 0 {
+		return len(o.ImageStyles)
+	}
+	if o.ImageCount < 1 {
+		return 1
+	}
+	return o.ImageCount
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunResult_Err(t *testing.T) {
+	published := PublishResult{AdapterName: "Twitter", Status: PublishStatusPublished}
+	failed := PublishResult{AdapterName: "Instagram", Status: PublishStatusFailed, Error: "login failed"}
+
+	testCases := []struct {
+		name          string
+		publications  []PublishResult
+		policy        FailurePolicy
+		expectedError string
+	}{
+		{name: "Ignore partial failure", publications: []PublishResult{published, failed}, policy: FailurePolicyIgnore},
+		{name: "Ignore complete failure", publications: []PublishResult{failed}, policy: FailurePolicyIgnore},
+		{
+			name:          "Any with partial failure",
+			publications:  []PublishResult{published, failed},
+			policy:        FailurePolicyAny,
+			expectedError: "publishing failed for 1 of 2 social medias: Instagram: login failed",
+		},
+		{name: "Any without failures", publications: []PublishResult{published}, policy: FailurePolicyAny},
+		{name: "All with partial failure", publications: []PublishResult{published, failed}, policy: FailurePolicyAll},
+		{
+			name:          "All with complete failure",
+			publications:  []PublishResult{failed},
+			policy:        FailurePolicyAll,
+			expectedError: "publishing failed for 1 of 1 social medias: Instagram: login failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := RunResult{Publications: tc.publications}.Err(tc.policy)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestPublishResult_Retryable(t *testing.T) {
+	assert.True(t, PublishResult{Status: PublishStatusFailed, Error: "outage"}.Retryable())
+	// Failing halfway still published the post
+	assert.False(t, PublishResult{Status: PublishStatusFailed, PostID: "1", Error: "reply failed"}.Retryable())
+	assert.False(t, PublishResult{Status: PublishStatusTimedOut}.Retryable())
+	assert.False(t, PublishResult{Status: PublishStatusCancelled}.Retryable())
+	assert.False(t, PublishResult{Status: PublishStatusPublished, PostID: "1"}.Retryable())
+}
+
+func TestTopic_HasSection(t *testing.T) {
+	technology := Topic{Name: "technology", Sections: []string{"technology", "Business"}}
+	assert.True(t, technology.HasSection("business"))
+	assert.False(t, technology.HasSection("sports"))
+
+	// Without sections the name of the topic is its section
+	assert.True(t, Topic{Name: "Sports"}.HasSection("sports"))
+	assert.False(t, Topic{Name: "Sports"}.HasSection("world"))
+}
+
+func TestParseArticleText(t *testing.T) {
+	article, err := ParseArticleText("\n# Bees return to the valley\r\n\nThe bees are back,\nyears later.\n\n\nFarmers are happy.\n")
+	assert.NoError(t, err)
+	assert.Equal(t, NewsArticle{Title: "Bees return to the valley", Body: "The bees are back, years later.\n\nFarmers are happy."}, article)
+
+	_, err = ParseArticleText(" \n\n")
+	assert.EqualError(t, err, "the article text is empty")
+	_, err = ParseArticleText("#\nbody")
+	assert.EqualError(t, err, "the article text has no title")
+}
+
+func TestContentPolicy_MatchKeywords(t *testing.T) {
+	policy := NewContentPolicy(DefaultSensitiveCategories, false, "")
+	assert.Equal(t, DefaultNeutralStyle, policy.NeutralStyle)
+
+	category, keyword, matched := policy.MatchKeywords(NewsArticle{Title: "Strong Earthquake Hits Coast"})
+	assert.True(t, matched)
+	assert.Equal(t, "disaster", category.Name)
+	assert.Equal(t, "earthquake", keyword)
+
+	category, keyword, matched = policy.MatchKeywords(NewsArticle{Title: "Update", Body: "The death\ntoll is rising"})
+	assert.True(t, matched)
+	assert.Equal(t, "death", category.Name)
+	assert.Equal(t, "death", keyword)
+
+	// Keywords only match whole words
+	_, _, matched = policy.MatchKeywords(NewsArticle{Title: "Deadline for warm floodlights extended"})
+	assert.False(t, matched)
+
+	// Changing the returned categories does not change the policy
+	categories := policy.Categories()
+	categories[0].Keywords = append(categories[0].Keywords, "deadline")
+	_, _, matched = policy.MatchKeywords(NewsArticle{Title: "Rain expected before the deadline"})
+	assert.False(t, matched)
+
+	// The zero policy has no categories
+	_, _, matched = ContentPolicy{}.MatchKeywords(NewsArticle{Title: "Flooding in the north"})
+	assert.False(t, matched)
+}
@@ -0,0 +1,9 @@
+package domain
+
+import "errors"
+
+// ErrNotFound is returned when the requested entity does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalidOptions is returned when content cannot be generated with the requested options, e.g. too many images
+var ErrInvalidOptions = errors.New("invalid options")
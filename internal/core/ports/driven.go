@@ -12,6 +12,16 @@ import (
 type NewsAdapter interface {
 	// GetMainArticle finds the main article, the concept of the main article will be adapter specific.
 	GetMainArticle(ctx context.Context) (domain.NewsArticle, error)
+	// GetTopArticles returns the most important articles of the moment, ordered by importance
+	GetTopArticles(ctx context.Context) ([]domain.NewsArticle, error)
+}
+
+// ArticleAdapter reads articles from anywhere on the web, not only from the news service
+//
+//go:generate mockery --name=ArticleAdapter
+type ArticleAdapter interface {
+	// GetArticle reads the article at the url, e.g. an article a run was asked to use instead of the main article
+	GetArticle(ctx context.Context, url string) (domain.NewsArticle, error)
 }
 
 // LLMAdapter is responsible for connecting to large language models like ChatGPT
@@ -36,11 +46,51 @@ type ImageGenerationAdapter interface {
 //
 //go:generate mockery --name=SocialMediaAdapter
 type SocialMediaAdapter interface {
-	// PublishImagePost publishes an image post to a social media service
-	PublishImagePost(ctx context.Context, image domain.ImagePath, prompt string, imageGeneratorName string, newsArticle domain.NewsArticle) error
+	// PublishImagePost publishes a post with one or more images to a social media service
+	PublishImagePost(ctx context.Context, post domain.Post) (domain.PublishedPost, error)
+	// PreviewImagePost returns what PublishImagePost would publish, without calling the social media service
+	PreviewImagePost(post domain.Post) domain.PostPreview
 	GetName() string
 }
 
+// PreviewAdapter renders the posts of a dry run, instead of them being published to social media
+//
+//go:generate mockery --name=PreviewAdapter
+type PreviewAdapter interface {
+	// RenderPreview renders what a social media adapter would have published
+	RenderPreview(ctx context.Context, preview domain.PostPreview) error
+}
+
+// RepositoryAdapter persists the content created by the service
+//
+//go:generate mockery --name=RepositoryAdapter
 type RepositoryAdapter interface {
-	SavePost(ctx context.Context, image domain.ImagePath, prompt string, newsArticle domain.NewsArticle) error
+	SavePost(ctx context.Context, post domain.Post) error
+	// StoreImage copies an image into the repository and returns it with its new path, generated image urls expire after
+	// a while. The url of the returned image is set when the stored copy, or the original, can be downloaded publicly.
+	StoreImage(ctx context.Context, image domain.GeneratedImage) (domain.GeneratedImage, error)
+	// SaveDraft creates or updates a draft
+	SaveDraft(ctx context.Context, draft domain.Draft) error
+	// GetDraft returns the draft with the id, wrapping domain.ErrNotFound if it does not exist
+	GetDraft(ctx context.Context, id string) (domain.Draft, error)
+	// ListDrafts returns the drafts with the status, oldest first
+	ListDrafts(ctx context.Context, status domain.DraftStatus) ([]domain.Draft, error)
+	// SaveRun creates or updates the checkpoint of a run
+	SaveRun(ctx context.Context, run domain.Run) error
+	// GetRun returns the run with the id, wrapping domain.ErrNotFound if it does not exist
+	GetRun(ctx context.Context, id string) (domain.Run, error)
+	// ListRuns returns the runs with the status, oldest first
+	ListRuns(ctx context.Context, status domain.RunStatus) ([]domain.Run, error)
+	// SaveAccessToken creates or updates the access token with the name, e.g. one a social media adapter refreshed
+	SaveAccessToken(ctx context.Context, name string, token domain.AccessToken) error
+	// GetAccessToken returns the access token with the name, wrapping domain.ErrNotFound if it does not exist
+	GetAccessToken(ctx context.Context, name string) (domain.AccessToken, error)
+}
+
+// SecretsAdapter looks up credentials like api keys and passwords, so they don't have to be stored in the configuration
+//
+//go:generate mockery --name=SecretsAdapter
+type SecretsAdapter interface {
+	// GetSecret returns the value of the secret with the name, wrapping domain.ErrNotFound if it does not exist
+	GetSecret(ctx context.Context, name string) (string, error)
 }
@@ -5,8 +5,25 @@ import (
 	"github.com/BaronBonet/content-generator/internal/core/domain"
 )
 
+//go:generate mockery --name=Service
 type Service interface {
-	GenerateNewsContent(ctx context.Context) error
+	// GenerateNewsContent generates content for the news article of each channel, returning a result per channel.
+	// Errors while publishing to social media are reported in the results instead of being returned, use RunResult.Err
+	// to decide if they should fail the run.
+	GenerateNewsContent(ctx context.Context, opts domain.GenerateOptions) ([]domain.RunResult, error)
+	// GenerateArticleContent generates content for the given article instead of the news, e.g. an article a user wrote.
+	// Every channel publishes it whatever its topics.
+	GenerateArticleContent(ctx context.Context, article domain.NewsArticle, opts domain.GenerateOptions) ([]domain.RunResult, error)
+	// ResumeRun continues an incomplete run, only performing the stages that did not succeed yet
+	ResumeRun(ctx context.Context, id string) (domain.RunResult, error)
+	// GetRun returns the run with the id, wrapping domain.ErrNotFound if it does not exist
+	GetRun(ctx context.Context, id string) (domain.Run, error)
 	CreatePrompt(ctx context.Context, prompt string) (string, error)
 	GenerateImage(ctx context.Context, prompt string) (domain.ImagePath, error)
+	// ListDrafts returns the drafts waiting for approval
+	ListDrafts(ctx context.Context) ([]domain.Draft, error)
+	// ApproveDraft publishes a pending draft to social media
+	ApproveDraft(ctx context.Context, id string) (domain.RunResult, error)
+	// RejectDraft discards a pending draft without publishing it
+	RejectDraft(ctx context.Context, id string) error
 }
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+)
+
+// articleCache fetches, classifies and checks the news once for all the channels of a run
+type articleCache struct {
+	newsAdapter    ports.NewsAdapter
+	articleAdapter ports.ArticleAdapter
+	classify       func(ctx context.Context, article domain.NewsArticle) (string, error)
+	checkPolicy    func(ctx context.Context, article domain.NewsArticle) (domain.PolicyDecision, error)
+	enrich         func(ctx context.Context, article domain.NewsArticle, readStory bool) domain.NewsArticle
+	main           *domain.NewsArticle
+	// requested is the article of the article url of the run
+	requested  *domain.NewsArticle
+	top        []domain.NewsArticle
+	topFetched bool
+	// topics, content policy decisions and enriched versions of the articles, by their url and title
+	topics      map[string]string
+	decisions   map[string]domain.PolicyDecision
+	enrichments map[string]domain.NewsArticle
+}
+
+func newArticleCache(srv *service) *articleCache {
+	return &articleCache{
+		newsAdapter:    srv.newsAdapter,
+		articleAdapter: srv.articleAdapter,
+		classify:       srv.classify,
+		checkPolicy:    srv.checkContentPolicy,
+		enrich:         srv.enrichArticle,
+		topics:         map[string]string{},
+		decisions:      map[string]domain.PolicyDecision{},
+		enrichments:    map[string]domain.NewsArticle{},
+	}
+}
+
+// articleFor returns the article the run was started with, or the article at the article url of the run when it is
+// set. Otherwise it returns the main article for channels without topics, or the most important of the top articles
+// about one of the topics of the channel. The topic of the article is returned with it, errNoArticle is returned when
+// none of the top articles is about the channel's topics.
+func (c *articleCache) articleFor(ctx context.Context, ch domain.Channel, run *domain.Run) (domain.NewsArticle, string, error) {
+	if run.Article.Title != "" {
+		topic, err := c.topicOf(ctx, run.Article)
+		return run.Article, topic, err
+	}
+
+	if articleURL := run.Options.ArticleURL; articleURL != "" {
+		if c.requested == nil {
+			article, err := c.articleAdapter.GetArticle(ctx, articleURL)
+			if err != nil {
+				return domain.NewsArticle{}, "", err
+			}
+			c.requested = &article
+		}
+		topic, err := c.topicOf(ctx, *c.requested)
+		return *c.requested, topic, err
+	}
+
+	if len(ch.Topics) == 0 {
+		if c.main == nil {
+			article, err := c.newsAdapter.GetMainArticle(ctx)
+			if err != nil {
+				return domain.NewsArticle{}, "", err
+			}
+			c.main = &article
+		}
+		topic, err := c.topicOf(ctx, *c.main)
+		return *c.main, topic, err
+	}
+
+	if !c.topFetched {
+		articles, err := c.newsAdapter.GetTopArticles(ctx)
+		if err != nil {
+			return domain.NewsArticle{}, "", err
+		}
+		c.top, c.topFetched = articles, true
+	}
+	// Articles are classified in order of importance until one matches, so the LLM is not asked about all of them
+	for _, article := range c.top {
+		topic, err := c.topicOf(ctx, article)
+		if err != nil {
+			return domain.NewsArticle{}, "", err
+		}
+		if ch.Covers(topic) {
+			return article, topic, nil
+		}
+	}
+	return domain.NewsArticle{}, "", errNoArticle
+}
+
+func (c *articleCache) topicOf(ctx context.Context, article domain.NewsArticle) (string, error) {
+	key := articleKey(article)
+	if topic, exists := c.topics[key]; exists {
+		return topic, nil
+	}
+	topic, err := c.classify(ctx, article)
+	if err != nil {
+		return "", err
+	}
+	c.topics[key] = topic
+	return topic, nil
+}
+
+// policyDecision returns the decision of the content policy about the article
+func (c *articleCache) policyDecision(ctx context.Context, article domain.NewsArticle) (domain.PolicyDecision, error) {
+	key := articleKey(article)
+	if decision, exists := c.decisions[key]; exists {
+		return decision, nil
+	}
+	decision, err := c.checkPolicy(ctx, article)
+	if err != nil {
+		return domain.PolicyDecision{}, err
+	}
+	c.decisions[key] = decision
+	return decision, nil
+}
+
+// enriched returns the article with the story it is about as its body, see service.enrichArticle
+func (c *articleCache) enriched(ctx context.Context, article domain.NewsArticle, readStory bool) domain.NewsArticle {
+	key := articleKey(article)
+	if enriched, exists := c.enrichments[key]; exists {
+		return enriched
+	}
+	enriched := c.enrich(ctx, article, readStory)
+	c.enrichments[key] = enriched
+	return enriched
+}
+
+func articleKey(article domain.NewsArticle) string {
+	return article.Url + "\n" + article.Title
+}
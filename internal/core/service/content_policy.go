@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+)
+
+// checkContentPolicy decides whether the article is about one of the sensitive categories of the content policy. The
+// keywords are checked first, the LLM is only asked when none of them match.
+func (srv *service) checkContentPolicy(ctx context.Context, article domain.NewsArticle) (domain.PolicyDecision, error) {
+	if category, keyword, matched := srv.contentPolicy.MatchKeywords(article); matched {
+		return domain.PolicyDecision{Action: category.Action, Category: category.Name, Reason: fmt.Sprintf("keyword %q", keyword)}, nil
+	}
+	if !srv.contentPolicy.UseLLM || len(srv.contentPolicy.Categories()) == 0 {
+		return domain.PolicyDecision{Action: domain.PolicyActionAllow}, nil
+	}
+
+	answer, err := srv.llmAdapter.Chat(ctx, createSensitivityRequest(article, srv.contentPolicy.Categories()))
+	if err != nil {
+		return domain.PolicyDecision{}, err
+	}
+	answer = strings.Trim(strings.TrimSpace(answer), `."'`)
+	for _, category := range srv.contentPolicy.Categories() {
+		if strings.EqualFold(category.Name, answer) {
+			return domain.PolicyDecision{Action: category.Action, Category: category.Name, Reason: "classified by the LLM"}, nil
+		}
+	}
+	return domain.PolicyDecision{Action: domain.PolicyActionAllow}, nil
+}
+
+// createSensitivityRequest asks the LLM which of the sensitive categories the article is about
+func createSensitivityRequest(article domain.NewsArticle, categories []domain.SensitiveCategory) string {
+	names := make([]string, 0, len(categories))
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+	return fmt.Sprintf("Decide if the following news article is about one of these sensitive categories: %s"+
+		"\nTitle: %s"+
+		"\nBody: %s"+
+		"\n\n Answer with only the name of the category, or none when the article is not about any of them.",
+		strings.Join(names, ", "), article.Title, article.Body)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+)
+
+// enrichArticle gives the LLM more to work with than the short summaries news sources provide. The body is replaced
+// with the story at the url of the article when readStory is set and the story is longer, and bodies longer than the
+// maximum length are summarized by the LLM so they fit in its prompts. Enriching is best effort, the body is kept when
+// the story cannot be read and cut off when it cannot be summarized.
+func (srv *service) enrichArticle(ctx context.Context, article domain.NewsArticle, readStory bool) domain.NewsArticle {
+	if readStory && article.Url != "" && srv.articleAdapter != nil {
+		story, err := srv.articleAdapter.GetArticle(ctx, article.Url)
+		if err != nil {
+			srv.logger.Warn("Could not read the story of the article, using its summary", "url", article.Url, "error", err)
+		} else if len(story.Body) > len(article.Body) {
+			srv.logger.Debug("Read the story of the article", "url", article.Url, "length", utf8.RuneCountInString(story.Body))
+			article.Body = story.Body
+		}
+	}
+
+	if srv.maxBodyLength <= 0 || utf8.RuneCountInString(article.Body) <= srv.maxBodyLength {
+		return article
+	}
+	summary, err := srv.llmAdapter.Chat(ctx, createSummaryRequest(article, srv.maxBodyLength))
+	summary = strings.TrimSpace(summary)
+	if err != nil || summary == "" {
+		srv.logger.Warn("Could not summarize the article, cutting it off", "title", article.Title, "error", err)
+		summary = article.Body
+	}
+	// The LLM does not always keep to the length it is asked for
+	article.Body = truncateText(summary, srv.maxBodyLength)
+	return article
+}
+
+// createSummaryRequest asks the LLM to summarize the article in at most the number of characters
+func createSummaryRequest(article domain.NewsArticle, maxLength int) string {
+	return fmt.Sprintf("Summarize the following news article in at most %d characters, keeping the people, places and "+
+		"events an illustrator would need to depict the story."+
+		"\nTitle: %s"+
+		"\nBody: %s"+
+		"\n\n Answer with only the summary.",
+		maxLength, article.Title, article.Body)
+}
+
+// truncateText cuts the text off at the last sentence or word that fits in the number of characters
+func truncateText(text string, maxLength int) string {
+	if utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+	truncated := string([]rune(text)[:maxLength])
+	// Sentences are only kept whole when that does not lose more than half of the text
+	if end := strings.LastIndexAny(truncated, ".!?"); end >= len(truncated)/2 {
+		return truncated[:end+1]
+	}
+	if end := strings.LastIndexAny(truncated, " \n"); end > 0 {
+		return strings.TrimSpace(truncated[:end])
+	}
+	return truncated
+}
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"text/template"
+	"time"
 
 	"github.com/BaronBonet/content-generator/internal/core/domain"
 	"github.com/BaronBonet/content-generator/internal/core/ports"
@@ -11,68 +15,720 @@ import (
 )
 
 type service struct {
-	logger              logger.Logger
-	newsAdapter         ports.NewsAdapter
-	llmAdapter          ports.LLMAdapter
-	generationAdapter   ports.ImageGenerationAdapter
-	socialMediaAdapters []ports.SocialMediaAdapter
+	logger            logger.Logger
+	newsAdapter       ports.NewsAdapter
+	articleAdapter    ports.ArticleAdapter
+	llmAdapter        ports.LLMAdapter
+	generationAdapter ports.ImageGenerationAdapter
+	channels          []channel
+	topicClassifier   domain.TopicClassifier
+	topics            []domain.Topic
+	contentPolicy     *domain.ContentPolicy
+	// enrichArticles reads the stories of the articles, maxBodyLength is the length above which they are summarized
+	enrichArticles bool
+	maxBodyLength  int
+	previewAdapter ports.PreviewAdapter
+	repository     ports.RepositoryAdapter
+	now            func() time.Time
+	// publishTimeout is the deadline of each social media adapter, ConfiguredAdapter overrides it per adapter
+	publishTimeout time.Duration
+	// publishBudget is the deadline for publishing to all social media adapters together
+	publishBudget time.Duration
 }
 
-func (srv *service) GenerateNewsContent(ctx context.Context) error {
-	article, err := srv.newsAdapter.GetMainArticle(ctx)
-	if err != nil {
-		srv.logger.Error("Error when getting article", "error", err)
-		return err
+// channel is a named group of social media adapters the content of a run is published to
+type channel struct {
+	domain.Channel
+	socialMediaAdapters []ConfiguredAdapter
+}
+
+// ConfiguredAdapter is a social media adapter with the settings of its instance, it can be passed wherever the service
+// takes a social media adapter. Other adapters are identified by their name and use the publish timeout of the service.
+type ConfiguredAdapter struct {
+	ports.SocialMediaAdapter
+	// ID identifies the adapter within its channel, so two accounts of the same social media keep their own results.
+	// It defaults to the name of the adapter.
+	ID string
+	// PublishTimeout overrides the publish timeout of the service when it is not zero
+	PublishTimeout time.Duration
+}
+
+// id returns the id of the adapter, its name when no id is configured
+func (a ConfiguredAdapter) id() string {
+	if a.ID == "" {
+		return a.GetName()
 	}
-	srv.logger.Debug("Got article", "article", article)
+	return a.ID
+}
 
-	prompt := fmt.Sprintf("Generate a single sentence image prompt based on the following news title and body:"+
-		"\nTitle: %s"+
-		"\nBody: %s"+
-		"\n Do not include prompts that will be rejected by the Dalle safety system. For example mentioning dictators like Vladimir Putin."+
-		"\n\n Examples of good prompts"+
-		"\n- 3D render of a pink balloon dog in a violet room"+
-		"\n- Illustration of a happy cat sitting on a couch in a living room with a coffee mug in its hand", article.Title, article.Body)
+// errNoArticle is returned when none of the top articles is about the topics of a channel
+var errNoArticle = errors.New("no article about the topics of the channel")
+
+// Option configures the optional parts of the service
+type Option func(*service)
+
+// WithChannel adds a channel publishing to the social media adapters, every channel gets its own run with its own
+// article and images
+func WithChannel(channel domain.Channel, socialMediaAdapters []ports.SocialMediaAdapter) Option {
+	return func(srv *service) {
+		srv.channels = append(srv.channels, newChannel(channel, socialMediaAdapters))
+	}
+}
+
+// WithTopics classifies the articles into the topics with the classifier. Channels publish the articles of their
+// topics, and the topics decide the style and prompt of the images of their articles.
+func WithTopics(classifier domain.TopicClassifier, topics []domain.Topic) Option {
+	return func(srv *service) {
+		srv.topicClassifier = classifier
+		srv.topics = topics
+	}
+}
+
+// WithContentPolicy checks every article against the content policy before its images are generated, so articles about
+// sensitive categories are skipped or get neutral images
+func WithContentPolicy(policy domain.ContentPolicy) Option {
+	return func(srv *service) {
+		srv.contentPolicy = &policy
+	}
+}
+
+// WithArticleEnrichment reads the story at the url of the news articles with the article adapter, the news only
+// provides a short summary. Stories longer than maxBodyLength characters are summarized by the LLM so they fit in its
+// prompts, zero means they are never summarized.
+func WithArticleEnrichment(maxBodyLength int) Option {
+	return func(srv *service) {
+		srv.enrichArticles = true
+		srv.maxBodyLength = maxBodyLength
+	}
+}
+
+// WithArticleAdapter sets how the articles of runs with an article url are read
+func WithArticleAdapter(articleAdapter ports.ArticleAdapter) Option {
+	return func(srv *service) {
+		srv.articleAdapter = articleAdapter
+	}
+}
+
+// WithRepositoryAdapter sets where drafts waiting for approval and the checkpoints of runs are stored
+func WithRepositoryAdapter(repository ports.RepositoryAdapter) Option {
+	return func(srv *service) {
+		srv.repository = repository
+	}
+}
+
+// WithPublishTimeout sets how long each social media adapter gets to publish a post, zero means no timeout
+func WithPublishTimeout(timeout time.Duration) Option {
+	return func(srv *service) {
+		srv.publishTimeout = timeout
+	}
+}
+
+// WithPublishBudget sets how long publishing to all social media adapters may take, zero means no budget. Adapters
+// that are still running when the budget runs out are reported as timed out.
+func WithPublishBudget(budget time.Duration) Option {
+	return func(srv *service) {
+		srv.publishBudget = budget
+	}
+}
+
+// WithPreviewAdapter sets where the posts of a dry run are rendered
+func WithPreviewAdapter(previewAdapter ports.PreviewAdapter) Option {
+	return func(srv *service) {
+		srv.previewAdapter = previewAdapter
+	}
+}
+
+func (srv *service) GenerateNewsContent(ctx context.Context, opts domain.GenerateOptions) ([]domain.RunResult, error) {
+	return srv.generate(ctx, opts, nil)
+}
+
+func (srv *service) GenerateArticleContent(ctx context.Context, article domain.NewsArticle, opts domain.GenerateOptions) ([]domain.RunResult, error) {
+	if strings.TrimSpace(article.Title) == "" {
+		return nil, fmt.Errorf("%w: the article has no title", domain.ErrInvalidOptions)
+	}
+	if opts.ArticleURL != "" {
+		return nil, fmt.Errorf("%w: an article url cannot be given together with an article", domain.ErrInvalidOptions)
+	}
+	if opts.Resume {
+		return nil, fmt.Errorf("%w: runs of a given article cannot be resumed, resume the run by its id instead", domain.ErrInvalidOptions)
+	}
+	return srv.generate(ctx, opts, &article)
+}
+
+// generate starts a run for every channel, the runs use the article when it is given instead of fetching the news
+func (srv *service) generate(ctx context.Context, opts domain.GenerateOptions, article *domain.NewsArticle) ([]domain.RunResult, error) {
+	if opts.NumberOfImages() > domain.MaxImagesPerPost {
+		return nil, fmt.Errorf("%w: at most %d images can be generated for a post, got %d", domain.ErrInvalidOptions, domain.MaxImagesPerPost, opts.NumberOfImages())
+	}
+	if opts.DryRun && srv.previewAdapter == nil {
+		return nil, fmt.Errorf("%w: dry run requested but no preview adapter is configured", domain.ErrInvalidOptions)
+	}
+	if opts.RequireApproval && srv.repository == nil {
+		return nil, fmt.Errorf("%w: approval required but no repository adapter is configured", domain.ErrInvalidOptions)
+	}
+	if opts.ArticleURL != "" && srv.articleAdapter == nil {
+		return nil, fmt.Errorf("%w: article url given but no article adapter is configured", domain.ErrInvalidOptions)
+	}
+
+	channels := srv.channels
+	if len(opts.Channels) > 0 {
+		channels = nil
+		for _, name := range opts.Channels {
+			ch, err := srv.getChannel(name)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", domain.ErrInvalidOptions, err)
+			}
+			channels = append(channels, ch)
+		}
+	}
+
+	var incompleteRuns []domain.Run
+	if opts.Resume && !opts.DryRun && srv.repository != nil {
+		var err error
+		incompleteRuns, err = srv.repository.ListRuns(ctx, domain.RunStatusInProgress)
+		if err != nil {
+			srv.logger.Error("Error when listing incomplete runs", "error", err)
+			return nil, err
+		}
+	}
+
+	// The channels share the articles, so the news is only fetched once
+	articles := newArticleCache(srv)
+	var results []domain.RunResult
+	var errs []error
+	for _, ch := range channels {
+		run := latestRun(incompleteRuns, ch.Name, opts.ArticleURL)
+		if run != nil {
+			srv.logger.Info("Resuming incomplete run", "run", run.ID, "channel", ch.Name)
+		} else {
+			run = &domain.Run{
+				ID:        newID(srv.now()),
+				Status:    domain.RunStatusInProgress,
+				Channel:   ch.Name,
+				Options:   opts,
+				Images:    make([]domain.GeneratedImage, opts.NumberOfImages()),
+				CreatedAt: srv.now(),
+			}
+			// Images that already have a prompt skip asking the LLM for one
+			for i := range run.Images {
+				run.Images[i].Prompt = opts.Prompt
+			}
+			if article != nil {
+				run.Article = *article
+			}
+		}
+
+		result, err := srv.execute(ctx, ch, run, articles)
+		if errors.Is(err, errNoArticle) {
+			srv.logger.Info("Skipping channel, none of the top articles is about its topics", "channel", ch.Name, "topics", ch.Topics)
+			continue
+		}
+		results = append(results, result)
+		if err != nil {
+			if ch.Name != "" {
+				err = fmt.Errorf("channel %s: %w", ch.Name, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 1 {
+		return results, errs[0]
+	}
+	return results, errors.Join(errs...)
+}
 
-	imagePrompt, err := srv.llmAdapter.Chat(ctx, prompt)
+func (srv *service) ResumeRun(ctx context.Context, id string) (domain.RunResult, error) {
+	if srv.repository == nil {
+		return domain.RunResult{}, errors.New("no repository adapter is configured")
+	}
+	run, err := srv.repository.GetRun(ctx, id)
 	if err != nil {
-		srv.logger.Error("Error when creating image prompt", "error", err)
-		return err
+		return domain.RunResult{}, err
 	}
-	srv.logger.Debug("Got image prompt", "imagePrompt", imagePrompt)
-	image, err := srv.generationAdapter.GenerateImage(ctx, imagePrompt)
+	if run.Status == domain.RunStatusCompleted {
+		return domain.RunResult{}, fmt.Errorf("run %s is already completed", id)
+	}
+	ch, err := srv.getChannel(run.Channel)
 	if err != nil {
-		srv.logger.Error("Error when generating image", "error", err)
-		return err
+		return domain.RunResult{}, err
+	}
+	srv.logger.Info("Resuming run", "run", run.ID)
+	return srv.execute(ctx, ch, &run, newArticleCache(srv))
+}
+
+func (srv *service) GetRun(ctx context.Context, id string) (domain.Run, error) {
+	if srv.repository == nil {
+		return domain.Run{}, errors.New("no repository adapter is configured")
+	}
+	return srv.repository.GetRun(ctx, id)
+}
+
+// getChannel returns the channel with the name, the empty name is the channel of the adapters the service was created with
+func (srv *service) getChannel(name string) (channel, error) {
+	for _, ch := range srv.channels {
+		if ch.Name == name {
+			return ch, nil
+		}
+	}
+	return channel{}, fmt.Errorf("unknown channel %q", name)
+}
+
+// latestRun returns the most recent of the runs that belongs to the channel and was started for the same article url,
+// so a run never wins over an article that was asked for. Runs are listed from oldest to newest
+func latestRun(runs []domain.Run, channelName string, articleURL string) *domain.Run {
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].Channel == channelName && runs[i].Options.ArticleURL == articleURL {
+			return &runs[i]
+		}
+	}
+	return nil
+}
+
+// execute performs the stages of the run that did not succeed yet, saving a checkpoint after each of them
+func (srv *service) execute(ctx context.Context, ch channel, run *domain.Run, articles *articleCache) (domain.RunResult, error) {
+	if !run.ArticleFetched {
+		article, topic, err := articles.articleFor(ctx, ch.Channel, run)
+		if errors.Is(err, errNoArticle) {
+			return srv.runResult(run), err
+		}
+		if err != nil {
+			srv.logger.Error("Error when getting article", "error", err)
+			return srv.runResult(run), err
+		}
+		srv.logger.Debug("Got article", "article", article, "topic", topic, "channel", ch.Name)
+		run.Article, run.Topic, run.ArticleFetched = article, topic, true
+		srv.checkpoint(ctx, run)
+	}
+
+	if srv.contentPolicy != nil && run.ContentPolicy.Action == "" {
+		decision, err := articles.policyDecision(ctx, run.Article)
+		if err != nil {
+			srv.logger.Error("Error when checking the content policy", "error", err)
+			return srv.runResult(run), err
+		}
+		if decision.Action != domain.PolicyActionAllow {
+			srv.logger.Info("Content policy applies to article", "title", run.Article.Title, "category", decision.Category,
+				"action", decision.Action, "reason", decision.Reason, "channel", ch.Name)
+		}
+		run.ContentPolicy = decision
+		srv.checkpoint(ctx, run)
+	}
+	if run.ContentPolicy.Action == domain.PolicyActionSkip {
+		run.Status = domain.RunStatusCompleted
+		srv.checkpoint(ctx, run)
+		return srv.runResult(run), nil
+	}
+
+	// The content policy is checked before enriching, so its keywords match the summary of the news and not every
+	// word of the story
+	if srv.enrichArticles && !run.ArticleEnriched {
+		run.Article = articles.enriched(ctx, run.Article, run.Options.ArticleURL == "")
+		run.ArticleEnriched = true
+		srv.checkpoint(ctx, run)
+	}
+
+	if err := srv.generateImages(ctx, run, srv.imageStyles(ch, run), srv.getTopic(run.Topic).PromptTemplate); err != nil {
+		srv.checkpoint(ctx, run)
+		return srv.runResult(run), err
+	}
+	run.ImageGeneratorName = srv.generationAdapter.GetGeneratorName()
+
+	// Generated image urls expire, so they are stored before a checkpoint refers to them
+	if srv.repository != nil && !run.Options.DryRun && !run.ImagesStored {
+		for i, image := range run.Images {
+			storedImage, err := srv.repository.StoreImage(ctx, image)
+			if err != nil {
+				srv.logger.Error("Error when storing image", "error", err)
+				return srv.runResult(run), err
+			}
+			run.Images[i] = storedImage
+		}
+		run.ImagesStored = true
+	}
+	srv.checkpoint(ctx, run)
+
+	result := srv.runResult(run)
+	switch {
+	case run.Options.DryRun:
+		var err error
+		result.Previews, err = srv.renderPreviews(ctx, ch, run.Post())
+		return result, err
+	case run.Options.RequireApproval:
+		if run.DraftID == "" {
+			draft, err := srv.createDraft(ctx, ch, run.Post())
+			if err != nil {
+				return result, err
+			}
+			run.DraftID = draft.ID
+		}
+		run.Status = domain.RunStatusCompleted
+	default:
+		var adapters []ConfiguredAdapter
+		for _, adapter := range ch.socialMediaAdapters {
+			if run.IsPublished(adapter.id()) {
+				srv.logger.Debug("Skipping social media the run already published to", "adapter", adapter.id())
+				continue
+			}
+			adapters = append(adapters, adapter)
+		}
+		run.Publications = mergePublications(run.Publications, srv.publish(ctx, run.Post(), adapters))
+		// The failed social medias are not retried by resuming, an account that keeps failing would otherwise keep
+		// every later run on the same article
+		run.Status = domain.RunStatusCompleted
+	}
+	srv.checkpoint(ctx, run)
+	return srv.runResult(run), nil
+}
+
+// checkpoint saves the progress of the run, failing to do so is logged but does not fail the run
+func (srv *service) checkpoint(ctx context.Context, run *domain.Run) {
+	if srv.repository == nil || run.Options.DryRun {
+		return
+	}
+	run.UpdatedAt = srv.now()
+	if err := srv.repository.SaveRun(ctx, *run); err != nil {
+		srv.logger.Warn("Could not save checkpoint of run, it can not be resumed", "run", run.ID, "error", err)
+	}
+}
+
+func (srv *service) runResult(run *domain.Run) domain.RunResult {
+	return domain.RunResult{
+		ID:            run.ID,
+		Channel:       run.Channel,
+		Post:          run.Post(),
+		DraftID:       run.DraftID,
+		Publications:  run.Publications,
+		ContentPolicy: run.ContentPolicy,
+		// Only runs with a checkpoint have an UpdatedAt
+		Resumable: run.Status == domain.RunStatusInProgress && !run.UpdatedAt.IsZero(),
+	}
+}
+
+// mergePublications replaces the previous results of social medias with their new results
+func mergePublications(previous []domain.PublishResult, latest []domain.PublishResult) []domain.PublishResult {
+	merged := append([]domain.PublishResult{}, previous...)
+	for _, publication := range latest {
+		replaced := false
+		for i := range merged {
+			if merged[i].AdapterID == publication.AdapterID {
+				merged[i], replaced = publication, true
+			}
+		}
+		if !replaced {
+			merged = append(merged, publication)
+		}
+	}
+	return merged
+}
+
+// publish publishes the post to the social medias concurrently. Errors are reported in the results instead of being
+// returned, since retrying would create duplicate posts on the social medias that succeeded.
+func (srv *service) publish(ctx context.Context, post domain.Post, adapters []ConfiguredAdapter) []domain.PublishResult {
+	if srv.publishBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, srv.publishBudget)
+		defer cancel()
 	}
-	srv.logger.Debug("Generated image", "image", image)
 
 	var wg sync.WaitGroup
-	errCh := make(chan error, len(srv.socialMediaAdapters))
+	results := make([]domain.PublishResult, len(adapters))
 
-	for _, adapter := range srv.socialMediaAdapters {
+	for i, adapter := range adapters {
 		wg.Add(1)
-		go func(adapter ports.SocialMediaAdapter) {
-			srv.logger.Debug("Publishing image to social media", "adapter", adapter.GetName())
+		go func(i int, adapter ConfiguredAdapter) {
 			defer wg.Done()
-			if err := adapter.PublishImagePost(ctx, image, imagePrompt, srv.generationAdapter.GetGeneratorName(), article); err != nil {
-				errCh <- err
+			results[i] = srv.publishWithAdapter(ctx, post, adapter)
+		}(i, adapter)
+	}
+
+	wg.Wait()
+
+	srv.logger.Debug("Published image to social medias")
+	return results
+}
+
+// publishWithAdapter publishes the post with a single adapter, isolating the other adapters from it. It stops waiting
+// once the adapter's deadline passes, even if the adapter ignores the context, and recovers when the adapter panics.
+func (srv *service) publishWithAdapter(ctx context.Context, post domain.Post, adapter ConfiguredAdapter) domain.PublishResult {
+	srv.logger.Debug("Publishing image to social media", "adapter", adapter.id())
+
+	timeout := srv.publishTimeout
+	if adapter.PublishTimeout > 0 {
+		timeout = adapter.PublishTimeout
+	}
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	type outcome struct {
+		publishedPost domain.PublishedPost
+		err           error
+	}
+	// Buffered, so an adapter that finishes after we stopped waiting does not block forever
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("adapter panicked: %v", r)}
 			}
-		}(adapter)
+		}()
+		publishedPost, err := adapter.PublishImagePost(ctx, post)
+		done <- outcome{publishedPost: publishedPost, err: err}
+	}()
+
+	result := domain.PublishResult{AdapterName: adapter.GetName(), AdapterID: adapter.id(), Status: domain.PublishStatusPublished}
+	select {
+	case o := <-done:
+		// Adapters that fail halfway, e.g. while replying to their post, still return what they published
+		result.PostID, result.URL = o.publishedPost.ID, o.publishedPost.URL
+		if o.err != nil {
+			srv.logger.Error("Error when posting image", "adapter", adapter.id(), "error", o.err)
+			result.Status, result.Error = domain.PublishStatusFailed, o.err.Error()
+		}
+	case <-ctx.Done():
+		result.Status, result.Error = domain.PublishStatusTimedOut, ctx.Err().Error()
+		if errors.Is(ctx.Err(), context.Canceled) {
+			result.Status = domain.PublishStatusCancelled
+		}
+		srv.logger.Error("Stopped waiting for social media", "adapter", adapter.id(), "status", result.Status)
+	}
+	return result
+}
+
+// createDraft stores the post as a draft waiting for approval, the images must already be stored in the repository
+// since the generated image urls expire before someone gets around to approving the draft.
+func (srv *service) createDraft(ctx context.Context, ch channel, post domain.Post) (domain.Draft, error) {
+	previews := make([]domain.PostPreview, 0, len(ch.socialMediaAdapters))
+	for _, adapter := range ch.socialMediaAdapters {
+		previews = append(previews, previewImagePost(ch, adapter, post))
+	}
+
+	draft := domain.Draft{
+		ID:        newID(srv.now()),
+		Status:    domain.DraftStatusPending,
+		Channel:   ch.Name,
+		Post:      post,
+		Previews:  previews,
+		CreatedAt: srv.now(),
+	}
+	if err := srv.repository.SaveDraft(ctx, draft); err != nil {
+		srv.logger.Error("Error when saving draft", "error", err)
+		return domain.Draft{}, err
+	}
+	srv.logger.Info("Created draft, it will be published once approved", "id", draft.ID)
+	return draft, nil
+}
+
+func (srv *service) ListDrafts(ctx context.Context) ([]domain.Draft, error) {
+	if srv.repository == nil {
+		return nil, errors.New("no repository adapter is configured")
+	}
+	return srv.repository.ListDrafts(ctx, domain.DraftStatusPending)
+}
+
+func (srv *service) ApproveDraft(ctx context.Context, id string) (domain.RunResult, error) {
+	draft, err := srv.getPendingDraft(ctx, id)
+	if err != nil {
+		return domain.RunResult{}, err
+	}
+	ch, err := srv.getChannel(draft.Channel)
+	if err != nil {
+		return domain.RunResult{}, err
+	}
+
+	var adapters []ConfiguredAdapter
+	for _, adapter := range ch.socialMediaAdapters {
+		if draft.IsPublished(adapter.id()) {
+			srv.logger.Debug("Skipping social media the draft was already published to", "adapter", adapter.id())
+			continue
+		}
+		adapters = append(adapters, adapter)
+	}
+	draft.Publications = mergePublications(draft.Publications, srv.publish(ctx, draft.Post, adapters))
+
+	// The draft stays pending while a social media failed before posting anything, so approving it again retries only
+	// those
+	draft.Status = domain.DraftStatusPublished
+	for _, adapter := range ch.socialMediaAdapters {
+		if !draft.IsPublished(adapter.id()) {
+			draft.Status = domain.DraftStatusPending
+		}
+	}
+
+	result := domain.RunResult{
+		ID:           newID(srv.now()),
+		Channel:      draft.Channel,
+		Post:         draft.Post,
+		DraftID:      draft.ID,
+		Publications: draft.Publications,
+	}
+	return result, srv.repository.SaveDraft(ctx, draft)
+}
+
+func (srv *service) RejectDraft(ctx context.Context, id string) error {
+	draft, err := srv.getPendingDraft(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	draft.Status = domain.DraftStatusRejected
+	return srv.repository.SaveDraft(ctx, draft)
+}
+
+func (srv *service) getPendingDraft(ctx context.Context, id string) (domain.Draft, error) {
+	if srv.repository == nil {
+		return domain.Draft{}, errors.New("no repository adapter is configured")
+	}
+	draft, err := srv.repository.GetDraft(ctx, id)
+	if err != nil {
+		return domain.Draft{}, err
+	}
+	if draft.Status != domain.DraftStatusPending {
+		return domain.Draft{}, fmt.Errorf("draft %s is already %s", id, draft.Status)
+	}
+	return draft, nil
+}
+
+// renderPreviews renders what each social media adapter would publish, instead of publishing the post
+func (srv *service) renderPreviews(ctx context.Context, ch channel, post domain.Post) ([]domain.PostPreview, error) {
+	previews := make([]domain.PostPreview, 0, len(ch.socialMediaAdapters))
+	for _, adapter := range ch.socialMediaAdapters {
+		preview := previewImagePost(ch, adapter, post)
+		if err := srv.previewAdapter.RenderPreview(ctx, preview); err != nil {
+			srv.logger.Error("Error when rendering preview", "adapter", adapter.id(), "error", err)
+			return previews, err
+		}
+		previews = append(previews, preview)
+	}
+	srv.logger.Debug("Rendered previews, nothing was published because of the dry run")
+	return previews, nil
+}
+
+// previewImagePost returns what the adapter would publish, identifying the adapter so previews of two accounts of the
+// same social media can be told apart
+func previewImagePost(ch channel, adapter ConfiguredAdapter, post domain.Post) domain.PostPreview {
+	preview := adapter.PreviewImagePost(post)
+	preview.Channel, preview.AdapterID = ch.Name, adapter.id()
+	return preview
+}
+
+// imageStyles returns the style of each image of the run. Requested styles are used over the style of the topic, which
+// is used over the style of the channel, and the neutral style of the content policy overrides all of them.
+func (srv *service) imageStyles(ch channel, run *domain.Run) []string {
+	style := ch.PromptStyle
+	if topicStyle := srv.getTopic(run.Topic).PromptStyle; topicStyle != "" {
+		style = topicStyle
 	}
+	styles := make([]string, len(run.Images))
+	for i := range styles {
+		switch {
+		case run.ContentPolicy.Action == domain.PolicyActionNeutral:
+			styles[i] = srv.contentPolicy.NeutralStyle
+		case len(run.Options.ImageStyles) > 0:
+			styles[i] = run.Options.ImageStyles[i]
+		default:
+			styles[i] = style
+		}
+	}
+	return styles
+}
+
+// generateImages generates the missing prompts and images of the run concurrently in their styles. Images that
+// succeeded are kept in the run when others fail, so a resumed run only generates the failed ones. The prompt template
+// replaces the default request for the image prompts when set.
+func (srv *service) generateImages(ctx context.Context, run *domain.Run, styles []string, promptTemplate string) error {
+	count := len(run.Images)
+	errs := make([]error, count)
 
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		if run.Images[i].Path != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			run.Images[i], errs[i] = srv.generateImage(ctx, run.Article, run.Images[i], promptTemplate, styles[i], i, count)
+		}(i)
+	}
 	wg.Wait()
 
-	close(errCh)
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	for err := range errCh {
+// generateImage creates the prompt of the image when it is missing and then generates the image. On failure, it
+// returns the image with the prompt when that was created successfully.
+func (srv *service) generateImage(ctx context.Context, article domain.NewsArticle, image domain.GeneratedImage, promptTemplate string, style string, index int, count int) (domain.GeneratedImage, error) {
+	if image.Prompt == "" {
+		request, err := createImagePromptRequest(article, promptTemplate, style, index, count)
 		if err != nil {
-			srv.logger.Error("Error when posting image", "error", err)
+			srv.logger.Error("Error when creating image prompt request", "error", err)
+			return image, err
 		}
+		imagePrompt, err := srv.llmAdapter.Chat(ctx, request)
+		if err != nil {
+			srv.logger.Error("Error when creating image prompt", "error", err)
+			return image, err
+		}
+		srv.logger.Debug("Got image prompt", "imagePrompt", imagePrompt)
+		image.Prompt = imagePrompt
 	}
 
-	srv.logger.Debug("Published image to social medias")
-	return nil
+	imagePath, err := srv.generationAdapter.GenerateImage(ctx, image.Prompt)
+	if err != nil {
+		srv.logger.Error("Error when generating image", "error", err)
+		return image, err
+	}
+	srv.logger.Debug("Generated image", "image", imagePath)
+	image.Path = imagePath
+
+	return image, nil
+}
+
+// createImagePromptRequest creates the request sent to the LLM for generating an image prompt. When several images are
+// generated for the same article, each one is asked to either use its own style or depict a different part of the story.
+// A prompt template replaces the default request, it is executed with the article.
+func createImagePromptRequest(article domain.NewsArticle, promptTemplate string, style string, index int, count int) (string, error) {
+	var request string
+	if promptTemplate != "" {
+		tmpl, err := template.New("prompt").Parse(promptTemplate)
+		if err != nil {
+			return "", fmt.Errorf("invalid prompt template: %w", err)
+		}
+		var builder strings.Builder
+		if err = tmpl.Execute(&builder, article); err != nil {
+			return "", fmt.Errorf("failed to execute prompt template: %w", err)
+		}
+		request = builder.String()
+	} else {
+		request = defaultImagePromptRequest(article)
+	}
+
+	if style != "" {
+		request += fmt.Sprintf("\n\n The image must be in the style of: %s", style)
+	} else if count > 1 {
+		request += fmt.Sprintf("\n\n This is image %d of %d for the story, depict part %d of the story so each image shows something different.", index+1, count, index+1)
+	}
+	return request, nil
+}
+
+func defaultImagePromptRequest(article domain.NewsArticle) string {
+	return fmt.Sprintf("Generate a single sentence image prompt based on the following news title and body:"+
+		"\nTitle: %s"+
+		"\nBody: %s"+
+		"\n Do not include prompts that will be rejected by the Dalle safety system. For example mentioning dictators like Vladimir Putin."+
+		"\n\n Examples of good prompts"+
+		"\n- 3D render of a pink balloon dog in a violet room"+
+		"\n- Illustration of a happy cat sitting on a couch in a living room with a coffee mug in its hand", article.Title, article.Body)
 }
 
 func (srv *service) CreatePrompt(ctx context.Context, prompt string) (string, error) {
@@ -89,12 +745,33 @@ func NewNewsContentService(
 	llmAdapter ports.LLMAdapter,
 	imageGenerationAdapter ports.ImageGenerationAdapter,
 	postingRepos []ports.SocialMediaAdapter,
+	opts ...Option,
 ) ports.Service {
-	return &service{
-		logger:              logger,
-		newsAdapter:         externalNewsAdapter,
-		llmAdapter:          llmAdapter,
-		generationAdapter:   imageGenerationAdapter,
-		socialMediaAdapters: postingRepos,
+	srv := &service{
+		logger:            logger,
+		newsAdapter:       externalNewsAdapter,
+		llmAdapter:        llmAdapter,
+		generationAdapter: imageGenerationAdapter,
+		now:               time.Now,
+	}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	// The adapters passed directly form the unnamed channel, which publishes the main article
+	if len(postingRepos) > 0 || len(srv.channels) == 0 {
+		srv.channels = append([]channel{newChannel(domain.Channel{}, postingRepos)}, srv.channels...)
+	}
+	return srv
+}
+
+func newChannel(ch domain.Channel, socialMediaAdapters []ports.SocialMediaAdapter) channel {
+	configuredAdapters := make([]ConfiguredAdapter, 0, len(socialMediaAdapters))
+	for _, adapter := range socialMediaAdapters {
+		configuredAdapter, ok := adapter.(ConfiguredAdapter)
+		if !ok {
+			configuredAdapter = ConfiguredAdapter{SocialMediaAdapter: adapter}
+		}
+		configuredAdapters = append(configuredAdapters, configuredAdapter)
 	}
+	return channel{Channel: ch, socialMediaAdapters: configuredAdapters}
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/BaronBonet/content-generator/internal/infrastructure"
 	"github.com/BaronBonet/go-logger/logger"
@@ -21,17 +23,20 @@ func TestService_GenerateNewsContent(t *testing.T) {
 	llmAdapter := ports.NewMockLLMAdapter(t)
 	mockImageGenerationAdapter := ports.NewMockImageGenerationAdapter(t)
 	mockSocialMediaAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockPreviewAdapter := ports.NewMockPreviewAdapter(t)
 
 	testCases := []struct {
-		name          string
-		setupMocks    func()
-		expectedError error
+		name                 string
+		opts                 domain.GenerateOptions
+		setupMocks           func()
+		expectedPublications []domain.PublishResult
+		expectedError        error
 	}{
 		{
 			name: "Success",
 			setupMocks: func() {
 				newsArticle := domain.NewsArticle{Title: "Test Article", Body: "Test body"}
-				mockNewsAdapter.On("GetMainArticle", mock.AnythingOfType("*context.emptyCtx")).Return(newsArticle, nil)
+				mockNewsAdapter.On("GetMainArticle", mock.Anything).Return(newsArticle, nil)
 				prompt := fmt.Sprintf("Generate a single sentence image prompt based on the following news title and body:"+
 					"\nTitle: %s"+
 					"\nBody: %s"+
@@ -40,16 +45,56 @@ func TestService_GenerateNewsContent(t *testing.T) {
 					"\n- 3D render of a pink balloon dog in a violet room"+
 					"\n- Illustration of a happy cat sitting on a couch in a living room with a coffee mug in its hand", newsArticle.Title, newsArticle.Body)
 
-				llmAdapter.On("Chat", mock.AnythingOfType("*context.emptyCtx"), prompt).Return(prompt, nil)
+				llmAdapter.On("Chat", mock.Anything, prompt).Return(prompt, nil)
 				imagePath := "https://test.com/test.jpg"
 				generatorName := "TestGenerator"
-				mockImageGenerationAdapter.On("GenerateImage", mock.AnythingOfType("*context.emptyCtx"), prompt).Return(domain.ImagePath(imagePath), nil)
+				mockImageGenerationAdapter.On("GenerateImage", mock.Anything, prompt).Return(domain.ImagePath(imagePath), nil)
 				mockImageGenerationAdapter.On("GetGeneratorName").Return(generatorName)
-				mockSocialMediaAdapter.On("PublishImagePost", mock.AnythingOfType("*context.emptyCtx"), domain.ImagePath(imagePath), prompt, generatorName, newsArticle).Return(nil)
+				mockSocialMediaAdapter.On("PublishImagePost", mock.Anything, domain.Post{
+					NewsArticle:        newsArticle,
+					Images:             []domain.GeneratedImage{{Path: domain.ImagePath(imagePath), Prompt: prompt}},
+					ImageGeneratorName: generatorName,
+				}).Return(domain.PublishedPost{ID: "1", URL: "https://twitter.com/i/web/status/1"}, nil)
 				mockSocialMediaAdapter.On("GetName").Return("Twitter")
 			},
+			expectedPublications: []domain.PublishResult{
+				{AdapterName: "Twitter", AdapterID: "Twitter", Status: domain.PublishStatusPublished, PostID: "1", URL: "https://twitter.com/i/web/status/1"},
+			},
 			expectedError: nil,
 		},
+		{
+			name: "MultipleImageStyles",
+			opts: domain.GenerateOptions{ImageStyles: []string{"watercolor painting", "3D render"}},
+			setupMocks: func() {
+				newsArticle := domain.NewsArticle{Title: "Test Article", Body: "Test body"}
+				mockNewsAdapter.On("GetMainArticle", mock.Anything).Return(newsArticle, nil)
+				for _, style := range []string{"watercolor painting", "3D render"} {
+					style := style
+					llmAdapter.On("Chat", mock.Anything, mock.MatchedBy(func(request string) bool {
+						return strings.HasSuffix(request, "style of: "+style)
+					})).Return(style+" prompt", nil).Once()
+					mockImageGenerationAdapter.On("GenerateImage", mock.Anything, style+" prompt").Return(domain.ImagePath(style+".png"), nil).Once()
+				}
+				mockImageGenerationAdapter.On("GetGeneratorName").Return("TestGenerator")
+				mockSocialMediaAdapter.On("PublishImagePost", mock.Anything, domain.Post{
+					NewsArticle: newsArticle,
+					Images: []domain.GeneratedImage{
+						{Path: "watercolor painting.png", Prompt: "watercolor painting prompt"},
+						{Path: "3D render.png", Prompt: "3D render prompt"},
+					},
+					ImageGeneratorName: "TestGenerator",
+				}).Return(domain.PublishedPost{ID: "1"}, nil)
+				mockSocialMediaAdapter.On("GetName").Return("Twitter")
+			},
+			expectedPublications: []domain.PublishResult{{AdapterName: "Twitter", AdapterID: "Twitter", Status: domain.PublishStatusPublished, PostID: "1"}},
+			expectedError:        nil,
+		},
+		{
+			name:          "TooManyImages",
+			opts:          domain.GenerateOptions{ImageCount: domain.MaxImagesPerPost + 1},
+			setupMocks:    func() {},
+			expectedError: fmt.Errorf("%w: at most %d images can be generated for a post, got %d", domain.ErrInvalidOptions, domain.MaxImagesPerPost, domain.MaxImagesPerPost+1),
+		},
 		{
 			name: "NewsAdapterError",
 			setupMocks: func() {
@@ -81,9 +126,12 @@ func TestService_GenerateNewsContent(t *testing.T) {
 				llmAdapter.On("Chat", mock.Anything, mock.Anything).Return("Test Image Prompt", nil)
 				mockImageGenerationAdapter.On("GenerateImage", mock.Anything, mock.Anything).Return(domain.ImagePath("Test Image Path"), nil)
 				mockImageGenerationAdapter.On("GetGeneratorName").Return("TestGenerator")
-				mockSocialMediaAdapter.On("PublishImagePost", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("social media error"))
+				mockSocialMediaAdapter.On("PublishImagePost", mock.Anything, mock.Anything).Return(domain.PublishedPost{}, errors.New("social media error"))
 				mockSocialMediaAdapter.On("GetName").Return("Twitter")
 			},
+			expectedPublications: []domain.PublishResult{
+				{AdapterName: "Twitter", AdapterID: "Twitter", Status: domain.PublishStatusFailed, Error: "social media error"},
+			},
 			// We don't want it to retry if the social media adapters fails
 			expectedError: nil,
 		},
@@ -99,17 +147,688 @@ func TestService_GenerateNewsContent(t *testing.T) {
 				llmAdapter,
 				mockImageGenerationAdapter,
 				[]ports.SocialMediaAdapter{mockSocialMediaAdapter},
+				WithPreviewAdapter(mockPreviewAdapter),
 			)
 
-			err := srv.GenerateNewsContent(context.Background())
+			results, err := srv.GenerateNewsContent(context.Background(), tc.opts)
 
 			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedPublications, publications(results))
 
 			infrastructure.TearDownAdapters(&mockNewsAdapter.Mock,
 				&llmAdapter.Mock,
 				&mockImageGenerationAdapter.Mock,
 				&mockSocialMediaAdapter.Mock,
+				&mockPreviewAdapter.Mock,
 			)
 		})
 	}
 }
+
+func TestService_RequireApproval(t *testing.T) {
+	mockNewsAdapter := ports.NewMockNewsAdapter(t)
+	llmAdapter := ports.NewMockLLMAdapter(t)
+	mockImageGenerationAdapter := ports.NewMockImageGenerationAdapter(t)
+	mockSocialMediaAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockRepository := ports.NewMockRepositoryAdapter(t)
+
+	newsArticle := domain.NewsArticle{Title: "Test Article"}
+	mockNewsAdapter.On("GetMainArticle", mock.Anything).Return(newsArticle, nil)
+	llmAdapter.On("Chat", mock.Anything, mock.Anything).Return("Test Image Prompt", nil)
+	mockImageGenerationAdapter.On("GenerateImage", mock.Anything, "Test Image Prompt").Return(domain.ImagePath("https://test.com/test.png"), nil)
+	mockImageGenerationAdapter.On("GetGeneratorName").Return("TestGenerator")
+	mockRepository.On("StoreImage", mock.Anything, domain.GeneratedImage{Path: "https://test.com/test.png", Prompt: "Test Image Prompt"}).Return(domain.GeneratedImage{
+		Path:   "/data/images/test.png",
+		Prompt: "Test Image Prompt",
+		URL:    "https://test.com/test.png",
+	}, nil)
+	mockRepository.On("SaveRun", mock.Anything, mock.Anything).Return(nil)
+
+	storedPost := domain.Post{
+		NewsArticle:        newsArticle,
+		Images:             []domain.GeneratedImage{{Path: "/data/images/test.png", Prompt: "Test Image Prompt", URL: "https://test.com/test.png"}},
+		ImageGeneratorName: "TestGenerator",
+	}
+	preview := domain.PostPreview{AdapterName: "Twitter", Text: "Test Article"}
+	mockSocialMediaAdapter.On("GetName").Return("Twitter")
+	mockSocialMediaAdapter.On("PreviewImagePost", storedPost).Return(preview)
+
+	var draft domain.Draft
+	mockRepository.On("SaveDraft", mock.Anything, mock.MatchedBy(func(d domain.Draft) bool {
+		return d.Status == domain.DraftStatusPending
+	})).Run(func(args mock.Arguments) {
+		draft = args.Get(1).(domain.Draft)
+	}).Return(nil).Once()
+
+	srv := NewNewsContentService(
+		logger.NewTestLogger(),
+		mockNewsAdapter,
+		llmAdapter,
+		mockImageGenerationAdapter,
+		[]ports.SocialMediaAdapter{mockSocialMediaAdapter},
+		WithRepositoryAdapter(mockRepository),
+	)
+
+	results, err := srv.GenerateNewsContent(context.Background(), domain.GenerateOptions{RequireApproval: true})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, draft.ID)
+	assert.Len(t, results, 1)
+	assert.Equal(t, draft.ID, results[0].DraftID)
+	assert.Equal(t, storedPost, draft.Post)
+	// The previews identify the adapter, like the results of publishing
+	preview.AdapterID = "Twitter"
+	assert.Equal(t, []domain.PostPreview{preview}, draft.Previews)
+
+	// Approving publishes the stored post and marks the draft as published
+	mockRepository.On("GetDraft", mock.Anything, draft.ID).Return(draft, nil).Once()
+	mockSocialMediaAdapter.On("PublishImagePost", mock.Anything, storedPost).Return(domain.PublishedPost{ID: "1"}, nil).Once()
+	mockRepository.On("SaveDraft", mock.Anything, mock.MatchedBy(func(d domain.Draft) bool {
+		return d.ID == draft.ID && d.Status == domain.DraftStatusPublished
+	})).Return(nil).Once()
+
+	result, err := srv.ApproveDraft(context.Background(), draft.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.PublishResult{{AdapterName: "Twitter", AdapterID: "Twitter", Status: domain.PublishStatusPublished, PostID: "1"}}, result.Publications)
+
+	// A draft can only be approved or rejected once
+	draft.Status = domain.DraftStatusPublished
+	mockRepository.On("GetDraft", mock.Anything, draft.ID).Return(draft, nil).Once()
+
+	err = srv.RejectDraft(context.Background(), draft.ID)
+	assert.EqualError(t, err, fmt.Sprintf("draft %s is already published", draft.ID))
+}
+
+func TestService_ApproveDraftRetriesFailedPublications(t *testing.T) {
+	mockTwitterAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockMastodonAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockRepository := ports.NewMockRepositoryAdapter(t)
+	mockTwitterAdapter.On("GetName").Return("Twitter")
+	mockMastodonAdapter.On("GetName").Return("Mastodon")
+
+	post := domain.Post{NewsArticle: domain.NewsArticle{Title: "Test Article"}}
+	draft := domain.Draft{ID: "draft", Status: domain.DraftStatusPending, Post: post}
+
+	srv := NewNewsContentService(
+		logger.NewTestLogger(),
+		ports.NewMockNewsAdapter(t),
+		ports.NewMockLLMAdapter(t),
+		ports.NewMockImageGenerationAdapter(t),
+		[]ports.SocialMediaAdapter{mockTwitterAdapter, mockMastodonAdapter},
+		WithRepositoryAdapter(mockRepository),
+	)
+
+	// Nothing was published, so the draft stays pending
+	mockRepository.On("GetDraft", mock.Anything, "draft").Return(draft, nil).Once()
+	mockTwitterAdapter.On("PublishImagePost", mock.Anything, post).Return(domain.PublishedPost{}, errors.New("outage")).Once()
+	mockMastodonAdapter.On("PublishImagePost", mock.Anything, post).Return(domain.PublishedPost{}, errors.New("outage")).Once()
+	mockRepository.On("SaveDraft", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		draft = args.Get(1).(domain.Draft)
+	}).Return(nil)
+
+	_, err := srv.ApproveDraft(context.Background(), "draft")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DraftStatusPending, draft.Status)
+
+	// Only Mastodon recovers, the draft stays pending for Twitter
+	mockRepository.On("GetDraft", mock.Anything, "draft").Return(draft, nil).Once()
+	mockTwitterAdapter.On("PublishImagePost", mock.Anything, post).Return(domain.PublishedPost{}, errors.New("outage")).Once()
+	mockMastodonAdapter.On("PublishImagePost", mock.Anything, post).Return(domain.PublishedPost{ID: "2"}, nil).Once()
+
+	_, err = srv.ApproveDraft(context.Background(), "draft")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DraftStatusPending, draft.Status)
+
+	// Approving again only publishes to Twitter, which completes the draft
+	mockRepository.On("GetDraft", mock.Anything, "draft").Return(draft, nil).Once()
+	mockTwitterAdapter.On("PublishImagePost", mock.Anything, post).Return(domain.PublishedPost{ID: "1"}, nil).Once()
+
+	result, err := srv.ApproveDraft(context.Background(), "draft")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DraftStatusPublished, draft.Status)
+	assert.Equal(t, []domain.PublishResult{
+		{AdapterName: "Twitter", AdapterID: "Twitter", Status: domain.PublishStatusPublished, PostID: "1"},
+		{AdapterName: "Mastodon", AdapterID: "Mastodon", Status: domain.PublishStatusPublished, PostID: "2"},
+	}, result.Publications)
+}
+
+func TestService_ApproveDraftDoesNotRepublish(t *testing.T) {
+	mockTwitterAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockMastodonAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockBlueskyAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockRepository := ports.NewMockRepositoryAdapter(t)
+	mockTwitterAdapter.On("GetName").Return("Twitter")
+	mockMastodonAdapter.On("GetName").Return("Mastodon")
+	mockBlueskyAdapter.On("GetName").Return("Bluesky")
+
+	// Twitter posted the image but not its replies, and Mastodon may have published before it timed out, approving
+	// the draft again would post them twice
+	post := domain.Post{NewsArticle: domain.NewsArticle{Title: "Test Article"}}
+	draft := domain.Draft{ID: "draft", Status: domain.DraftStatusPending, Post: post, Publications: []domain.PublishResult{
+		{AdapterName: "Twitter", AdapterID: "Twitter", Status: domain.PublishStatusFailed, PostID: "1", Error: "reply failed"},
+		{AdapterName: "Mastodon", AdapterID: "Mastodon", Status: domain.PublishStatusTimedOut, Error: "context deadline exceeded"},
+		{AdapterName: "Bluesky", AdapterID: "Bluesky", Status: domain.PublishStatusFailed, Error: "outage"},
+	}}
+	mockRepository.On("GetDraft", mock.Anything, "draft").Return(draft, nil).Once()
+	mockBlueskyAdapter.On("PublishImagePost", mock.Anything, post).Return(domain.PublishedPost{ID: "3"}, nil).Once()
+	mockRepository.On("SaveDraft", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		draft = args.Get(1).(domain.Draft)
+	}).Return(nil)
+
+	srv := NewNewsContentService(
+		logger.NewTestLogger(),
+		ports.NewMockNewsAdapter(t),
+		ports.NewMockLLMAdapter(t),
+		ports.NewMockImageGenerationAdapter(t),
+		[]ports.SocialMediaAdapter{mockTwitterAdapter, mockMastodonAdapter, mockBlueskyAdapter},
+		WithRepositoryAdapter(mockRepository),
+	)
+
+	_, err := srv.ApproveDraft(context.Background(), "draft")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DraftStatusPublished, draft.Status)
+}
+
+func TestService_ApproveDraftWithTwoAccountsOfTheSameSocialMedia(t *testing.T) {
+	mockNewsAccount := ports.NewMockSocialMediaAdapter(t)
+	mockArtAccount := ports.NewMockSocialMediaAdapter(t)
+	mockRepository := ports.NewMockRepositoryAdapter(t)
+	mockArtAccount.On("GetName").Return("Mastodon")
+
+	// Only the art account failed, so approving the draft again only publishes to it
+	post := domain.Post{NewsArticle: domain.NewsArticle{Title: "Test Article"}}
+	draft := domain.Draft{ID: "draft", Status: domain.DraftStatusPending, Channel: "science", Post: post, Publications: []domain.PublishResult{
+		{AdapterName: "Mastodon", AdapterID: "mastodon", Status: domain.PublishStatusPublished, PostID: "1"},
+		{AdapterName: "Mastodon", AdapterID: "mastodon-art", Status: domain.PublishStatusFailed, Error: "outage"},
+	}}
+	mockRepository.On("GetDraft", mock.Anything, "draft").Return(draft, nil).Once()
+	mockArtAccount.On("PublishImagePost", mock.Anything, post).Return(domain.PublishedPost{ID: "2"}, nil).Once()
+	mockRepository.On("SaveDraft", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		draft = args.Get(1).(domain.Draft)
+	}).Return(nil)
+
+	srv := NewNewsContentService(
+		logger.NewTestLogger(),
+		ports.NewMockNewsAdapter(t),
+		ports.NewMockLLMAdapter(t),
+		ports.NewMockImageGenerationAdapter(t),
+		nil,
+		WithRepositoryAdapter(mockRepository),
+		WithChannel(domain.Channel{Name: "science"}, []ports.SocialMediaAdapter{
+			ConfiguredAdapter{SocialMediaAdapter: mockNewsAccount, ID: "mastodon"},
+			ConfiguredAdapter{SocialMediaAdapter: mockArtAccount, ID: "mastodon-art"},
+		}),
+	)
+
+	_, err := srv.ApproveDraft(context.Background(), "draft")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DraftStatusPublished, draft.Status)
+	assert.Equal(t, []domain.PublishResult{
+		{AdapterName: "Mastodon", AdapterID: "mastodon", Status: domain.PublishStatusPublished, PostID: "1"},
+		{AdapterName: "Mastodon", AdapterID: "mastodon-art", Status: domain.PublishStatusPublished, PostID: "2"},
+	}, draft.Publications)
+}
+
+func TestService_ResumeRun(t *testing.T) {
+	mockNewsAdapter := ports.NewMockNewsAdapter(t)
+	llmAdapter := ports.NewMockLLMAdapter(t)
+	mockImageGenerationAdapter := ports.NewMockImageGenerationAdapter(t)
+	mockTwitterAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockInstagramAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockRepository := ports.NewMockRepositoryAdapter(t)
+
+	// The run timed out while publishing to twitter, before generating the second image and publishing to instagram
+	article := domain.NewsArticle{Title: "Test Article"}
+	run := domain.Run{
+		ID:             "run",
+		Status:         domain.RunStatusInProgress,
+		Options:        domain.GenerateOptions{ImageCount: 2},
+		ArticleFetched: true,
+		Article:        article,
+		Images: []domain.GeneratedImage{
+			{Path: "https://test.com/1.png", Prompt: "first prompt"},
+			{Prompt: "second prompt"},
+		},
+		Publications: []domain.PublishResult{
+			{AdapterName: "Twitter", AdapterID: "Twitter", Status: domain.PublishStatusPublished, PostID: "1"},
+		},
+	}
+	mockRepository.On("GetRun", mock.Anything, "run").Return(run, nil)
+	mockImageGenerationAdapter.On("GenerateImage", mock.Anything, "second prompt").Return(domain.ImagePath("https://test.com/2.png"), nil).Once()
+	mockImageGenerationAdapter.On("GetGeneratorName").Return("TestGenerator")
+	mockRepository.On("StoreImage", mock.Anything, mock.Anything).Return(func(_ context.Context, image domain.GeneratedImage) domain.GeneratedImage {
+		image.Path = domain.ImagePath(strings.Replace(string(image.Path), "https://test.com", "/data/images", 1))
+		return image
+	}, nil)
+	mockTwitterAdapter.On("GetName").Return("Twitter")
+	mockInstagramAdapter.On("GetName").Return("Instagram")
+	mockInstagramAdapter.On("PublishImagePost", mock.Anything, domain.Post{
+		NewsArticle: article,
+		Images: []domain.GeneratedImage{
+			{Path: "/data/images/1.png", Prompt: "first prompt"},
+			{Path: "/data/images/2.png", Prompt: "second prompt"},
+		},
+		ImageGeneratorName: "TestGenerator",
+	}).Return(domain.PublishedPost{ID: "2"}, nil).Once()
+
+	var lastCheckpoint domain.Run
+	mockRepository.On("SaveRun", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		lastCheckpoint = args.Get(1).(domain.Run)
+	}).Return(nil)
+
+	srv := NewNewsContentService(
+		logger.NewTestLogger(),
+		mockNewsAdapter,
+		llmAdapter,
+		mockImageGenerationAdapter,
+		[]ports.SocialMediaAdapter{mockTwitterAdapter, mockInstagramAdapter},
+		WithRepositoryAdapter(mockRepository),
+	)
+
+	result, err := srv.ResumeRun(context.Background(), "run")
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.PublishResult{
+		{AdapterName: "Twitter", AdapterID: "Twitter", Status: domain.PublishStatusPublished, PostID: "1"},
+		{AdapterName: "Instagram", AdapterID: "Instagram", Status: domain.PublishStatusPublished, PostID: "2"},
+	}, result.Publications)
+	assert.Equal(t, domain.RunStatusCompleted, lastCheckpoint.Status)
+	assert.False(t, result.Resumable)
+
+	// A run that fails again stays resumable
+	mockRepository.On("GetRun", mock.Anything, "failing").Return(domain.Run{
+		ID:             "failing",
+		Status:         domain.RunStatusInProgress,
+		ArticleFetched: true,
+		Article:        article,
+		Images:         []domain.GeneratedImage{{Prompt: "failing prompt"}},
+	}, nil)
+	mockImageGenerationAdapter.On("GenerateImage", mock.Anything, "failing prompt").Return(domain.ImagePath(""), errors.New("image error")).Once()
+	result, err = srv.ResumeRun(context.Background(), "failing")
+	assert.EqualError(t, err, "image error")
+	assert.True(t, result.Resumable)
+
+	// A run is completed once it tried to publish, the social medias that failed are not retried
+	mockRepository.On("GetRun", mock.Anything, "unpublished").Return(domain.Run{
+		ID:             "unpublished",
+		Status:         domain.RunStatusInProgress,
+		ArticleFetched: true,
+		Article:        article,
+		Images:         []domain.GeneratedImage{{Path: "/data/images/1.png", Prompt: "first prompt"}},
+	}, nil)
+	mockTwitterAdapter.On("PublishImagePost", mock.Anything, mock.Anything).Return(domain.PublishedPost{}, errors.New("twitter error")).Once()
+	mockInstagramAdapter.On("PublishImagePost", mock.Anything, mock.Anything).Return(domain.PublishedPost{ID: "3"}, nil).Once()
+	result, err = srv.ResumeRun(context.Background(), "unpublished")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.RunStatusCompleted, lastCheckpoint.Status)
+	assert.False(t, result.Resumable)
+
+	// Completed runs can not be resumed
+	mockRepository.On("GetRun", mock.Anything, "completed").Return(domain.Run{ID: "completed", Status: domain.RunStatusCompleted}, nil)
+	_, err = srv.ResumeRun(context.Background(), "completed")
+	assert.EqualError(t, err, "run completed is already completed")
+}
+
+func TestLatestRun(t *testing.T) {
+	runs := []domain.Run{
+		{ID: "older", Channel: "news"},
+		{ID: "article", Channel: "news", Options: domain.GenerateOptions{ArticleURL: "https://example.com/bees"}},
+		{ID: "science", Channel: "science"},
+	}
+	assert.Equal(t, "older", latestRun(runs, "news", "").ID)
+	assert.Equal(t, "article", latestRun(runs, "news", "https://example.com/bees").ID)
+	// A run never wins over an article that was asked for
+	assert.Nil(t, latestRun(runs, "news", "https://example.com/wasps"))
+	assert.Nil(t, latestRun(runs, "sports", ""))
+}
+
+func TestService_PublishIsolation(t *testing.T) {
+	mockNewsAdapter := ports.NewMockNewsAdapter(t)
+	llmAdapter := ports.NewMockLLMAdapter(t)
+	mockImageGenerationAdapter := ports.NewMockImageGenerationAdapter(t)
+	mockTwitterAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockInstagramAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockMastodonAdapter := ports.NewMockSocialMediaAdapter(t)
+
+	mockNewsAdapter.On("GetMainArticle", mock.Anything).Return(domain.NewsArticle{Title: "Test Article"}, nil)
+	llmAdapter.On("Chat", mock.Anything, mock.Anything).Return("Test Image Prompt", nil)
+	mockImageGenerationAdapter.On("GenerateImage", mock.Anything, mock.Anything).Return(domain.ImagePath("Test Image Path"), nil)
+	mockImageGenerationAdapter.On("GetGeneratorName").Return("TestGenerator")
+
+	mockTwitterAdapter.On("GetName").Return("Twitter")
+	mockTwitterAdapter.On("PublishImagePost", mock.Anything, mock.Anything).Return(domain.PublishedPost{ID: "1"}, nil)
+
+	// Instagram hangs without respecting the context, like a login that never returns
+	release := make(chan struct{})
+	defer close(release)
+	mockInstagramAdapter.On("GetName").Return("Instagram")
+	mockInstagramAdapter.On("PublishImagePost", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		<-release
+	}).Return(domain.PublishedPost{}, nil)
+
+	mockMastodonAdapter.On("GetName").Return("Mastodon")
+	mockMastodonAdapter.On("PublishImagePost", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		panic("boom")
+	}).Return(domain.PublishedPost{}, nil)
+
+	srv := NewNewsContentService(
+		logger.NewTestLogger(),
+		mockNewsAdapter,
+		llmAdapter,
+		mockImageGenerationAdapter,
+		[]ports.SocialMediaAdapter{
+			mockTwitterAdapter,
+			ConfiguredAdapter{SocialMediaAdapter: mockInstagramAdapter, PublishTimeout: 10 * time.Millisecond},
+			mockMastodonAdapter,
+		},
+		WithPublishTimeout(time.Minute),
+	)
+
+	results, err := srv.GenerateNewsContent(context.Background(), domain.GenerateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.PublishResult{
+		{AdapterName: "Twitter", AdapterID: "Twitter", Status: domain.PublishStatusPublished, PostID: "1"},
+		{AdapterName: "Instagram", AdapterID: "Instagram", Status: domain.PublishStatusTimedOut, Error: "context deadline exceeded"},
+		{AdapterName: "Mastodon", AdapterID: "Mastodon", Status: domain.PublishStatusFailed, Error: "adapter panicked: boom"},
+	}, publications(results))
+}
+
+func TestService_Channels(t *testing.T) {
+	mockNewsAdapter := ports.NewMockNewsAdapter(t)
+	llmAdapter := ports.NewMockLLMAdapter(t)
+	mockImageGenerationAdapter := ports.NewMockImageGenerationAdapter(t)
+	mockScienceAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockWorldAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockSportsAdapter := ports.NewMockSocialMediaAdapter(t)
+
+	scienceArticle := domain.NewsArticle{Title: "Comet spotted", Section: "Science"}
+	worldArticle := domain.NewsArticle{Title: "Summit held", Section: "world"}
+	mockNewsAdapter.On("GetTopArticles", mock.Anything).Return([]domain.NewsArticle{
+		worldArticle,
+		{Title: "Markets rally", Section: "business"},
+		scienceArticle,
+	}, nil).Once()
+
+	// Each channel generates its image in its own style for its own article
+	llmAdapter.On("Chat", mock.Anything, mock.MatchedBy(func(request string) bool {
+		return strings.Contains(request, "Comet spotted") && strings.HasSuffix(request, "style of: watercolor painting")
+	})).Return("comet prompt", nil).Once()
+	llmAdapter.On("Chat", mock.Anything, mock.MatchedBy(func(request string) bool {
+		return strings.Contains(request, "Summit held") && strings.HasSuffix(request, "style of: photograph")
+	})).Return("summit prompt", nil).Once()
+	mockImageGenerationAdapter.On("GenerateImage", mock.Anything, "comet prompt").Return(domain.ImagePath("comet.png"), nil).Once()
+	mockImageGenerationAdapter.On("GenerateImage", mock.Anything, "summit prompt").Return(domain.ImagePath("summit.png"), nil).Once()
+	mockImageGenerationAdapter.On("GetGeneratorName").Return("TestGenerator")
+
+	mockScienceAdapter.On("GetName").Return("Mastodon")
+	mockScienceAdapter.On("PublishImagePost", mock.Anything, mock.MatchedBy(func(post domain.Post) bool {
+		return post.NewsArticle == scienceArticle
+	})).Return(domain.PublishedPost{ID: "1"}, nil).Once()
+	mockWorldAdapter.On("GetName").Return("Mastodon")
+	mockWorldAdapter.On("PublishImagePost", mock.Anything, mock.MatchedBy(func(post domain.Post) bool {
+		return post.NewsArticle == worldArticle
+	})).Return(domain.PublishedPost{ID: "2"}, nil).Once()
+
+	srv := NewNewsContentService(
+		logger.NewTestLogger(),
+		mockNewsAdapter,
+		llmAdapter,
+		mockImageGenerationAdapter,
+		nil,
+		WithChannel(domain.Channel{Name: "science", PromptStyle: "watercolor painting", Topics: []string{"science", "climate"}}, []ports.SocialMediaAdapter{mockScienceAdapter}),
+		WithChannel(domain.Channel{Name: "world", PromptStyle: "photograph", Topics: []string{"world"}}, []ports.SocialMediaAdapter{mockWorldAdapter}),
+		// None of the top articles is about sports, so the channel is skipped
+		WithChannel(domain.Channel{Name: "sports", Topics: []string{"sports"}}, []ports.SocialMediaAdapter{mockSportsAdapter}),
+	)
+
+	results, err := srv.GenerateNewsContent(context.Background(), domain.GenerateOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "science", results[0].Channel)
+	assert.Equal(t, []domain.PublishResult{{AdapterName: "Mastodon", AdapterID: "Mastodon", Status: domain.PublishStatusPublished, PostID: "1"}}, results[0].Publications)
+	assert.Equal(t, "world", results[1].Channel)
+	assert.Equal(t, []domain.PublishResult{{AdapterName: "Mastodon", AdapterID: "Mastodon", Status: domain.PublishStatusPublished, PostID: "2"}}, results[1].Publications)
+
+	_, err = srv.GenerateNewsContent(context.Background(), domain.GenerateOptions{Channels: []string{"politics"}})
+	assert.EqualError(t, err, `invalid options: unknown channel "politics"`)
+}
+
+func TestService_TopicRouting(t *testing.T) {
+	mockNewsAdapter := ports.NewMockNewsAdapter(t)
+	llmAdapter := ports.NewMockLLMAdapter(t)
+	mockImageGenerationAdapter := ports.NewMockImageGenerationAdapter(t)
+	mockTechAdapter := ports.NewMockSocialMediaAdapter(t)
+	mockMainAdapter := ports.NewMockSocialMediaAdapter(t)
+
+	mainArticle := domain.NewsArticle{Title: "Chip shortage ends", Section: "business"}
+	mockNewsAdapter.On("GetMainArticle", mock.Anything).Return(mainArticle, nil).Once()
+	mockNewsAdapter.On("GetTopArticles", mock.Anything).Return([]domain.NewsArticle{
+		{Title: "Cup final tonight", Section: "sports"},
+		mainArticle,
+	}, nil).Once()
+
+	// The main article is only classified once, although both channels need its topic
+	isClassification := func(title string) any {
+		return mock.MatchedBy(func(request string) bool {
+			return strings.HasPrefix(request, "Classify the following news article into exactly one of these topics: technology, sports") &&
+				strings.Contains(request, title)
+		})
+	}
+	llmAdapter.On("Chat", mock.Anything, isClassification("Cup final tonight")).Return("Sports.", nil).Once()
+	llmAdapter.On("Chat", mock.Anything, isClassification("Chip shortage ends")).Return("technology", nil).Once()
+
+	// Both channels use the template and style of the technology topic
+	request := "Draw Chip shortage ends\n\n The image must be in the style of: cyberpunk"
+	llmAdapter.On("Chat", mock.Anything, request).Return("neon chips", nil).Twice()
+	mockImageGenerationAdapter.On("GenerateImage", mock.Anything, "neon chips").Return(domain.ImagePath("chips.png"), nil).Twice()
+	mockImageGenerationAdapter.On("GetGeneratorName").Return("TestGenerator")
+
+	mockTechAdapter.On("GetName").Return("Mastodon")
+	mockTechAdapter.On("PublishImagePost", mock.Anything, mock.Anything).Return(domain.PublishedPost{ID: "1"}, nil).Once()
+	mockMainAdapter.On("GetName").Return("Twitter")
+	mockMainAdapter.On("PublishImagePost", mock.Anything, mock.Anything).Return(domain.PublishedPost{ID: "2"}, nil).Once()
+
+	srv := NewNewsContentService(
+		logger.NewTestLogger(),
+		mockNewsAdapter,
+		llmAdapter,
+		mockImageGenerationAdapter,
+		[]ports.SocialMediaAdapter{mockMainAdapter},
+		WithTopics(domain.TopicClassifierLLM, []domain.Topic{
+			{Name: "technology", PromptStyle: "cyberpunk", PromptTemplate: "Draw {{.Title}}"},
+			{Name: "sports"},
+		}),
+		WithChannel(domain.Channel{Name: "tech", PromptStyle: "watercolor painting", Topics: []string{"technology"}}, []ports.SocialMediaAdapter{mockTechAdapter}),
+	)
+
+	results, err := srv.GenerateNewsContent(context.Background(), domain.GenerateOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, []domain.PublishResult{
+		{AdapterName: "Twitter", AdapterID: "Twitter", Status: domain.PublishStatusPublished, PostID: "2"},
+		{AdapterName: "Mastodon", AdapterID: "Mastodon", Status: domain.PublishStatusPublished, PostID: "1"},
+	}, publications(results))
+}
+
+func TestService_ContentPolicy(t *testing.T) {
+	mockNewsAdapter := ports.NewMockNewsAdapter(t)
+	llmAdapter := ports.NewMockLLMAdapter(t)
+	mockImageGenerationAdapter := ports.NewMockImageGenerationAdapter(t)
+	mockSocialMediaAdapter := ports.NewMockSocialMediaAdapter(t)
+
+	policy := domain.NewContentPolicy([]domain.SensitiveCategory{
+		{Name: "violence", Action: domain.PolicyActionSkip, Keywords: []string{"shooting"}},
+		{Name: "disaster", Action: domain.PolicyActionNeutral, Keywords: []string{"earthquake"}},
+	}, true, "neutral")
+	srv := NewNewsContentService(
+		logger.NewTestLogger(),
+		mockNewsAdapter,
+		llmAdapter,
+		mockImageGenerationAdapter,
+		[]ports.SocialMediaAdapter{mockSocialMediaAdapter},
+		WithContentPolicy(policy),
+	)
+	isSensitivityRequest := mock.MatchedBy(func(request string) bool {
+		return strings.HasPrefix(request, "Decide if the following news article is about one of these sensitive categories: violence, disaster")
+	})
+
+	// Articles matching a keyword of a skipped category are not generated or published
+	mockNewsAdapter.On("GetMainArticle", mock.Anything).Return(domain.NewsArticle{Title: "Shooting downtown"}, nil).Once()
+	results, err := srv.GenerateNewsContent(context.Background(), domain.GenerateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PolicyDecision{Action: domain.PolicyActionSkip, Category: "violence", Reason: `keyword "shooting"`}, results[0].ContentPolicy)
+	assert.Empty(t, results[0].Publications)
+
+	// The LLM is asked when no keyword matches, neutral categories replace the requested style
+	mockNewsAdapter.On("GetMainArticle", mock.Anything).Return(domain.NewsArticle{Title: "Tremors felt in the capital"}, nil).Once()
+	llmAdapter.On("Chat", mock.Anything, isSensitivityRequest).Return("Disaster", nil).Once()
+	llmAdapter.On("Chat", mock.Anything, mock.MatchedBy(func(request string) bool {
+		return strings.HasSuffix(request, "style of: neutral")
+	})).Return("calm prompt", nil).Once()
+	mockImageGenerationAdapter.On("GenerateImage", mock.Anything, "calm prompt").Return(domain.ImagePath("calm.png"), nil).Once()
+	mockImageGenerationAdapter.On("GetGeneratorName").Return("TestGenerator")
+	mockSocialMediaAdapter.On("GetName").Return("Twitter")
+	mockSocialMediaAdapter.On("PublishImagePost", mock.Anything, mock.Anything).Return(domain.PublishedPost{ID: "1"}, nil).Once()
+
+	results, err = srv.GenerateNewsContent(context.Background(), domain.GenerateOptions{ImageStyles: []string{"cartoon"}})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PolicyDecision{Action: domain.PolicyActionNeutral, Category: "disaster", Reason: "classified by the LLM"}, results[0].ContentPolicy)
+	assert.Equal(t, []domain.PublishResult{{AdapterName: "Twitter", AdapterID: "Twitter", Status: domain.PublishStatusPublished, PostID: "1"}}, results[0].Publications)
+}
+
+func TestService_ArticleURLAndPrompt(t *testing.T) {
+	mockNewsAdapter := ports.NewMockNewsAdapter(t)
+	mockArticleAdapter := ports.NewMockArticleAdapter(t)
+	llmAdapter := ports.NewMockLLMAdapter(t)
+	mockImageGenerationAdapter := ports.NewMockImageGenerationAdapter(t)
+	mockScienceAdapter := ports.NewMockSocialMediaAdapter(t)
+
+	// The requested article is published by the channel whatever its topics, with the prompt instead of one from the LLM
+	article := domain.NewsArticle{Title: "Local bakery wins award", Section: "food"}
+	mockArticleAdapter.On("GetArticle", mock.Anything, "https://example.com/bakery").Return(article, nil).Once()
+	mockImageGenerationAdapter.On("GenerateImage", mock.Anything, "a golden loaf of bread").Return(domain.ImagePath("bread.png"), nil).Twice()
+	mockImageGenerationAdapter.On("GetGeneratorName").Return("TestGenerator")
+	mockScienceAdapter.On("GetName").Return("Mastodon")
+	mockScienceAdapter.On("PublishImagePost", mock.Anything, domain.Post{
+		NewsArticle: article,
+		Images: []domain.GeneratedImage{
+			{Path: "bread.png", Prompt: "a golden loaf of bread"},
+			{Path: "bread.png", Prompt: "a golden loaf of bread"},
+		},
+		ImageGeneratorName: "TestGenerator",
+	}).Return(domain.PublishedPost{ID: "1"}, nil).Once()
+
+	srv := NewNewsContentService(
+		logger.NewTestLogger(),
+		mockNewsAdapter,
+		llmAdapter,
+		mockImageGenerationAdapter,
+		nil,
+		WithArticleAdapter(mockArticleAdapter),
+		WithChannel(domain.Channel{Name: "science", Topics: []string{"science"}}, []ports.SocialMediaAdapter{mockScienceAdapter}),
+	)
+
+	results, err := srv.GenerateNewsContent(context.Background(), domain.GenerateOptions{
+		ImageCount: 2,
+		ArticleURL: "https://example.com/bakery",
+		Prompt:     "a golden loaf of bread",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.PublishResult{{AdapterName: "Mastodon", AdapterID: "Mastodon", Status: domain.PublishStatusPublished, PostID: "1"}}, publications(results))
+}
+
+func TestService_GenerateArticleContent(t *testing.T) {
+	mockNewsAdapter := ports.NewMockNewsAdapter(t)
+	llmAdapter := ports.NewMockLLMAdapter(t)
+	mockImageGenerationAdapter := ports.NewMockImageGenerationAdapter(t)
+	mockScienceAdapter := ports.NewMockSocialMediaAdapter(t)
+
+	// The given article is published by the channel whatever its topics, without fetching the news
+	article := domain.NewsArticle{Title: "Our garden in spring", Body: "The tulips are out.", Source: "My blog"}
+	llmAdapter.On("Chat", mock.Anything, mock.MatchedBy(func(request string) bool {
+		return strings.Contains(request, "Our garden in spring")
+	})).Return("tulips in a garden", nil).Once()
+	mockImageGenerationAdapter.On("GenerateImage", mock.Anything, "tulips in a garden").Return(domain.ImagePath("tulips.png"), nil).Once()
+	mockImageGenerationAdapter.On("GetGeneratorName").Return("TestGenerator")
+	mockScienceAdapter.On("GetName").Return("Mastodon")
+	mockScienceAdapter.On("PublishImagePost", mock.Anything, domain.Post{
+		NewsArticle:        article,
+		Images:             []domain.GeneratedImage{{Path: "tulips.png", Prompt: "tulips in a garden"}},
+		ImageGeneratorName: "TestGenerator",
+	}).Return(domain.PublishedPost{ID: "1"}, nil).Once()
+
+	srv := NewNewsContentService(
+		logger.NewTestLogger(),
+		mockNewsAdapter,
+		llmAdapter,
+		mockImageGenerationAdapter,
+		nil,
+		WithChannel(domain.Channel{Name: "science", Topics: []string{"science"}}, []ports.SocialMediaAdapter{mockScienceAdapter}),
+	)
+
+	results, err := srv.GenerateArticleContent(context.Background(), article, domain.GenerateOptions{ImageCount: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.PublishResult{{AdapterName: "Mastodon", AdapterID: "Mastodon", Status: domain.PublishStatusPublished, PostID: "1"}}, publications(results))
+
+	_, err = srv.GenerateArticleContent(context.Background(), domain.NewsArticle{Body: "No title"}, domain.GenerateOptions{})
+	assert.EqualError(t, err, "invalid options: the article has no title")
+	_, err = srv.GenerateArticleContent(context.Background(), article, domain.GenerateOptions{Resume: true})
+	assert.EqualError(t, err, "invalid options: runs of a given article cannot be resumed, resume the run by its id instead")
+}
+
+func TestService_ArticleEnrichment(t *testing.T) {
+	mockNewsAdapter := ports.NewMockNewsAdapter(t)
+	mockArticleAdapter := ports.NewMockArticleAdapter(t)
+	llmAdapter := ports.NewMockLLMAdapter(t)
+	mockImageGenerationAdapter := ports.NewMockImageGenerationAdapter(t)
+	mockSocialMediaAdapter := ports.NewMockSocialMediaAdapter(t)
+
+	// The story replaces the abstract of the news and is summarized, since it is longer than the maximum length
+	abstract := domain.NewsArticle{Title: "Bees return", Body: "Bees are back.", Url: "https://example.com/bees"}
+	story := "The bees have returned to the valley, years after they disappeared. Farmers expect the harvest to recover."
+	mockNewsAdapter.On("GetMainArticle", mock.Anything).Return(abstract, nil).Once()
+	mockArticleAdapter.On("GetArticle", mock.Anything, "https://example.com/bees").Return(domain.NewsArticle{Title: "Bees return", Body: story}, nil).Once()
+	llmAdapter.On("Chat", mock.Anything, mock.MatchedBy(func(request string) bool {
+		return strings.HasPrefix(request, "Summarize the following news article in at most 60 characters") && strings.Contains(request, story)
+	})).Return("Bees returned to the valley after years.", nil).Once()
+	enriched := domain.NewsArticle{Title: "Bees return", Body: "Bees returned to the valley after years.", Url: "https://example.com/bees"}
+	llmAdapter.On("Chat", mock.Anything, mock.MatchedBy(func(request string) bool {
+		return strings.Contains(request, "Bees returned to the valley after years.")
+	})).Return("bees in a valley", nil).Once()
+	mockImageGenerationAdapter.On("GenerateImage", mock.Anything, "bees in a valley").Return(domain.ImagePath("bees.png"), nil).Once()
+	mockImageGenerationAdapter.On("GetGeneratorName").Return("TestGenerator")
+	mockSocialMediaAdapter.On("GetName").Return("Mastodon")
+	mockSocialMediaAdapter.On("PublishImagePost", mock.Anything, domain.Post{
+		NewsArticle:        enriched,
+		Images:             []domain.GeneratedImage{{Path: "bees.png", Prompt: "bees in a valley"}},
+		ImageGeneratorName: "TestGenerator",
+	}).Return(domain.PublishedPost{ID: "1"}, nil).Once()
+
+	srv := NewNewsContentService(
+		logger.NewTestLogger(),
+		mockNewsAdapter,
+		llmAdapter,
+		mockImageGenerationAdapter,
+		[]ports.SocialMediaAdapter{mockSocialMediaAdapter},
+		WithArticleAdapter(mockArticleAdapter),
+		WithArticleEnrichment(60),
+	)
+
+	results, err := srv.GenerateNewsContent(context.Background(), domain.GenerateOptions{ImageCount: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.PublishResult{{AdapterName: "Mastodon", AdapterID: "Mastodon", Status: domain.PublishStatusPublished, PostID: "1"}}, publications(results))
+}
+
+func TestTruncateText(t *testing.T) {
+	assert.Equal(t, "Short.", truncateText("Short.", 10))
+	assert.Equal(t, "The bees are back.", truncateText("The bees are back. Farmers are happy.", 25))
+	assert.Equal(t, "The bees are back in", truncateText("The bees are back in the valley", 22))
+	assert.Equal(t, "Bijenvolk", truncateText("Bijenvolkeren", 9))
+}
+
+// publications returns the publications of the runs of every channel
+func publications(results []domain.RunResult) []domain.PublishResult {
+	var publications []domain.PublishResult
+	for _, result := range results {
+		publications = append(publications, result.Publications...)
+	}
+	return publications
+}
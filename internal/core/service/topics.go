@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+)
+
+// classify returns the topic of the article. The section classifier falls back to the section of the article when no
+// topic has it, so channels can list sections as their topics without defining topics.
+func (srv *service) classify(ctx context.Context, article domain.NewsArticle) (string, error) {
+	if srv.topicClassifier == domain.TopicClassifierLLM && len(srv.topics) > 0 {
+		answer, err := srv.llmAdapter.Chat(ctx, createClassificationRequest(article, srv.topics))
+		if err != nil {
+			srv.logger.Error("Error when classifying article", "error", err)
+			return "", err
+		}
+		answer = strings.Trim(strings.TrimSpace(answer), `."'`)
+		for _, topic := range srv.topics {
+			if strings.EqualFold(topic.Name, answer) {
+				srv.logger.Debug("Classified article", "title", article.Title, "topic", topic.Name)
+				return topic.Name, nil
+			}
+		}
+		srv.logger.Debug("Article is not about any of the topics", "title", article.Title, "answer", answer)
+		return "", nil
+	}
+
+	for _, topic := range srv.topics {
+		if topic.HasSection(article.Section) {
+			return topic.Name, nil
+		}
+	}
+	return article.Section, nil
+}
+
+// getTopic returns the topic with the name, or an empty topic when it is not defined
+func (srv *service) getTopic(name string) domain.Topic {
+	for _, topic := range srv.topics {
+		if strings.EqualFold(topic.Name, name) {
+			return topic
+		}
+	}
+	return domain.Topic{}
+}
+
+// createClassificationRequest asks the LLM which of the topics the article is about
+func createClassificationRequest(article domain.NewsArticle, topics []domain.Topic) string {
+	names := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		names = append(names, topic.Name)
+	}
+	return fmt.Sprintf("Classify the following news article into exactly one of these topics: %s"+
+		"\nTitle: %s"+
+		"\nBody: %s"+
+		"\n\n Answer with only the name of the topic, or none when the article is not about any of them.",
+		strings.Join(names, ", "), article.Title, article.Body)
+}
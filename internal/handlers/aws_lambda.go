@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/BaronBonet/content-generator/internal/core/domain"
 	"github.com/BaronBonet/content-generator/internal/core/ports"
 	"github.com/BaronBonet/go-logger/logger"
 )
@@ -16,9 +18,63 @@ func NewAWSLambdaEventHandler(logger logger.Logger, srv ports.Service) *AWSLambd
 	return &AWSLambdaEventHandler{logger: logger, srv: srv}
 }
 
-func (handler *AWSLambdaEventHandler) HandleEvent(ctx context.Context, request interface{}) {
-	err := handler.srv.GenerateNewsContent(ctx)
+// LambdaEvent is the payload the lambda is invoked with, fields that are not set use the defaults.
+// Scheduled EventBridge events do not contain any of these fields.
+type LambdaEvent struct {
+	// DryRun logs what would be published instead of publishing it
+	DryRun bool `json:"dryRun"`
+	// RequireApproval stores the post as a draft instead of publishing it
+	RequireApproval bool `json:"requireApproval"`
+	// Resume continues the most recent incomplete run, e.g. one where the lambda timed out, instead of starting a new one
+	Resume bool `json:"resume"`
+	// ArticleURL generates content for the article at the url instead of the main article
+	ArticleURL string `json:"articleUrl"`
+	// Style is the style the image is generated in, e.g. "watercolor painting"
+	Style string `json:"style"`
+	// Channels only generates content for the channels with the names, instead of for every channel
+	Channels []string `json:"channels"`
+	// Prompt is used as the prompt of the image instead of asking the LLM for one
+	Prompt string `json:"prompt"`
+	// FailurePolicy decides if failing to publish to social media fails the invocation, see domain.FailurePolicy
+	FailurePolicy string `json:"failurePolicy"`
+}
+
+// LambdaResponse is the result of an invocation, e.g. the output of a Step Functions task
+type LambdaResponse struct {
+	// Runs contains the run of every channel
+	Runs []runResponse `json:"runs"`
+}
+
+// HandleEvent generates news content with the options of the event. An error fails the invocation, so it is retried
+// by asynchronous invocations and fails the task of a Step Functions state machine.
+func (handler *AWSLambdaEventHandler) HandleEvent(ctx context.Context, event LambdaEvent) (LambdaResponse, error) {
+	failurePolicy, err := domain.ParseFailurePolicy(event.FailurePolicy)
+	if err != nil {
+		return LambdaResponse{}, fmt.Errorf("invalid event: %w", err)
+	}
+	opts := domain.GenerateOptions{
+		DryRun:          event.DryRun,
+		RequireApproval: event.RequireApproval,
+		Resume:          event.Resume,
+		Channels:        event.Channels,
+		ArticleURL:      event.ArticleURL,
+		Prompt:          event.Prompt,
+	}
+	if event.Style != "" {
+		opts.ImageStyles = []string{event.Style}
+	}
+
+	results, err := handler.srv.GenerateNewsContent(ctx, opts)
+	// The channels that succeeded are logged before failing for the others
+	logRunResults(handler.logger, results)
+	response := LambdaResponse{Runs: newRunResponses(results)}
 	if err != nil {
-		handler.logger.Fatal("Error while generating news content.", "error", err)
+		handler.logger.Error("Error while generating news content.", "error", err)
+		return response, fmt.Errorf("error while generating news content: %w", err)
+	}
+	if err := runResultsErr(results, failurePolicy); err != nil {
+		handler.logger.Error("Error while publishing news content.", "error", err)
+		return response, fmt.Errorf("error while publishing news content: %w", err)
 	}
+	return response, nil
 }
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAWSLambdaEventHandler_HandleEvent(t *testing.T) {
+	mockService := ports.NewMockService(t)
+	handler := NewAWSLambdaEventHandler(logger.NewTestLogger(), mockService)
+	failed := domain.RunResult{
+		ID:           "run",
+		Publications: []domain.PublishResult{{AdapterName: "Twitter", Status: domain.PublishStatusFailed, Error: "rate limited"}},
+	}
+
+	mockService.On("GenerateNewsContent", mock.Anything, domain.GenerateOptions{
+		ImageStyles: []string{"watercolor painting"},
+		Channels:    []string{"science"},
+		ArticleURL:  "https://example.com/comet",
+		Prompt:      "a comet",
+	}).Return([]domain.RunResult{failed}, nil).Twice()
+
+	event := LambdaEvent{Style: "watercolor painting", Channels: []string{"science"}, ArticleURL: "https://example.com/comet", Prompt: "a comet"}
+	response, err := handler.HandleEvent(context.Background(), event)
+	assert.NoError(t, err)
+	assert.Equal(t, []publicationResponse{{Adapter: "Twitter", Status: "failed", Error: "rate limited"}}, response.Runs[0].Publications)
+
+	// The failure policy decides if failed publications fail the invocation
+	event.FailurePolicy = string(domain.FailurePolicyAny)
+	response, err = handler.HandleEvent(context.Background(), event)
+	assert.EqualError(t, err, "error while publishing news content: publishing failed for 1 of 1 social medias: Twitter: rate limited")
+	assert.Len(t, response.Runs, 1)
+
+	mockService.On("GenerateNewsContent", mock.Anything, domain.GenerateOptions{}).Return(nil, errors.New("news error")).Once()
+	_, err = handler.HandleEvent(context.Background(), LambdaEvent{})
+	assert.EqualError(t, err, "error while generating news content: news error")
+
+	_, err = handler.HandleEvent(context.Background(), LambdaEvent{FailurePolicy: "sometimes"})
+	assert.ErrorContains(t, err, "invalid event: invalid failure policy")
+}
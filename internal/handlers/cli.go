@@ -2,10 +2,19 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
 	"sort"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/BaronBonet/content-generator/internal/core/domain"
 	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/content-generator/internal/infrastructure"
 	"github.com/BaronBonet/go-logger/logger"
 	"github.com/urfave/cli/v2"
 )
@@ -23,11 +32,232 @@ func NewCLIHandler(ctx context.Context, service ports.Service, logger logger.Log
 			{
 				Name:  "generateNewsContent",
 				Usage: "Run the news content generation process",
+				Flags: append(generateOptionFlags(), failurePolicyFlag()),
 				Action: func(c *cli.Context) error {
-					err := service.GenerateNewsContent(ctx)
+					failurePolicy, err := domain.ParseFailurePolicy(c.String("fail-on"))
 					if err != nil {
 						return err
 					}
+					results, err := service.GenerateNewsContent(ctx, generateOptionsFromFlags(c))
+					logRunResults(logger, results)
+					if err != nil {
+						return err
+					}
+					return runResultsErr(results, failurePolicy)
+				},
+			},
+			{
+				Name:      "generateArticleContent",
+				Usage:     "Run the news content generation process for an article of your choice instead of the main article",
+				ArgsUsage: "<article url | file | - for stdin>",
+				Description: "An url is read like --article-url. The text of a file or stdin is the article itself, its first " +
+					"line is the title and the rest is the body.",
+				Flags: append(generateOptionFlags("resume", "article-url"), failurePolicyFlag(),
+					&cli.StringFlag{
+						Name:  "source",
+						Usage: "Name of the source published with an article read from a file or stdin, e.g. the name of your blog",
+					},
+				),
+				Action: func(c *cli.Context) error {
+					location := c.Args().Get(0)
+					if location == "" {
+						return errors.New("an article url, file or - for stdin is required")
+					}
+					failurePolicy, err := domain.ParseFailurePolicy(c.String("fail-on"))
+					if err != nil {
+						return err
+					}
+
+					opts := generateOptionsFromFlags(c)
+					var results []domain.RunResult
+					if isArticleURL(location) {
+						opts.ArticleURL = location
+						results, err = service.GenerateNewsContent(ctx, opts)
+					} else {
+						var article domain.NewsArticle
+						article, err = readArticle(c, location)
+						if err != nil {
+							return err
+						}
+						results, err = service.GenerateArticleContent(ctx, article, opts)
+					}
+					logRunResults(logger, results)
+					if err != nil {
+						return err
+					}
+					return runResultsErr(results, failurePolicy)
+				},
+			},
+			{
+				Name:      "resume",
+				Usage:     "Continue an incomplete run, only performing the stages that did not succeed yet",
+				ArgsUsage: "<run id>",
+				Flags:     []cli.Flag{failurePolicyFlag()},
+				Action: func(c *cli.Context) error {
+					id := c.Args().Get(0)
+					if id == "" {
+						return errors.New("a run id is required")
+					}
+					failurePolicy, err := domain.ParseFailurePolicy(c.String("fail-on"))
+					if err != nil {
+						return err
+					}
+					result, err := service.ResumeRun(ctx, id)
+					if err != nil {
+						return err
+					}
+					logRunResult(logger, result)
+					return result.Err(failurePolicy)
+				},
+			},
+			{
+				Name:  "serve",
+				Usage: "Keep running and generate news content on a cron schedule",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "schedule",
+						Value: "0 8,21 * * *",
+						Usage: "Cron expression with the fields minute, hour, day of month, month and day of week",
+					},
+					&cli.StringFlag{
+						Name:  "timezone",
+						Value: "UTC",
+						Usage: "IANA timezone the schedule is evaluated in, e.g. Europe/Amsterdam",
+					},
+					&cli.DurationFlag{
+						Name:  "jitter",
+						Usage: "Delay each run by a random duration up to this value, e.g. 10m",
+					},
+					&cli.StringFlag{
+						Name:  "catch-up",
+						Value: string(CatchUpSkip),
+						Usage: fmt.Sprintf("What to do with runs missed while not running, %q or %q to run once at startup", CatchUpSkip, CatchUpOnce),
+					},
+					&cli.StringFlag{
+						Name:  "state-file",
+						Value: "data/scheduler.json",
+						Usage: "File storing when the scheduler last ran, used to detect missed runs",
+					},
+				}, generateOptionFlags()...),
+				Action: func(c *cli.Context) error {
+					location, err := time.LoadLocation(c.String("timezone"))
+					if err != nil {
+						return fmt.Errorf("invalid timezone: %w", err)
+					}
+					schedule, err := infrastructure.ParseCronSchedule(c.String("schedule"), location)
+					if err != nil {
+						return err
+					}
+					catchUp := CatchUpPolicy(c.String("catch-up"))
+					if catchUp != CatchUpSkip && catchUp != CatchUpOnce {
+						return fmt.Errorf("invalid catch-up policy %q", catchUp)
+					}
+
+					ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+					defer stop()
+
+					return NewSchedulerHandler(logger, service, SchedulerConfig{
+						Schedule:  schedule,
+						Jitter:    c.Duration("jitter"),
+						CatchUp:   catchUp,
+						StateFile: c.String("state-file"),
+						Options:   generateOptionsFromFlags(c),
+					}).Run(ctx)
+				},
+			},
+			{
+				Name:  "http",
+				Usage: "Serve a JSON API for generating news content, prompts and images",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "addr",
+						Value:   "127.0.0.1:8080",
+						EnvVars: []string{"HTTP_ADDR"},
+						Usage:   "Address the API listens on, only this host can reach the default address",
+					},
+					&cli.StringFlag{
+						Name:    "token",
+						EnvVars: []string{"HTTP_API_TOKEN"},
+						Usage:   "Bearer token every request must have, prefer the environment variable so it is not visible in the process list",
+					},
+					&cli.DurationFlag{
+						Name:  "shutdown-timeout",
+						Value: 2 * time.Minute,
+						Usage: "How long to wait for requests in progress when stopping, generating content can take minutes",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+					defer stop()
+
+					if c.String("token") == "" {
+						return errors.New("a token is required, set HTTP_API_TOKEN or --token")
+					}
+					return NewHTTPHandler(logger, service, c.String("token")).Serve(ctx, c.String("addr"), c.Duration("shutdown-timeout"))
+				},
+			},
+			{
+				Name:  "drafts",
+				Usage: "List the drafts waiting for approval",
+				Action: func(c *cli.Context) error {
+					drafts, err := service.ListDrafts(ctx)
+					if err != nil {
+						return err
+					}
+					if len(drafts) == 0 {
+						fmt.Println("No drafts waiting for approval")
+					}
+					for _, draft := range drafts {
+						fmt.Printf("Draft %s created at %s\n\n", draft.ID, draft.CreatedAt.Format(time.RFC1123))
+						for _, preview := range draft.Previews {
+							fmt.Printf("%s:\n%s\n\n", preview.AdapterName, preview.Text)
+						}
+						for _, image := range draft.Post.Images {
+							fmt.Println(image.Path)
+						}
+						for _, publication := range draft.Publications {
+							fmt.Printf("%s: %s %s\n", publication.AdapterName, publication.Status, publication.Error)
+						}
+						fmt.Println("")
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "approve",
+				Usage:     "Publish a draft to social media",
+				ArgsUsage: "<draft id>",
+				Flags:     []cli.Flag{failurePolicyFlag()},
+				Action: func(c *cli.Context) error {
+					id := c.Args().Get(0)
+					if id == "" {
+						return errors.New("a draft id is required")
+					}
+					failurePolicy, err := domain.ParseFailurePolicy(c.String("fail-on"))
+					if err != nil {
+						return err
+					}
+					result, err := service.ApproveDraft(ctx, id)
+					if err != nil {
+						return err
+					}
+					logRunResult(logger, result)
+					return result.Err(failurePolicy)
+				},
+			},
+			{
+				Name:      "reject",
+				Usage:     "Discard a draft without publishing it",
+				ArgsUsage: "<draft id>",
+				Action: func(c *cli.Context) error {
+					id := c.Args().Get(0)
+					if id == "" {
+						return errors.New("a draft id is required")
+					}
+					if err := service.RejectDraft(ctx, id); err != nil {
+						return err
+					}
+					logger.Info("Rejected draft", "id", id)
 					return nil
 				},
 			},
@@ -72,6 +302,105 @@ func NewCLIHandler(ctx context.Context, service ports.Service, logger logger.Log
 	return &CliHandler{app: app}
 }
 
+// generateOptionFlags are the flags of the commands that generate news content, except for the flags with the excluded
+// names
+func generateOptionFlags(excluded ...string) []cli.Flag {
+	flags := []cli.Flag{
+		&cli.IntFlag{
+			Name:  "images",
+			Value: 1,
+			Usage: fmt.Sprintf("Number of images to generate for the article, each showing a different part of the story (max %d)", domain.MaxImagesPerPost),
+		},
+		&cli.StringSliceFlag{
+			Name:  "style",
+			Usage: "Generate one image per style, e.g. --style \"watercolor painting\" --style \"3D render\"",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Render what would be published to each social media instead of publishing it",
+		},
+		&cli.BoolFlag{
+			Name:  "require-approval",
+			Usage: "Store the post as a draft, which is published once approved with the approve command",
+		},
+		&cli.BoolFlag{
+			Name:  "resume",
+			Usage: "Continue the most recent incomplete run instead of starting a new one, if there is one",
+		},
+		&cli.StringSliceFlag{
+			Name:  "channel",
+			Usage: "Only generate content for the channel with this name, instead of for every channel, can be repeated",
+		},
+		&cli.StringFlag{
+			Name:  "article-url",
+			Usage: "Generate content for the article at this url instead of the main article",
+		},
+		&cli.StringFlag{
+			Name:  "prompt",
+			Usage: "Use this prompt for the images instead of asking the LLM for one",
+		},
+	}
+	var included []cli.Flag
+flags:
+	for _, flag := range flags {
+		for _, name := range excluded {
+			if flag.Names()[0] == name {
+				continue flags
+			}
+		}
+		included = append(included, flag)
+	}
+	return included
+}
+
+func failurePolicyFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "fail-on",
+		Value: string(domain.FailurePolicyIgnore),
+		Usage: fmt.Sprintf("Exit with an error when publishing to social media fails, %q never does, %q when any social media fails and %q when all of them fail",
+			domain.FailurePolicyIgnore, domain.FailurePolicyAny, domain.FailurePolicyAll),
+	}
+}
+
+func generateOptionsFromFlags(c *cli.Context) domain.GenerateOptions {
+	return domain.GenerateOptions{
+		ImageCount:      c.Int("images"),
+		ImageStyles:     c.StringSlice("style"),
+		DryRun:          c.Bool("dry-run"),
+		RequireApproval: c.Bool("require-approval"),
+		Resume:          c.Bool("resume"),
+		Channels:        c.StringSlice("channel"),
+		ArticleURL:      c.String("article-url"),
+		Prompt:          c.String("prompt"),
+	}
+}
+
+func isArticleURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// readArticle reads the article from the file, or from stdin when the path is -. It is dated today.
+func readArticle(c *cli.Context, path string) (domain.NewsArticle, error) {
+	var text []byte
+	var err error
+	if path == "-" {
+		text, err = io.ReadAll(c.App.Reader)
+	} else {
+		text, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return domain.NewsArticle{}, fmt.Errorf("failed to read article: %w", err)
+	}
+
+	article, err := domain.ParseArticleText(string(text))
+	if err != nil {
+		return domain.NewsArticle{}, err
+	}
+	article.Date = domain.NewDate(time.Now())
+	article.Source = c.String("source")
+	return article, nil
+}
+
 func (h *CliHandler) Run(args []string) error {
 	return h.app.Run(args)
 }
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+// maxRequestBodySize limits the JSON bodies of the requests, they only contain options and prompts
+const maxRequestBodySize = 1 << 20
+
+// HTTPHandler exposes the service as a JSON API, so other tools can drive the generator. Every request needs the token
+// of the handler as a bearer token, since the API spends money on images and publishes to social media.
+//
+//	POST /runs       generates news content, the body contains the options
+//	GET  /runs/{id}  returns a stored run, requires a repository
+//	POST /prompts    creates an image prompt from the prompt in the body
+//	POST /images     generates an image from the prompt in the body
+type HTTPHandler struct {
+	logger logger.Logger
+	srv    ports.Service
+	token  string
+	mux    *http.ServeMux
+}
+
+func NewHTTPHandler(logger logger.Logger, srv ports.Service, token string) *HTTPHandler {
+	h := &HTTPHandler{logger: logger, srv: srv, token: token, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/runs", h.handleRuns)
+	h.mux.HandleFunc("/runs/", h.handleRun)
+	h.mux.HandleFunc("/prompts", h.handlePrompts)
+	h.mux.HandleFunc("/images", h.handleImages)
+	return h
+}
+
+// RunRequest is the body of POST /runs, fields that are not set use the defaults
+type RunRequest struct {
+	// Images is the number of images to generate, each showing a different part of the story
+	Images int `json:"images"`
+	// Styles generates one image per style
+	Styles          []string `json:"styles"`
+	DryRun          bool     `json:"dryRun"`
+	RequireApproval bool     `json:"requireApproval"`
+	Resume          bool     `json:"resume"`
+	// Channels only generates content for the channels with the names
+	Channels []string `json:"channels"`
+	// ArticleURL generates content for the article at the url instead of the main article
+	ArticleURL string `json:"articleUrl"`
+	// Prompt is used as the prompt of the images instead of asking the LLM for one
+	Prompt string `json:"prompt"`
+}
+
+// promptBody is the body of the requests and responses with a prompt
+type promptBody struct {
+	Prompt string `json:"prompt"`
+}
+
+type runsResponse struct {
+	Runs []runResponse `json:"runs"`
+	// Error is set when generating the content of one of the channels failed, the runs of the others are still returned
+	Error string `json:"error,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		h.respondError(w, http.StatusUnauthorized, errors.New("a valid bearer token is required"))
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// authorized returns true when the request has the token of the handler as its bearer token, a handler without a token
+// authorizes nothing
+func (h *HTTPHandler) authorized(r *http.Request) bool {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return found && h.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+// Serve listens on the address until the context is cancelled, then stops accepting requests and waits up to the
+// shutdown timeout for the requests in progress to finish
+func (h *HTTPHandler) Serve(ctx context.Context, addr string, shutdownTimeout time.Duration) error {
+	server := &http.Server{Addr: addr, Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	h.logger.Info("Serving HTTP API", "address", listener.Addr().String())
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	h.logger.Info("Shutting down HTTP API, waiting for requests in progress", "timeout", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down HTTP API: %w", err)
+	}
+	return nil
+}
+
+func (h *HTTPHandler) handleRuns(w http.ResponseWriter, r *http.Request) {
+	if !h.allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request RunRequest
+	if !h.decode(w, r, &request) {
+		return
+	}
+	if request.Images == 0 {
+		request.Images = 1
+	}
+
+	results, err := h.srv.GenerateNewsContent(r.Context(), domain.GenerateOptions{
+		ImageCount:      request.Images,
+		ImageStyles:     request.Styles,
+		DryRun:          request.DryRun,
+		RequireApproval: request.RequireApproval,
+		Resume:          request.Resume,
+		Channels:        request.Channels,
+		ArticleURL:      request.ArticleURL,
+		Prompt:          request.Prompt,
+	})
+	if errors.Is(err, domain.ErrInvalidOptions) {
+		h.respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	logRunResults(h.logger, results)
+	response := runsResponse{Runs: newRunResponses(results)}
+	if err != nil {
+		h.logger.Error("Error while generating news content", "error", err)
+		response.Error = err.Error()
+		h.respond(w, http.StatusInternalServerError, response)
+		return
+	}
+	h.respond(w, http.StatusOK, response)
+}
+
+func (h *HTTPHandler) handleRun(w http.ResponseWriter, r *http.Request) {
+	if !h.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/runs/")
+	if id == "" || strings.Contains(id, "/") {
+		h.respondError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	run, err := h.srv.GetRun(r.Context(), id)
+	if err != nil {
+		h.respondServiceError(w, err)
+		return
+	}
+	h.respond(w, http.StatusOK, newStoredRunResponse(run))
+}
+
+func (h *HTTPHandler) handlePrompts(w http.ResponseWriter, r *http.Request) {
+	prompt, ok := h.decodePrompt(w, r)
+	if !ok {
+		return
+	}
+	imagePrompt, err := h.srv.CreatePrompt(r.Context(), prompt)
+	if err != nil {
+		h.respondServiceError(w, err)
+		return
+	}
+	h.respond(w, http.StatusOK, promptBody{Prompt: imagePrompt})
+}
+
+func (h *HTTPHandler) handleImages(w http.ResponseWriter, r *http.Request) {
+	prompt, ok := h.decodePrompt(w, r)
+	if !ok {
+		return
+	}
+	imagePath, err := h.srv.GenerateImage(r.Context(), prompt)
+	if err != nil {
+		h.respondServiceError(w, err)
+		return
+	}
+	h.respond(w, http.StatusOK, imageResponse{Path: string(imagePath), Prompt: prompt})
+}
+
+// decodePrompt decodes the body of a POST request with a prompt, responding with an error when it is invalid
+func (h *HTTPHandler) decodePrompt(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if !h.allowMethod(w, r, http.MethodPost) {
+		return "", false
+	}
+	var request promptBody
+	if !h.decode(w, r, &request) {
+		return "", false
+	}
+	if strings.TrimSpace(request.Prompt) == "" {
+		h.respondError(w, http.StatusBadRequest, errors.New("prompt is required"))
+		return "", false
+	}
+	return request.Prompt, true
+}
+
+func (h *HTTPHandler) allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	h.respondError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+// decode decodes the JSON body of the request, an empty body leaves the value unchanged
+func (h *HTTPHandler) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		h.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func (h *HTTPHandler) respondServiceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrNotFound) {
+		h.respondError(w, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, domain.ErrInvalidOptions) {
+		h.respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.logger.Error("Error while handling request", "error", err)
+	h.respondError(w, http.StatusInternalServerError, err)
+}
+
+func (h *HTTPHandler) respondError(w http.ResponseWriter, status int, err error) {
+	h.respond(w, status, errorResponse{Error: err.Error()})
+}
+
+func (h *HTTPHandler) respond(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Warn("Could not write response", "error", err)
+	}
+}
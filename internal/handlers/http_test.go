@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	mockService := ports.NewMockService(t)
+	mockService.On("GenerateNewsContent", mock.Anything, domain.GenerateOptions{ImageCount: 1, DryRun: true, Channels: []string{"science"}}).Return([]domain.RunResult{{
+		ID:           "run",
+		Channel:      "science",
+		Post:         domain.Post{NewsArticle: domain.NewsArticle{Title: "Comet spotted"}, Images: []domain.GeneratedImage{{Path: "comet.png", Prompt: "a comet"}}},
+		Publications: []domain.PublishResult{{AdapterName: "Mastodon", Status: domain.PublishStatusPublished, PostID: "1"}},
+	}}, nil)
+	mockService.On("GenerateNewsContent", mock.Anything, domain.GenerateOptions{ImageCount: 5}).Return(nil,
+		fmt.Errorf("%w: at most 4 images can be generated for a post, got 5", domain.ErrInvalidOptions))
+	mockService.On("GetRun", mock.Anything, "missing").Return(domain.Run{}, fmt.Errorf("run missing: %w", domain.ErrNotFound))
+	mockService.On("CreatePrompt", mock.Anything, "a comet").Return("a bright comet over the sea", nil)
+
+	handler := NewHTTPHandler(logger.NewTestLogger(), mockService, "secret")
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		token          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Generate",
+			method:         http.MethodPost,
+			path:           "/runs",
+			body:           `{"dryRun": true, "channels": ["science"]}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"runs":[{"id":"run","channel":"science","article":{"title":"Comet spotted","body":""},` +
+				`"images":[{"path":"comet.png","prompt":"a comet"}],"publications":[{"adapter":"Mastodon","status":"published","postId":"1"}]}]}`,
+		},
+		{
+			name:           "Unknown option",
+			method:         http.MethodPost,
+			path:           "/runs",
+			body:           `{"imageCount": 2}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid request body: json: unknown field \"imageCount\""}`,
+		},
+		{
+			name:           "Invalid options",
+			method:         http.MethodPost,
+			path:           "/runs",
+			body:           `{"images": 5}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid options: at most 4 images can be generated for a post, got 5"}`,
+		},
+		{
+			name:           "Missing token",
+			method:         http.MethodPost,
+			path:           "/runs",
+			token:          "-",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"a valid bearer token is required"}`,
+		},
+		{
+			name:           "Wrong token",
+			method:         http.MethodPost,
+			path:           "/images",
+			body:           `{"prompt": "a comet"}`,
+			token:          "guess",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"a valid bearer token is required"}`,
+		},
+		{
+			name:           "Run not found",
+			method:         http.MethodGet,
+			path:           "/runs/missing",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"run missing: not found"}`,
+		},
+		{
+			name:           "Create prompt",
+			method:         http.MethodPost,
+			path:           "/prompts",
+			body:           `{"prompt": "a comet"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"prompt":"a bright comet over the sea"}`,
+		},
+		{
+			name:           "Missing prompt",
+			method:         http.MethodPost,
+			path:           "/images",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"prompt is required"}`,
+		},
+		{
+			name:           "Wrong method",
+			method:         http.MethodGet,
+			path:           "/prompts",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   `{"error":"method GET not allowed"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			switch tc.token {
+			case "":
+				request.Header.Set("Authorization", "Bearer secret")
+			case "-":
+			default:
+				request.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.expectedBody, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+)
+
+// runResponse is the JSON representation of a run, shared by the handlers that respond with runs
+type runResponse struct {
+	ID      string `json:"id"`
+	Channel string `json:"channel,omitempty"`
+	// Status is only known for stored runs
+	Status        string                 `json:"status,omitempty"`
+	Article       articleResponse        `json:"article"`
+	Topic         string                 `json:"topic,omitempty"`
+	Images        []imageResponse        `json:"images"`
+	DraftID       string                 `json:"draftId,omitempty"`
+	Previews      []previewResponse      `json:"previews,omitempty"`
+	Publications  []publicationResponse  `json:"publications"`
+	ContentPolicy *contentPolicyResponse `json:"contentPolicy,omitempty"`
+	CreatedAt     *time.Time             `json:"createdAt,omitempty"`
+	UpdatedAt     *time.Time             `json:"updatedAt,omitempty"`
+}
+
+type articleResponse struct {
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	Date    string `json:"date,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Section string `json:"section,omitempty"`
+}
+
+type imageResponse struct {
+	Path   string `json:"path"`
+	Prompt string `json:"prompt"`
+}
+
+type previewResponse struct {
+	Adapter string   `json:"adapter"`
+	Text    string   `json:"text"`
+	Replies []string `json:"replies,omitempty"`
+}
+
+type publicationResponse struct {
+	Adapter string `json:"adapter"`
+	Status  string `json:"status"`
+	PostID  string `json:"postId,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type contentPolicyResponse struct {
+	Action   string `json:"action"`
+	Category string `json:"category,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func newRunResponse(result domain.RunResult) runResponse {
+	response := runResponse{
+		ID:            result.ID,
+		Channel:       result.Channel,
+		Article:       newArticleResponse(result.Post.NewsArticle),
+		Images:        newImageResponses(result.Post.Images),
+		DraftID:       result.DraftID,
+		Publications:  newPublicationResponses(result.Publications),
+		ContentPolicy: newContentPolicyResponse(result.ContentPolicy),
+	}
+	for _, preview := range result.Previews {
+		response.Previews = append(response.Previews, previewResponse{Adapter: preview.AdapterName, Text: preview.Text, Replies: preview.Replies})
+	}
+	return response
+}
+
+func newRunResponses(results []domain.RunResult) []runResponse {
+	responses := make([]runResponse, 0, len(results))
+	for _, result := range results {
+		responses = append(responses, newRunResponse(result))
+	}
+	return responses
+}
+
+// newStoredRunResponse describes a run from the repository, which also knows its status and progress
+func newStoredRunResponse(run domain.Run) runResponse {
+	return runResponse{
+		ID:            run.ID,
+		Channel:       run.Channel,
+		Status:        string(run.Status),
+		Article:       newArticleResponse(run.Article),
+		Topic:         run.Topic,
+		Images:        newImageResponses(run.Images),
+		DraftID:       run.DraftID,
+		Publications:  newPublicationResponses(run.Publications),
+		ContentPolicy: newContentPolicyResponse(run.ContentPolicy),
+		CreatedAt:     &run.CreatedAt,
+		UpdatedAt:     &run.UpdatedAt,
+	}
+}
+
+func newArticleResponse(article domain.NewsArticle) articleResponse {
+	response := articleResponse{
+		Title:   article.Title,
+		Body:    article.Body,
+		URL:     article.Url,
+		Source:  article.Source,
+		Section: article.Section,
+	}
+	if article.Date.Year != 0 {
+		response.Date = time.Date(article.Date.Year, article.Date.Month, article.Date.Day, 0, 0, 0, 0, time.UTC).Format(time.DateOnly)
+	}
+	return response
+}
+
+func newImageResponses(images []domain.GeneratedImage) []imageResponse {
+	responses := make([]imageResponse, 0, len(images))
+	for _, image := range images {
+		responses = append(responses, imageResponse{Path: string(image.Path), Prompt: image.Prompt})
+	}
+	return responses
+}
+
+func newPublicationResponses(publications []domain.PublishResult) []publicationResponse {
+	responses := make([]publicationResponse, 0, len(publications))
+	for _, publication := range publications {
+		responses = append(responses, publicationResponse{
+			Adapter: publication.AdapterName,
+			Status:  string(publication.Status),
+			PostID:  publication.PostID,
+			URL:     publication.URL,
+			Error:   publication.Error,
+		})
+	}
+	return responses
+}
+
+func newContentPolicyResponse(decision domain.PolicyDecision) *contentPolicyResponse {
+	if decision.Action == "" {
+		return nil
+	}
+	return &contentPolicyResponse{Action: string(decision.Action), Category: decision.Category, Reason: decision.Reason}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+// logRunResult logs where the post of a run ended up
+func logRunResult(logger logger.Logger, result domain.RunResult) {
+	run := []any{"run", result.ID}
+	if result.Channel != "" {
+		run = append(run, "channel", result.Channel)
+	}
+	if result.ContentPolicy.Action == domain.PolicyActionSkip {
+		logger.Warn("Skipped article because of the content policy", append(run, "category", result.ContentPolicy.Category, "reason", result.ContentPolicy.Reason)...)
+	} else if result.ContentPolicy.Action == domain.PolicyActionNeutral {
+		logger.Info("Generated neutral images because of the content policy", append(run, "category", result.ContentPolicy.Category, "reason", result.ContentPolicy.Reason)...)
+	}
+	if result.Resumable {
+		logger.Warn(fmt.Sprintf("Run %s is incomplete, it can be continued with the resume command", result.ID), run...)
+	} else if result.ID != "" {
+		logger.Info("Finished run", run...)
+	}
+	if result.DraftID != "" && len(result.Publications) == 0 {
+		logger.Info("Stored draft waiting for approval", append(run, "draft", result.DraftID)...)
+	}
+	for _, publication := range result.Publications {
+		if publication.Status == domain.PublishStatusPublished {
+			logger.Info("Published post", append(run, "adapter", publication.AdapterName, "url", publication.URL)...)
+		} else {
+			logger.Error("Could not publish post", append(run, "adapter", publication.AdapterName, "status", publication.Status, "error", publication.Error)...)
+		}
+	}
+}
+
+// logRunResults logs the runs of every channel
+func logRunResults(logger logger.Logger, results []domain.RunResult) {
+	for _, result := range results {
+		logRunResult(logger, result)
+	}
+}
+
+// runResultsErr returns the errors of the runs that failed according to the policy
+func runResultsErr(results []domain.RunResult, policy domain.FailurePolicy) error {
+	var errs []error
+	for _, result := range results {
+		if err := result.Err(policy); err != nil {
+			if result.Channel != "" {
+				err = fmt.Errorf("channel %s: %w", result.Channel, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
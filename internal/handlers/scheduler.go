@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/content-generator/internal/infrastructure"
+	"github.com/BaronBonet/go-logger/logger"
+)
+
+// CatchUpPolicy decides what happens to runs that were missed while the scheduler was not running
+type CatchUpPolicy string
+
+const (
+	// CatchUpSkip ignores missed runs and waits for the next scheduled run
+	CatchUpSkip CatchUpPolicy = "skip"
+	// CatchUpOnce runs once at startup if one or more runs were missed
+	CatchUpOnce CatchUpPolicy = "once"
+)
+
+type SchedulerConfig struct {
+	Schedule *infrastructure.CronSchedule
+	// Jitter delays each run by a random duration up to this value, so runs don't happen at the exact same minute. It is
+	// capped at half the time until the following run, so a delayed run never runs into the next one.
+	Jitter  time.Duration
+	CatchUp CatchUpPolicy
+	// StateFile stores when the scheduler last ran, it is required to detect missed runs
+	StateFile string
+	Options   domain.GenerateOptions
+}
+
+type SchedulerHandler struct {
+	logger logger.Logger
+	srv    ports.Service
+	config SchedulerConfig
+	now    func() time.Time
+	after  func(time.Duration) <-chan time.Time
+	random func(n int64) int64
+}
+
+func NewSchedulerHandler(logger logger.Logger, srv ports.Service, config SchedulerConfig) *SchedulerHandler {
+	return &SchedulerHandler{logger: logger, srv: srv, config: config, now: time.Now, after: time.After, random: rand.Int63n}
+}
+
+type schedulerState struct {
+	LastScheduledRun time.Time `json:"lastScheduledRun"`
+}
+
+// Run generates news content on the schedule until the context is cancelled. Failed runs are logged and do not stop
+// the scheduler.
+func (h *SchedulerHandler) Run(ctx context.Context) error {
+	var lastRun time.Time
+	if h.config.CatchUp == CatchUpOnce {
+		var err error
+		if lastRun, err = h.lastScheduledRun(); err != nil {
+			h.logger.Warn("Could not read scheduler state, missed runs will not be caught up", "error", err)
+		}
+		if missed := h.config.Schedule.Next(lastRun); !lastRun.IsZero() && !missed.IsZero() && missed.Before(h.now()) {
+			h.logger.Info("Catching up on missed run", "missedRun", missed)
+			h.run(ctx, missed)
+			lastRun = missed
+		}
+	}
+
+	for {
+		// A run is never repeated, even when the clock went back while it ran
+		from := h.now()
+		if from.Before(lastRun) {
+			from = lastRun
+		}
+		next := h.config.Schedule.Next(from)
+		if next.IsZero() {
+			return errors.New("the schedule has no upcoming runs")
+		}
+		delay := next.Sub(h.now()) + h.jitter(next)
+		h.logger.Info("Waiting for next scheduled run", "next", next, "delay", delay.Round(time.Second))
+
+		select {
+		case <-ctx.Done():
+			h.logger.Info("Stopping scheduler")
+			return nil
+		case <-h.after(delay):
+			h.run(ctx, next)
+			lastRun = next
+		}
+	}
+}
+
+// jitter returns the random delay of the run, below half the time until the run after it
+func (h *SchedulerHandler) jitter(next time.Time) time.Duration {
+	maxJitter := h.config.Jitter
+	if following := h.config.Schedule.Next(next); !following.IsZero() && following.Sub(next)/2 < maxJitter {
+		maxJitter = following.Sub(next) / 2
+	}
+	if maxJitter <= 0 {
+		return 0
+	}
+	return time.Duration(h.random(int64(maxJitter)))
+}
+
+func (h *SchedulerHandler) run(ctx context.Context, scheduled time.Time) {
+	h.logger.Info("Starting scheduled run", "scheduled", scheduled)
+	results, err := h.srv.GenerateNewsContent(ctx, h.config.Options)
+	logRunResults(h.logger, results)
+	if err != nil {
+		h.logger.Error("Scheduled run failed", "error", err)
+	}
+	if err := h.saveLastScheduledRun(scheduled); err != nil {
+		h.logger.Warn("Could not save scheduler state", "error", err)
+	}
+}
+
+func (h *SchedulerHandler) lastScheduledRun() (time.Time, error) {
+	if h.config.StateFile == "" {
+		return time.Time{}, nil
+	}
+	data, err := os.ReadFile(h.config.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	var state schedulerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse scheduler state: %w", err)
+	}
+	return state.LastScheduledRun, nil
+}
+
+func (h *SchedulerHandler) saveLastScheduledRun(scheduled time.Time) error {
+	if h.config.StateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(schedulerState{LastScheduledRun: scheduled})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.config.StateFile), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(h.config.StateFile, data, 0o644)
+}
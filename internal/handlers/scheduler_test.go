@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BaronBonet/content-generator/internal/core/domain"
+	"github.com/BaronBonet/content-generator/internal/core/ports"
+	"github.com/BaronBonet/content-generator/internal/infrastructure"
+	"github.com/BaronBonet/go-logger/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulerHandler_Run(t *testing.T) {
+	start := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule string
+		jitter   time.Duration
+		catchUp  CatchUpPolicy
+		// state is the content of the state file, it is not created when empty
+		state string
+		// waits is the number of runs the scheduler waits for, it is stopped while waiting for the one after
+		waits        int
+		expectedRuns []time.Time
+		// expectedJitter is the maximum jitter of every wait
+		expectedJitter []int64
+		expectedState  string
+	}{
+		{
+			name:          "catches up on a missed run",
+			schedule:      "0 8 * * *",
+			catchUp:       CatchUpOnce,
+			state:         `{"lastScheduledRun":"2023-06-01T08:00:00Z"}`,
+			waits:         1,
+			expectedRuns:  []time.Time{start, time.Date(2023, 6, 4, 8, 0, 0, 0, time.UTC)},
+			expectedState: `{"lastScheduledRun":"2023-06-04T08:00:00Z"}`,
+		},
+		{
+			name:          "skips missed runs",
+			schedule:      "0 8 * * *",
+			catchUp:       CatchUpSkip,
+			state:         `{"lastScheduledRun":"2023-06-01T08:00:00Z"}`,
+			waits:         1,
+			expectedRuns:  []time.Time{time.Date(2023, 6, 4, 8, 0, 0, 0, time.UTC)},
+			expectedState: `{"lastScheduledRun":"2023-06-04T08:00:00Z"}`,
+		},
+		{
+			name:          "nothing to catch up without state",
+			schedule:      "0 8 * * *",
+			catchUp:       CatchUpOnce,
+			waits:         1,
+			expectedRuns:  []time.Time{time.Date(2023, 6, 4, 8, 0, 0, 0, time.UTC)},
+			expectedState: `{"lastScheduledRun":"2023-06-04T08:00:00Z"}`,
+		},
+		{
+			name:          "nothing to catch up when no run was missed",
+			schedule:      "0 8 * * *",
+			catchUp:       CatchUpOnce,
+			state:         `{"lastScheduledRun":"2023-06-03T08:00:00Z"}`,
+			waits:         1,
+			expectedRuns:  []time.Time{time.Date(2023, 6, 4, 8, 0, 0, 0, time.UTC)},
+			expectedState: `{"lastScheduledRun":"2023-06-04T08:00:00Z"}`,
+		},
+		{
+			name:     "jitter delays the runs",
+			schedule: "0 8,21 * * *",
+			jitter:   10 * time.Minute,
+			waits:    2,
+			expectedRuns: []time.Time{
+				time.Date(2023, 6, 3, 21, 9, 59, 999999999, time.UTC),
+				time.Date(2023, 6, 4, 8, 9, 59, 999999999, time.UTC),
+			},
+			expectedJitter: []int64{int64(10 * time.Minute), int64(10 * time.Minute), int64(10 * time.Minute)},
+			expectedState:  `{"lastScheduledRun":"2023-06-04T08:00:00Z"}`,
+		},
+		{
+			name:     "jitter is capped below the time until the following run",
+			schedule: "0,20 11 * * *",
+			jitter:   time.Hour,
+			waits:    3,
+			expectedRuns: []time.Time{
+				time.Date(2023, 6, 3, 11, 9, 59, 999999999, time.UTC),
+				time.Date(2023, 6, 3, 12, 19, 59, 999999999, time.UTC),
+				time.Date(2023, 6, 4, 11, 9, 59, 999999999, time.UTC),
+			},
+			expectedJitter: []int64{int64(10 * time.Minute), int64(time.Hour), int64(10 * time.Minute), int64(time.Hour)},
+			expectedState:  `{"lastScheduledRun":"2023-06-04T11:00:00Z"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := infrastructure.ParseCronSchedule(tt.schedule, time.UTC)
+			require.NoError(t, err)
+			stateFile := filepath.Join(t.TempDir(), "state", "scheduler.json")
+			if tt.state != "" {
+				require.NoError(t, os.MkdirAll(filepath.Dir(stateFile), 0o755))
+				require.NoError(t, os.WriteFile(stateFile, []byte(tt.state), 0o644))
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			clock := start
+			var runs []time.Time
+			options := domain.GenerateOptions{ImageStyles: []string{"watercolor painting"}}
+			mockService := ports.NewMockService(t)
+			mockService.On("GenerateNewsContent", mock.Anything, options).
+				Run(func(mock.Arguments) { runs = append(runs, clock) }).
+				Return(nil, nil)
+
+			handler := NewSchedulerHandler(logger.NewTestLogger(), mockService, SchedulerConfig{
+				Schedule:  schedule,
+				Jitter:    tt.jitter,
+				CatchUp:   tt.catchUp,
+				StateFile: stateFile,
+				Options:   options,
+			})
+			var jitters []int64
+			handler.now = func() time.Time { return clock }
+			handler.random = func(n int64) int64 {
+				jitters = append(jitters, n)
+				return n - 1
+			}
+			waits := 0
+			handler.after = func(delay time.Duration) <-chan time.Time {
+				if waits++; waits > tt.waits {
+					cancel()
+					return nil
+				}
+				clock = clock.Add(delay)
+				fired := make(chan time.Time, 1)
+				fired <- clock
+				return fired
+			}
+
+			require.NoError(t, handler.Run(ctx))
+			assert.Equal(t, tt.expectedRuns, runs)
+			assert.Equal(t, tt.expectedJitter, jitters)
+			state, err := os.ReadFile(stateFile)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expectedState, string(state))
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package infrastructure
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes which adapters the pipeline is built from and how they are configured
+type Config struct {
+	News           AdapterConfig   `yaml:"news"`
+	LLM            AdapterConfig   `yaml:"llm"`
+	ImageGenerator AdapterConfig   `yaml:"imageGenerator"`
+	SocialMedia    []AdapterConfig `yaml:"socialMedia"`
+	// Channels publish to their own social media accounts, next to the social medias above
+	Channels []ChannelConfig `yaml:"channels"`
+	// Routing classifies the articles into topics, which channels publish and how their images are generated
+	Routing RoutingConfig `yaml:"routing"`
+	// ContentPolicy skips articles about sensitive categories like deaths, or generates neutral images for them
+	ContentPolicy ContentPolicyConfig `yaml:"contentPolicy"`
+	// Enrichment reads the stories of the news articles, the news only provides a short summary of them
+	Enrichment EnrichmentConfig `yaml:"enrichment"`
+	Preview    PreviewConfig    `yaml:"preview"`
+	Repository RepositoryConfig `yaml:"repository"`
+	Publishing PublishingConfig `yaml:"publishing"`
+	// Secrets selects where the ${secret:NAME} references in the settings of the adapters are looked up
+	Secrets AdapterConfig `yaml:"secrets"`
+}
+
+// AdapterConfig selects an adapter by its type, the settings are specific to the type of adapter
+type AdapterConfig struct {
+	Type string `yaml:"type"`
+	// ID identifies a social media adapter within its channel, in the results of runs and the previews. It defaults to
+	// the type, so it only has to be set when a channel publishes to two accounts of the same social media.
+	ID string `yaml:"id"`
+	// Enabled defaults to true, an empty value disables the adapter so it can depend on an environment variable being
+	// set, e.g. enabled: ${MASTODON_SERVER:+true}
+	Enabled bool `yaml:"enabled"`
+	// Timeout overrides the publish timeout for a social media adapter
+	Timeout  time.Duration     `yaml:"timeout"`
+	Settings map[string]string `yaml:"settings"`
+}
+
+// ChannelConfig is a named group of social media accounts with its own image style and topics, e.g. a science account
+type ChannelConfig struct {
+	Name string `yaml:"name"`
+	// PromptStyle is the style the images of the channel are generated in
+	PromptStyle string `yaml:"promptStyle"`
+	// Topics are the sections of the news the channel publishes about, it publishes the main article when empty
+	Topics      []string        `yaml:"topics"`
+	SocialMedia []AdapterConfig `yaml:"socialMedia"`
+}
+
+type RoutingConfig struct {
+	// Classifier is "section" to use the section of the article, the default, or "llm" to ask the LLM for the topic
+	Classifier string        `yaml:"classifier"`
+	Topics     []TopicConfig `yaml:"topics"`
+}
+
+// TopicConfig describes which articles are about a topic and how their images are generated, see domain.Topic
+type TopicConfig struct {
+	Name string `yaml:"name"`
+	// Sections of the news that are about the topic, defaults to the section with the name of the topic
+	Sections    []string `yaml:"sections"`
+	PromptStyle string   `yaml:"promptStyle"`
+	// PromptTemplate replaces the request for the image prompt, e.g. "Describe a cyberpunk scene about {{.Title}}"
+	PromptTemplate string `yaml:"promptTemplate"`
+}
+
+type ContentPolicyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// LLM asks the LLM whether an article is about one of the categories when none of their keywords match
+	LLM bool `yaml:"llm"`
+	// NeutralStyle is the style of the images of articles in a category with the neutral action
+	NeutralStyle string `yaml:"neutralStyle"`
+	// Categories replace the default categories, death, violence and disaster, when set
+	Categories []SensitiveCategoryConfig `yaml:"categories"`
+}
+
+type SensitiveCategoryConfig struct {
+	Name string `yaml:"name"`
+	// Action is "skip", the default, "neutral" or "allow"
+	Action   string   `yaml:"action"`
+	Keywords []string `yaml:"keywords"`
+}
+
+type EnrichmentConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxBodyLength is the number of characters above which the LLM summarizes a story, zero means never
+	MaxBodyLength int `yaml:"maxBodyLength"`
+}
+
+type PreviewConfig struct {
+	// Directory the previews of dry runs are written to together with the images, they are printed to stdout when empty
+	Directory string `yaml:"directory"`
+}
+
+type RepositoryConfig struct {
+	// Directory drafts and checkpoints are stored in, nothing is stored when empty and no bucket is set
+	Directory string `yaml:"directory"`
+	// Bucket stores drafts and checkpoints in S3 instead, e.g. for the lambda whose filesystem does not outlive an
+	// invocation. The directory only caches the images then and defaults to the temporary directory.
+	Bucket string `yaml:"bucket"`
+	// Prefix is prepended to the keys of the objects in the bucket
+	Prefix          string `yaml:"prefix"`
+	Region          string `yaml:"region"`
+	AccessKeyID     string `yaml:"accessKeyId"`
+	SecretAccessKey string `yaml:"secretAccessKey"`
+	SessionToken    string `yaml:"sessionToken"`
+	Endpoint        string `yaml:"endpoint"`
+}
+
+type PublishingConfig struct {
+	// Timeout is how long each social media adapter gets to publish a post, zero means no timeout
+	Timeout time.Duration `yaml:"timeout"`
+	// Budget is how long publishing to all social media adapters may take, zero means no budget
+	Budget time.Duration `yaml:"budget"`
+}
+
+// AdapterID returns the id of the adapter, its type when no id is set
+func (a AdapterConfig) AdapterID() string {
+	if a.ID == "" {
+		return a.Type
+	}
+	return a.ID
+}
+
+func (a *AdapterConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plainAdapterConfig AdapterConfig
+	plain := plainAdapterConfig{Enabled: true}
+	if err := node.Decode(&plain); err != nil {
+		return err
+	}
+	*a = AdapterConfig(plain)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "enabled" && node.Content[i+1].ShortTag() == "!!null" {
+			a.Enabled = false
+		}
+	}
+	return nil
+}
+
+// LoadConfig reads the configuration file at path, see ParseConfig
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses a YAML configuration. Environment variables are interpolated in the values before they are
+// parsed, so secrets don't have to be stored in the file:
+//
+//	${VAR}          the value of VAR, empty when it is not set
+//	${VAR:-default} the value of VAR, or default when it is not set or empty
+//	${VAR:+word}    word when VAR is set and not empty, otherwise empty
+func ParseConfig(data []byte) (Config, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return Config{}, err
+	}
+	interpolateEnv(&root)
+
+	var config Config
+	if err := root.Decode(&config); err != nil {
+		return Config{}, err
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Validate checks that every adapter has a type, whether the type and its settings are valid is checked when the
+// adapter is created
+func (c Config) Validate() error {
+	var errs []error
+	if c.News.Type == "" {
+		errs = append(errs, errors.New("news: type not set"))
+	}
+	if c.LLM.Type == "" {
+		errs = append(errs, errors.New("llm: type not set"))
+	}
+	if c.ImageGenerator.Type == "" {
+		errs = append(errs, errors.New("imageGenerator: type not set"))
+	}
+	errs = append(errs, validateSocialMedia("socialMedia", c.SocialMedia)...)
+	names := map[string]bool{}
+	for i, channel := range c.Channels {
+		switch {
+		case channel.Name == "":
+			errs = append(errs, fmt.Errorf("channels[%d]: name not set", i))
+		case names[channel.Name]:
+			errs = append(errs, fmt.Errorf("channels[%d]: name %q is used by another channel", i, channel.Name))
+		}
+		names[channel.Name] = true
+		errs = append(errs, validateSocialMedia(fmt.Sprintf("channels[%d].socialMedia", i), channel.SocialMedia)...)
+	}
+	if c.Repository.Bucket != "" && c.Repository.Region == "" {
+		errs = append(errs, errors.New("repository: the bucket requires a region"))
+	}
+	if c.Routing.Classifier == "llm" && len(c.Routing.Topics) == 0 {
+		errs = append(errs, errors.New("routing: the llm classifier requires topics"))
+	}
+	topics := map[string]bool{}
+	for i, topic := range c.Routing.Topics {
+		switch {
+		case topic.Name == "":
+			errs = append(errs, fmt.Errorf("routing.topics[%d]: name not set", i))
+		case topics[strings.ToLower(topic.Name)]:
+			errs = append(errs, fmt.Errorf("routing.topics[%d]: name %q is used by another topic", i, topic.Name))
+		}
+		topics[strings.ToLower(topic.Name)] = true
+		if _, err := template.New(topic.Name).Parse(topic.PromptTemplate); err != nil {
+			errs = append(errs, fmt.Errorf("routing.topics[%d]: invalid prompt template: %w", i, err))
+		}
+	}
+	for i, category := range c.ContentPolicy.Categories {
+		if category.Name == "" {
+			errs = append(errs, fmt.Errorf("contentPolicy.categories[%d]: name not set", i))
+		}
+	}
+	if c.Enrichment.MaxBodyLength < 0 {
+		errs = append(errs, errors.New("enrichment: maxBodyLength must not be negative"))
+	}
+	if c.Publishing.Timeout < 0 || c.Publishing.Budget < 0 {
+		errs = append(errs, errors.New("publishing: timeout and budget must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+func validateSocialMedia(path string, adapters []AdapterConfig) []error {
+	var errs []error
+	ids := map[string]bool{}
+	for i, adapter := range adapters {
+		switch {
+		case adapter.Type == "":
+			errs = append(errs, fmt.Errorf("%s[%d]: type not set", path, i))
+		case ids[adapter.AdapterID()]:
+			errs = append(errs, fmt.Errorf("%s[%d]: id %q is used by another adapter, set a unique id", path, i, adapter.AdapterID()))
+		}
+		ids[adapter.AdapterID()] = true
+		if adapter.Timeout < 0 {
+			errs = append(errs, fmt.Errorf("%s[%d]: timeout must not be negative", path, i))
+		}
+	}
+	return errs
+}
+
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:-|:\+)([^}]*))?\}`)
+
+// interpolateEnv expands the references to environment variables in the scalar values of the document
+func interpolateEnv(node *yaml.Node) {
+	for _, child := range node.Content {
+		interpolateEnv(child)
+	}
+	if node.Kind != yaml.ScalarNode || !envReferencePattern.MatchString(node.Value) {
+		return
+	}
+	node.Value = envReferencePattern.ReplaceAllStringFunc(node.Value, func(reference string) string {
+		match := envReferencePattern.FindStringSubmatch(reference)
+		value := os.Getenv(match[1])
+		switch match[2] {
+		case ":-":
+			if value == "" {
+				return match[3]
+			}
+		case ":+":
+			if value != "" {
+				return match[3]
+			}
+			return ""
+		}
+		return value
+	})
+	// Unquoted values are resolved again, so they can become booleans, numbers or empty
+	if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+		node.Tag = ""
+	}
+}
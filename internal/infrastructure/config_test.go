@@ -0,0 +1,146 @@
+package infrastructure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+news:
+  type: nytimes
+  settings:
+    apiKey: ${TEST_NEWS_KEY}
+llm:
+  type: chatgpt
+  settings:
+    apiKey: '${TEST_OPENAI_KEY:-fallback}'
+imageGenerator:
+  type: dalle
+socialMedia:
+  - type: twitter
+    timeout: ${TEST_TWITTER_TIMEOUT:-1m}
+  - type: mastodon
+    enabled: ${TEST_MASTODON_SERVER:+true}
+    settings:
+      server: https://${TEST_MASTODON_SERVER}
+  - type: instagram${TEST_INSTAGRAM_TOKEN:+-graph}
+channels:
+  - name: science
+    promptStyle: watercolor painting
+    topics: [science, climate]
+    socialMedia:
+      - type: mastodon
+        timeout: 30s
+publishing:
+  timeout: ${TEST_PUBLISH_TIMEOUT}
+  budget: 2m
+`
+
+func TestParseConfig(t *testing.T) {
+	t.Setenv("TEST_NEWS_KEY", "123")
+	t.Setenv("TEST_MASTODON_SERVER", "")
+	t.Setenv("TEST_INSTAGRAM_TOKEN", "")
+
+	config, err := ParseConfig([]byte(testConfig))
+	require.NoError(t, err)
+
+	require.Equal(t, "nytimes", config.News.Type)
+	require.Equal(t, "123", config.News.Settings["apiKey"])
+	require.Equal(t, "fallback", config.LLM.Settings["apiKey"])
+	require.True(t, config.News.Enabled)
+
+	require.Len(t, config.SocialMedia, 3)
+	require.True(t, config.SocialMedia[0].Enabled)
+	require.Equal(t, time.Minute, config.SocialMedia[0].Timeout)
+	require.False(t, config.SocialMedia[1].Enabled)
+	require.Equal(t, "https://", config.SocialMedia[1].Settings["server"])
+	require.True(t, config.SocialMedia[2].Enabled)
+	require.Equal(t, "instagram", config.SocialMedia[2].Type)
+
+	require.Len(t, config.Channels, 1)
+	require.Equal(t, "science", config.Channels[0].Name)
+	require.Equal(t, "watercolor painting", config.Channels[0].PromptStyle)
+	require.Equal(t, []string{"science", "climate"}, config.Channels[0].Topics)
+	require.Equal(t, []AdapterConfig{{Type: "mastodon", Enabled: true, Timeout: 30 * time.Second}}, config.Channels[0].SocialMedia)
+
+	require.Zero(t, config.Publishing.Timeout)
+	require.Equal(t, 2*time.Minute, config.Publishing.Budget)
+}
+
+func TestParseConfig_EnabledByEnv(t *testing.T) {
+	t.Setenv("TEST_MASTODON_SERVER", "mastodon.social")
+	t.Setenv("TEST_INSTAGRAM_TOKEN", "token")
+
+	config, err := ParseConfig([]byte(testConfig))
+	require.NoError(t, err)
+
+	require.True(t, config.SocialMedia[1].Enabled)
+	require.Equal(t, "https://mastodon.social", config.SocialMedia[1].Settings["server"])
+	require.Equal(t, "instagram-graph", config.SocialMedia[2].Type)
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{
+			name:          "Missing types",
+			config:        "socialMedia:\n  - settings: {}\n",
+			expectedError: "news: type not set\nllm: type not set\nimageGenerator: type not set\nsocialMedia[0]: type not set",
+		},
+		{
+			name:          "Invalid duration",
+			config:        "news: {type: nytimes}\nllm: {type: chatgpt}\nimageGenerator: {type: dalle}\npublishing:\n  timeout: soon\n",
+			expectedError: "cannot unmarshal !!str `soon` into time.Duration",
+		},
+		{
+			name:          "Negative timeout",
+			config:        "news: {type: nytimes}\nllm: {type: chatgpt}\nimageGenerator: {type: dalle}\npublishing:\n  budget: -1m\n",
+			expectedError: "publishing: timeout and budget must not be negative",
+		},
+		{
+			name: "Invalid channels",
+			config: "news: {type: nytimes}\nllm: {type: chatgpt}\nimageGenerator: {type: dalle}\nchannels:\n" +
+				"  - name: science\n    socialMedia:\n      - settings: {}\n" +
+				"  - name: science\n  - topics: [world]\n",
+			expectedError: "channels[0].socialMedia[0]: type not set\n" +
+				"channels[1]: name \"science\" is used by another channel\n" +
+				"channels[2]: name not set",
+		},
+		{
+			name: "Duplicate adapter ids",
+			config: "news: {type: nytimes}\nllm: {type: chatgpt}\nimageGenerator: {type: dalle}\nchannels:\n" +
+				"  - name: science\n    socialMedia:\n      - type: mastodon\n      - type: mastodon\n" +
+				"      - {type: mastodon, id: mastodon-science}\n",
+			expectedError: "channels[0].socialMedia[1]: id \"mastodon\" is used by another adapter, set a unique id",
+		},
+		{
+			name: "Invalid topics",
+			config: "news: {type: nytimes}\nllm: {type: chatgpt}\nimageGenerator: {type: dalle}\nrouting:\n  topics:\n" +
+				"  - name: technology\n    promptTemplate: '{{.Title'\n  - name: Technology\n",
+			expectedError: "routing.topics[0]: invalid prompt template: template: technology:1: unclosed action\n" +
+				"routing.topics[1]: name \"Technology\" is used by another topic",
+		},
+		{
+			name:          "Negative max body length",
+			config:        "news: {type: nytimes}\nllm: {type: chatgpt}\nimageGenerator: {type: dalle}\nenrichment:\n  maxBodyLength: -1\n",
+			expectedError: "enrichment: maxBodyLength must not be negative",
+		},
+		{
+			name:          "LLM classifier without topics",
+			config:        "news: {type: nytimes}\nllm: {type: chatgpt}\nimageGenerator: {type: dalle}\nrouting:\n  classifier: llm\n",
+			expectedError: "routing: the llm classifier requires topics",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tc.config))
+			require.ErrorContains(t, err, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package infrastructure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronSchedule_Next(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+	stJohns, err := time.LoadLocation("America/St_Johns")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		expression string
+		location   *time.Location
+		after      time.Time
+		expected   time.Time
+	}{
+		{
+			name:       "Twice per day",
+			expression: "0 8,21 * * ?",
+			location:   time.UTC,
+			after:      time.Date(2023, 7, 1, 9, 30, 0, 0, time.UTC),
+			expected:   time.Date(2023, 7, 1, 21, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Next day",
+			expression: "0 8,21 * * *",
+			location:   time.UTC,
+			after:      time.Date(2023, 7, 1, 21, 0, 0, 0, time.UTC),
+			expected:   time.Date(2023, 7, 2, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Steps",
+			expression: "*/15 * * * *",
+			location:   time.UTC,
+			after:      time.Date(2023, 7, 1, 9, 31, 10, 0, time.UTC),
+			expected:   time.Date(2023, 7, 1, 9, 45, 0, 0, time.UTC),
+		},
+		{
+			name:       "Weekdays by name",
+			expression: "30 7 * * MON-FRI",
+			location:   time.UTC,
+			after:      time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), // Saturday
+			expected:   time.Date(2023, 7, 3, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "Day of month or day of week",
+			expression: "0 0 15 * SUN",
+			location:   time.UTC,
+			after:      time.Date(2023, 7, 10, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2023, 7, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Sunday as seven",
+			expression: "0 0 * * 7",
+			location:   time.UTC,
+			after:      time.Date(2023, 7, 10, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2023, 7, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Month rollover",
+			expression: "@monthly",
+			location:   time.UTC,
+			after:      time.Date(2023, 12, 5, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Timezone",
+			expression: "0 8 * * *",
+			location:   amsterdam,
+			after:      time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2023, 7, 1, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Timezone with a half hour offset",
+			expression: "0 8 * * *",
+			location:   kolkata,
+			after:      time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2023, 7, 1, 2, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "Timezone with a half hour offset behind UTC",
+			expression: "15 8,21 * * *",
+			location:   stJohns,
+			after:      time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC),
+			expected:   time.Date(2023, 7, 1, 23, 45, 0, 0, time.UTC),
+		},
+		{
+			name:       "Impossible date",
+			expression: "0 0 31 2 *",
+			location:   time.UTC,
+			after:      time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+			expected:   time.Time{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := ParseCronSchedule(tc.expression, tc.location)
+			require.NoError(t, err)
+			require.True(t, tc.expected.Equal(schedule.Next(tc.after)), "expected %s, got %s", tc.expected, schedule.Next(tc.after))
+		})
+	}
+}
+
+func TestParseCronSchedule_Invalid(t *testing.T) {
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* * * JANUARY *", "*/0 * * * *", "5-1 * * * *"} {
+		_, err := ParseCronSchedule(expression, time.UTC)
+		require.Error(t, err, expression)
+	}
+}
@@ -14,6 +14,42 @@ resource "null_resource" "create_temp_zip" {
   depends_on = [aws_s3_bucket.builds]
 }
 
+# Drafts, checkpoints of the runs and the generated images, the lambda filesystem does not outlive an invocation
+resource "aws_s3_bucket" "data" {
+  bucket = "${var.project_name}-data"
+}
+
+resource "aws_s3_bucket_public_access_block" "data" {
+  bucket = aws_s3_bucket.data.id
+
+  block_public_acls       = true
+  block_public_policy     = true
+  ignore_public_acls      = true
+  restrict_public_buckets = true
+}
+
+locals {
+  secrets = {
+    NEW_YORK_TIMES_KEY          = var.env_vars.new_york_times_key
+    OPENAI_KEY                  = var.env_vars.openai_key
+    TWITTER_API_KEY             = var.env_vars.twitter_api_key
+    TWITTER_API_KEY_SECRET      = var.env_vars.twitter_api_key_secret
+    TWITTER_ACCESS_TOKEN        = var.env_vars.twitter_access_token
+    TWITTER_ACCESS_TOKEN_SECRET = var.env_vars.twitter_access_token_secret
+    INSTAGRAM_USERNAME          = var.env_vars.instagram_username
+    INSTAGRAM_PASSWORD          = var.env_vars.instagram_password
+  }
+}
+
+# The credentials are stored encrypted in the parameter store instead of as plain environment variables of the lambda
+resource "aws_ssm_parameter" "secrets" {
+  for_each = nonsensitive(toset(keys(local.secrets)))
+
+  name  = "/${var.project_name}/${each.key}"
+  type  = "SecureString"
+  value = local.secrets[each.key]
+}
+
 module "lambda_function_existing_package_s3" {
   source  = "terraform-aws-modules/lambda/aws"
   version = "4.18.0"
@@ -30,14 +66,29 @@ module "lambda_function_existing_package_s3" {
   maximum_retry_attempts            = 0
 
   environment_variables = {
-    NEW_YORK_TIMES_KEY          = var.env_vars.new_york_times_key
-    OPENAI_KEY                  = var.env_vars.openai_key
-    TWITTER_API_KEY             = var.env_vars.twitter_api_key
-    TWITTER_API_KEY_SECRET      = var.env_vars.twitter_api_key_secret
-    TWITTER_ACCESS_TOKEN        = var.env_vars.twitter_access_token
-    TWITTER_ACCESS_TOKEN_SECRET = var.env_vars.twitter_access_token_secret
-    INSTAGRAM_USERNAME          = var.env_vars.instagram_username
-    INSTAGRAM_PASSWORD          = var.env_vars.instagram_password
+    SECRETS_PROVIDER  = "ssm"
+    SECRETS_PREFIX    = "/${var.project_name}/"
+    REPOSITORY_BUCKET = aws_s3_bucket.data.id
+  }
+
+  attach_policy_statements = true
+  policy_statements = {
+    secrets = {
+      effect    = "Allow"
+      actions   = ["ssm:GetParameter"]
+      resources = [for parameter in aws_ssm_parameter.secrets : parameter.arn]
+    }
+    repository_objects = {
+      effect    = "Allow"
+      actions   = ["s3:GetObject", "s3:PutObject", "s3:DeleteObject"]
+      resources = ["${aws_s3_bucket.data.arn}/*"]
+    }
+    # Listing the bucket is needed for the runs in progress, and makes S3 answer 404 instead of 403 for missing objects
+    repository_bucket = {
+      effect    = "Allow"
+      actions   = ["s3:ListBucket"]
+      resources = [aws_s3_bucket.data.arn]
+    }
   }
 
   create_package = false